@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/goravel/framework/contracts/console"
+	"github.com/goravel/framework/contracts/console/command"
+
+	"pms/app/services"
+)
+
+type CheckReorderPoints struct {
+	// Dependent services
+	reorderService *services.ReorderService
+}
+
+func NewCheckReorderPoints() *CheckReorderPoints {
+	return &CheckReorderPoints{
+		// Inject services
+		reorderService: services.NewReorderService(),
+	}
+}
+
+// Signature The name and signature of the console command.
+func (r *CheckReorderPoints) Signature() string {
+	return "stock:check-reorder"
+}
+
+// Description The console command description.
+func (r *CheckReorderPoints) Description() string {
+	return "Compare raw material stock with reorder points, draft replenishment suggestions and mail the achat role"
+}
+
+// Extend The console command extend.
+func (r *CheckReorderPoints) Extend() command.Extend {
+	return command.Extend{
+		Category: "stock",
+		Flags: []command.Flag{
+			&command.BoolFlag{
+				Name:  "no-mail",
+				Usage: "Update the suggestions without sending the low-stock mail",
+			},
+		},
+	}
+}
+
+// Handle Execute the console command.
+func (r *CheckReorderPoints) Handle(ctx console.Context) error {
+	report, err := r.reorderService.Check()
+	if err != nil {
+		ctx.Error("Reorder point check failed: " + err.Error())
+		return err
+	}
+
+	ctx.Info(fmt.Sprintf("%d rule(s) checked, %d below reorder point, %d suggestion(s) resolved", report.Rules, len(report.Suggestions), report.Resolved))
+	for _, suggestion := range report.Suggestions {
+		ctx.Line(fmt.Sprintf("  variant %d: available %.3f, suggested %.3f %s", suggestion.VariantID, suggestion.Available, suggestion.SuggestedQuantity, suggestion.Unit))
+	}
+
+	if ctx.OptionBool("no-mail") {
+		return nil
+	}
+
+	recipients, err := r.reorderService.Notify(report)
+	if err != nil {
+		ctx.Error("Low-stock mail failed: " + err.Error())
+		return err
+	}
+	if recipients > 0 {
+		ctx.Info(fmt.Sprintf("Low-stock report mailed to %d achat user(s)", recipients))
+	}
+
+	return nil
+}
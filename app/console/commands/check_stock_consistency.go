@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/goravel/framework/contracts/console"
+	"github.com/goravel/framework/contracts/console/command"
+
+	"pms/app/services"
+)
+
+type CheckStockConsistency struct {
+	// Dependent services
+	historyService *services.StockHistoryService
+}
+
+func NewCheckStockConsistency() *CheckStockConsistency {
+	return &CheckStockConsistency{
+		// Inject services
+		historyService: services.NewStockHistoryService(),
+	}
+}
+
+// Signature The name and signature of the console command.
+func (r *CheckStockConsistency) Signature() string {
+	return "stock:check-consistency"
+}
+
+// Description The console command description.
+func (r *CheckStockConsistency) Description() string {
+	return "Compare stock levels with the movement ledger and optionally repair the drift"
+}
+
+// Extend The console command extend.
+func (r *CheckStockConsistency) Extend() command.Extend {
+	return command.Extend{
+		Category: "stock",
+		Flags: []command.Flag{
+			&command.BoolFlag{
+				Name:  "repair",
+				Usage: "Realign drifted stock levels, cost layers and lot balances on the movement ledger",
+			},
+		},
+	}
+}
+
+// Handle Execute the console command.
+func (r *CheckStockConsistency) Handle(ctx console.Context) error {
+	if ctx.OptionBool("repair") {
+		repaired, err := r.historyService.Repair()
+		for _, drift := range repaired {
+			ctx.Line(fmt.Sprintf("  product %d at location %d: %.3f -> %.3f", drift.ProductID, drift.LocationID, drift.Recorded, drift.Replayed))
+			r.lots(ctx, drift)
+		}
+		if err != nil {
+			ctx.Error("Stock repair failed: " + err.Error())
+			return err
+		}
+		ctx.Info(fmt.Sprintf("%d stock level(s) repaired", len(repaired)))
+		return nil
+	}
+
+	drifts, err := r.historyService.CheckConsistency()
+	if err != nil {
+		ctx.Error("Stock consistency check failed: " + err.Error())
+		return err
+	}
+
+	if len(drifts) == 0 {
+		ctx.Info("Stock levels match the movement ledger")
+		return nil
+	}
+
+	ctx.Warning(fmt.Sprintf("%d stock level(s) drifted from the movement ledger", len(drifts)))
+	for _, drift := range drifts {
+		ctx.Line(fmt.Sprintf("  product %d at location %d: recorded %.3f, replayed %.3f (%+.3f)", drift.ProductID, drift.LocationID, drift.Recorded, drift.Replayed, drift.Difference))
+		if drift.LayerQuantity > drift.Replayed {
+			ctx.Line(fmt.Sprintf("    cost layers hold %.3f", drift.LayerQuantity))
+		}
+		r.lots(ctx, drift)
+	}
+
+	return nil
+}
+
+// lots prints the lot balances of a drift that differ from the lot ledger
+func (r *CheckStockConsistency) lots(ctx console.Context, drift services.StockDrift) {
+	for _, lot := range drift.Lots {
+		ctx.Line(fmt.Sprintf("    lot %d: recorded %.3f, replayed %.3f (%+.3f)", lot.LotID, lot.Recorded, lot.Replayed, lot.Difference))
+	}
+}
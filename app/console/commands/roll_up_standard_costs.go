@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/goravel/framework/contracts/console"
+	"github.com/goravel/framework/contracts/console/command"
+
+	"pms/app/services"
+)
+
+type RollUpStandardCosts struct {
+	// Dependent services
+	costService *services.CostService
+}
+
+func NewRollUpStandardCosts() *RollUpStandardCosts {
+	return &RollUpStandardCosts{
+		// Inject services
+		costService: services.NewCostService(),
+	}
+}
+
+// Signature The name and signature of the console command.
+func (r *RollUpStandardCosts) Signature() string {
+	return "costing:roll-up"
+}
+
+// Description The console command description.
+func (r *RollUpStandardCosts) Description() string {
+	return "Roll up the recipes of all variants into their standard costs"
+}
+
+// Extend The console command extend.
+func (r *RollUpStandardCosts) Extend() command.Extend {
+	return command.Extend{
+		Category: "costing",
+		Flags: []command.Flag{
+			&command.StringFlag{
+				Name:  "product",
+				Usage: "Only roll up the variants of the given product ID",
+			},
+			&command.StringFlag{
+				Name:  "source",
+				Usage: "Material cost source, purchase or valuation, defaults to app.costing.material_cost_source",
+			},
+		},
+	}
+}
+
+// Handle Execute the console command.
+func (r *RollUpStandardCosts) Handle(ctx console.Context) error {
+	var productID *uint
+	if value := ctx.Option("product"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			ctx.Error("--product must be a product ID")
+			return err
+		}
+		product := uint(id)
+		productID = &product
+	}
+
+	costs, failures, err := r.costService.RollUpAll(productID, ctx.Option("source"))
+	if err != nil {
+		ctx.Error("Cost roll-up failed: " + err.Error())
+		return err
+	}
+
+	ctx.Info(fmt.Sprintf("%d standard cost(s) updated", len(costs)))
+	for _, cost := range costs {
+		ctx.Line(fmt.Sprintf("  variant %d: material %.4f + labour %.4f = %.4f (%s)", cost.VariantID, cost.MaterialCost, cost.LabourCost, cost.TotalCost, cost.CostSource))
+	}
+
+	if len(failures) > 0 {
+		ctx.Warning(fmt.Sprintf("%d variant(s) could not be costed", len(failures)))
+		for _, failure := range failures {
+			ctx.Line(fmt.Sprintf("  variant %d (%s): %s", failure.VariantID, failure.SKU, failure.Error))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/goravel/framework/contracts/console"
+	"github.com/goravel/framework/contracts/console/command"
+
+	"pms/app/services"
+)
+
+type StockSnapshot struct {
+	// Dependent services
+	historyService *services.StockHistoryService
+}
+
+func NewStockSnapshot() *StockSnapshot {
+	return &StockSnapshot{
+		// Inject services
+		historyService: services.NewStockHistoryService(),
+	}
+}
+
+// Signature The name and signature of the console command.
+func (r *StockSnapshot) Signature() string {
+	return "stock:snapshot"
+}
+
+// Description The console command description.
+func (r *StockSnapshot) Description() string {
+	return "Replay stock movements to list the quantities per variant and location at a point in time"
+}
+
+// Extend The console command extend.
+func (r *StockSnapshot) Extend() command.Extend {
+	return command.Extend{
+		Category: "stock",
+		Flags: []command.Flag{
+			&command.StringFlag{
+				Name:  "at",
+				Usage: "Point in time as YYYY-MM-DD (end of day) or RFC3339, defaults to now",
+			},
+			&command.StringFlag{
+				Name:  "location",
+				Usage: "Only replay the given storage location ID",
+			},
+		},
+	}
+}
+
+// Handle Execute the console command.
+func (r *StockSnapshot) Handle(ctx console.Context) error {
+	at := time.Now()
+	if value := ctx.Option("at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			date, dateErr := time.ParseInLocation("2006-01-02", value, time.Local)
+			if dateErr != nil {
+				ctx.Error("--at must be a YYYY-MM-DD date or an RFC3339 timestamp")
+				return dateErr
+			}
+			parsed = date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		at = parsed
+	}
+
+	var filter services.StockSnapshotFilter
+	if value := ctx.Option("location"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			ctx.Error("--location must be a storage location ID")
+			return err
+		}
+		locationID := uint(id)
+		filter.LocationID = &locationID
+	}
+
+	lines, err := r.historyService.Snapshot(at, filter)
+	if err != nil {
+		ctx.Error("Stock replay failed: " + err.Error())
+		return err
+	}
+
+	ctx.Info(fmt.Sprintf("Stock at %s: %d line(s)", at.Format(time.RFC3339), len(lines)))
+	for _, line := range lines {
+		variant := "-"
+		if line.VariantID != nil {
+			variant = strconv.FormatUint(uint64(*line.VariantID), 10)
+		}
+		ctx.Line(fmt.Sprintf("  location %d, product %d, variant %s: %.3f (%d movement(s))", line.LocationID, line.ProductID, variant, line.Quantity, line.Movements))
+	}
+
+	return nil
+}
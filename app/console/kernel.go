@@ -3,15 +3,25 @@ package console
 import (
 	"github.com/goravel/framework/contracts/console"
 	"github.com/goravel/framework/contracts/schedule"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/console/commands"
 )
 
 type Kernel struct {
 }
 
 func (kernel Kernel) Schedule() []schedule.Event {
-	return []schedule.Event{}
+	return []schedule.Event{
+		facades.Schedule().Command("stock:check-reorder").DailyAt(facades.Config().GetString("app.stock.reorder_check_at", "06:00")).SkipIfStillRunning(),
+	}
 }
 
 func (kernel Kernel) Commands() []console.Command {
-	return []console.Command{}
+	return []console.Command{
+		commands.NewCheckReorderPoints(),
+		commands.NewStockSnapshot(),
+		commands.NewCheckStockConsistency(),
+		commands.NewRollUpStandardCosts(),
+	}
 }
@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type DeliveryController struct {
+	// Dependent services
+	serialService *services.SerialService
+}
+
+func NewDeliveryController() *DeliveryController {
+	return &DeliveryController{
+		// Inject services
+		serialService: services.NewSerialService(),
+	}
+}
+
+// CreateDeliveryRequest represents the delivery of serial numbers to a client site
+type CreateDeliveryRequest struct {
+	ClientSiteID uint   `json:"client_site_id" form:"client_site_id" validate:"required"`
+	SerialIDs    []uint `json:"serial_ids" form:"serial_ids" validate:"required"`
+	Notes        string `json:"notes" form:"notes"`
+}
+
+// authUser returns the authenticated user with the role loaded
+func (r *DeliveryController) authUser(ctx http.Context) (models.User, bool) {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return user, false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return user, true
+}
+
+// Index returns a paginated list of deliveries
+func (r *DeliveryController) Index(ctx http.Context) http.Response {
+	user, ok := r.authUser(ctx)
+	if !ok || !slices.Contains([]string{"admin", "magasinier", "commercial"}, user.Role.Key) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Commercial or Admin access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+	searchQuery := ctx.Request().Query("query", "")
+
+	// Parse filter data
+	filterClient := ctx.Request().Query("filterData[client_id]", "")
+	filterSite := ctx.Request().Query("filterData[client_site_id]", "")
+
+	query := facades.Orm().Query().With("Client").With("ClientSite").With("Creator")
+
+	// Apply search filter
+	if searchQuery != "" {
+		query = query.Where("delivery_number LIKE ?", "%"+searchQuery+"%")
+	}
+
+	// Apply specific filters
+	if filterClient != "" {
+		query = query.Where("client_id", filterClient)
+	}
+	if filterSite != "" {
+		query = query.Where("client_site_id", filterSite)
+	}
+
+	query = query.OrderBy("delivered_at", "desc")
+
+	var deliveries []models.Delivery
+
+	// Get total count
+	total, err := query.Model(&models.Delivery{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count deliveries",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&deliveries); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve deliveries",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"deliveries": deliveries,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Show returns a delivery with its serial numbers
+func (r *DeliveryController) Show(ctx http.Context) http.Response {
+	user, ok := r.authUser(ctx)
+	if !ok || !slices.Contains([]string{"admin", "magasinier", "commercial"}, user.Role.Key) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Commercial or Admin access required",
+		})
+	}
+
+	var delivery models.Delivery
+	if err := facades.Orm().Query().With("Client").With("ClientSite").With("Creator").With("SerialNumbers.Product").
+		Where("id", ctx.Request().Route("id")).FirstOrFail(&delivery); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Delivery not found",
+				"message": "The requested delivery does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve delivery",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"delivery": delivery,
+	})
+}
+
+// Store ships serial numbers in stock to a client site (magasinier/admin)
+func (r *DeliveryController) Store(ctx http.Context) http.Response {
+	user, ok := r.authUser(ctx)
+	if !ok || (user.Role.Key != "admin" && user.Role.Key != "magasinier") {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier or Admin access required",
+		})
+	}
+
+	var request CreateDeliveryRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	slices.Sort(request.SerialIDs)
+	request.SerialIDs = slices.Compact(request.SerialIDs)
+	if request.ClientSiteID == 0 || len(request.SerialIDs) == 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "client_site_id and at least one serial_ids entry are required",
+		})
+	}
+
+	var site models.ClientSite
+	if err := facades.Orm().Query().Where("id", request.ClientSiteID).FirstOrFail(&site); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid client site",
+			"message": "The specified client site does not exist",
+		})
+	}
+
+	delivery := models.Delivery{
+		ClientID:     site.ClientID,
+		ClientSiteID: site.ID,
+		Notes:        request.Notes,
+	}
+	if err := r.serialService.Deliver(&delivery, request.SerialIDs, user); err != nil {
+		if errors.Is(err, services.ErrSerialNotFound) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Invalid serial numbers",
+				"message": "One or more serial numbers do not exist",
+			})
+		}
+		var serialErr *services.SerialNotAvailableError
+		if errors.As(err, &serialErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":   "Serial number not available",
+				"message": "Serial number " + serialErr.SerialNumber + " is not in stock",
+			})
+		}
+		var stockErr *services.InsufficientStockError
+		if errors.As(err, &stockErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":     "Insufficient stock",
+				"message":   "The delivery would take the stock level below zero",
+				"available": stockErr.Available,
+				"requested": stockErr.Requested,
+			})
+		}
+		var countErr *services.CountInProgressError
+		if errors.As(err, &countErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":        "Inventory count in progress",
+				"message":      "Movements are blocked on the location until inventory count " + countErr.CountNumber + " is closed",
+				"count_number": countErr.CountNumber,
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to create delivery",
+		})
+	}
+
+	facades.Orm().Query().With("Client").With("ClientSite").With("Creator").With("SerialNumbers.Product").Where("id", delivery.ID).First(&delivery)
+
+	return ctx.Response().Status(201).Json(http.Json{
+		"message":  "Delivery created successfully",
+		"delivery": delivery,
+	})
+}
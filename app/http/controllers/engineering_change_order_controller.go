@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type EngineeringChangeOrderController struct {
+	// Dependent services
+	engineeringChangeOrderService *services.EngineeringChangeOrderService
+}
+
+func NewEngineeringChangeOrderController() *EngineeringChangeOrderController {
+	return &EngineeringChangeOrderController{
+		// Inject services
+		engineeringChangeOrderService: services.NewEngineeringChangeOrderService(),
+	}
+}
+
+// CreateEngineeringChangeOrderRequest represents the change order creation payload
+type CreateEngineeringChangeOrderRequest struct {
+	RecipeVariantID uint   `json:"recipe_variant_id" form:"recipe_variant_id" validate:"required"`
+	Reason          string `json:"reason" form:"reason" validate:"required"`
+	EffectiveFrom   string `json:"effective_from" form:"effective_from"`
+}
+
+// RejectEngineeringChangeOrderRequest represents the change order rejection payload
+type RejectEngineeringChangeOrderRequest struct {
+	Reason string `json:"reason" form:"reason" validate:"required"`
+}
+
+// authUser returns the authenticated user with its role loaded
+func (r *EngineeringChangeOrderController) authUser(ctx http.Context) (models.User, error) {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return user, err
+	}
+
+	if err := facades.Orm().Query().With("Role").Where("id", user.ID).FirstOrFail(&user); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// methodes returns the authenticated user if it may manage change orders
+func (r *EngineeringChangeOrderController) methodes(ctx http.Context) (models.User, http.Response) {
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return user, ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+	if user.Role.Key != "admin" && user.Role.Key != "ingenieur_methodes" {
+		return user, ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	return user, nil
+}
+
+// Index returns a paginated list of engineering change orders
+func (r *EngineeringChangeOrderController) Index(ctx http.Context) http.Response {
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+	if !slices.Contains([]string{"admin", "ingenieur_methodes", "magasinier", "achat", "commercial"}, user.Role.Key) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes, Stock or Admin access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+	searchQuery := ctx.Request().Query("query", "")
+
+	// Parse filter data
+	filterStatus := ctx.Request().Query("filterData[status]", "")
+	filterVariant := ctx.Request().Query("filterData[variant_id]", "")
+
+	query := facades.Orm().Query().With("Variant").With("RecipeVariant").With("Requester").With("Approver")
+
+	// Apply search filter
+	if searchQuery != "" {
+		query = query.Where("eco_number LIKE ? OR reason LIKE ?",
+			"%"+searchQuery+"%", "%"+searchQuery+"%")
+	}
+
+	// Apply specific filters
+	if filterStatus != "" {
+		query = query.Where("status", filterStatus)
+	}
+	if filterVariant != "" {
+		query = query.Where("variant_id", filterVariant)
+	}
+
+	query = query.OrderBy("created_at", "desc")
+
+	var orders []models.EngineeringChangeOrder
+
+	// Get total count
+	total, err := query.Model(&models.EngineeringChangeOrder{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count engineering change orders",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&orders); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve engineering change orders",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"engineering_change_orders": orders,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Show returns a change order with the new and the replaced recipe versions
+func (r *EngineeringChangeOrderController) Show(ctx http.Context) http.Response {
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+	if !slices.Contains([]string{"admin", "ingenieur_methodes", "magasinier", "achat", "commercial"}, user.Role.Key) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes, Stock or Admin access required",
+		})
+	}
+
+	order, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"engineering_change_order": order,
+	})
+}
+
+// Store submits a draft recipe version for approval (methodes/admin)
+func (r *EngineeringChangeOrderController) Store(ctx http.Context) http.Response {
+	user, response := r.methodes(ctx)
+	if response != nil {
+		return response
+	}
+
+	var request CreateEngineeringChangeOrderRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	if request.RecipeVariantID == 0 || request.Reason == "" {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "recipe_variant_id and reason are required",
+		})
+	}
+
+	order := models.EngineeringChangeOrder{
+		RecipeVariantID: request.RecipeVariantID,
+		Reason:          request.Reason,
+	}
+	if request.EffectiveFrom != "" {
+		effectiveFrom, err := time.ParseInLocation("2006-01-02", request.EffectiveFrom, time.Local)
+		if err != nil {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Validation failed",
+				"message": "effective_from must be a date in YYYY-MM-DD format",
+			})
+		}
+		order.EffectiveFrom = &effectiveFrom
+	}
+
+	if err := r.engineeringChangeOrderService.Create(&order, user); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(400).Json(http.Json{
+				"error":   "Invalid recipe",
+				"message": "The specified recipe version does not exist",
+			})
+		}
+		return r.workflowError(ctx, err, "Failed to create engineering change order")
+	}
+
+	return r.respond(ctx, 201, order.ID, "Engineering change order created successfully")
+}
+
+// Approve puts the recipe version of a change order into effect (methodes/admin). The
+// requester may not approve their own change order unless they are admin.
+func (r *EngineeringChangeOrderController) Approve(ctx http.Context) http.Response {
+	user, response := r.methodes(ctx)
+	if response != nil {
+		return response
+	}
+
+	order, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+	if order.RequestedBy == user.ID && user.Role.Key != "admin" {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "A change order must be approved by someone other than its requester",
+		})
+	}
+
+	if err := r.engineeringChangeOrderService.Approve(&order, user); err != nil {
+		return r.workflowError(ctx, err, "Failed to approve engineering change order")
+	}
+
+	return r.respond(ctx, 200, order.ID, "Engineering change order approved successfully")
+}
+
+// Reject closes a change order without putting its recipe version into effect (methodes/admin)
+func (r *EngineeringChangeOrderController) Reject(ctx http.Context) http.Response {
+	user, response := r.methodes(ctx)
+	if response != nil {
+		return response
+	}
+
+	order, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	var request RejectEngineeringChangeOrderRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+	if request.Reason == "" {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "A rejection reason is required",
+		})
+	}
+
+	if err := r.engineeringChangeOrderService.Reject(&order, request.Reason, user); err != nil {
+		return r.workflowError(ctx, err, "Failed to reject engineering change order")
+	}
+
+	return r.respond(ctx, 200, order.ID, "Engineering change order rejected successfully")
+}
+
+// find loads the change order referenced by the route
+func (r *EngineeringChangeOrderController) find(ctx http.Context) (models.EngineeringChangeOrder, http.Response) {
+	var order models.EngineeringChangeOrder
+
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return order, ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Engineering change order ID is required",
+		})
+	}
+
+	if err := facades.Orm().Query().With("Variant").With("RecipeVariant.RecipeVariantItems.MaterialVariant").
+		With("PreviousRecipe.RecipeVariantItems.MaterialVariant").With("Requester").With("Approver").
+		Where("id", id).FirstOrFail(&order); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return order, ctx.Response().Status(404).Json(http.Json{
+				"error":   "Engineering change order not found",
+				"message": "The requested engineering change order does not exist",
+			})
+		}
+		return order, ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve engineering change order",
+		})
+	}
+
+	return order, nil
+}
+
+// respond reloads a change order and renders it with a message
+func (r *EngineeringChangeOrderController) respond(ctx http.Context, status int, id uint, message string) http.Response {
+	var order models.EngineeringChangeOrder
+	facades.Orm().Query().With("Variant").With("RecipeVariant.RecipeVariantItems.MaterialVariant").
+		With("PreviousRecipe.RecipeVariantItems.MaterialVariant").With("Requester").With("Approver").
+		Where("id", id).First(&order)
+
+	return ctx.Response().Status(status).Json(http.Json{
+		"message":                  message,
+		"engineering_change_order": order,
+	})
+}
+
+// workflowError renders the errors returned by the change order workflow
+func (r *EngineeringChangeOrderController) workflowError(ctx http.Context, err error, message string) http.Response {
+	var statusErr *services.InvalidChangeOrderStatusError
+	if errors.As(err, &statusErr) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":            "Invalid status",
+			"message":          "The " + statusErr.Document + " is " + statusErr.Status,
+			"current_status":   statusErr.Status,
+			"allowed_statuses": statusErr.Expected,
+		})
+	}
+	if errors.Is(err, services.ErrChangeOrderPending) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Change order already pending",
+			"message": "The recipe version already has a pending engineering change order",
+		})
+	}
+	var cycleErr *services.RecipeCycleError
+	if errors.As(err, &cycleErr) {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Recipe cycle",
+			"message": "The recipe would make the variant a material of itself",
+			"path":    cycleErr.Path,
+		})
+	}
+
+	return ctx.Response().Status(500).Json(http.Json{
+		"error":   "Database error",
+		"message": message,
+	})
+}
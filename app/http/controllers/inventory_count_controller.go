@@ -0,0 +1,478 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type InventoryCountController struct {
+	// Dependent services
+	inventoryCountService *services.InventoryCountService
+}
+
+func NewInventoryCountController() *InventoryCountController {
+	return &InventoryCountController{
+		// Inject services
+		inventoryCountService: services.NewInventoryCountService(),
+	}
+}
+
+// OpenInventoryCountRequest represents the count session opening payload
+type OpenInventoryCountRequest struct {
+	LocationID     uint   `json:"location_id" form:"location_id" validate:"required"`
+	CategoryIDs    []uint `json:"category_ids" form:"category_ids"`
+	BlockMovements *bool  `json:"block_movements" form:"block_movements"`
+	Notes          string `json:"notes" form:"notes"`
+}
+
+// CountEntryRequest represents one counted quantity
+type CountEntryRequest struct {
+	ProductID uint    `json:"product_id" form:"product_id"`
+	VariantID *uint   `json:"variant_id" form:"variant_id"`
+	Quantity  float64 `json:"quantity" form:"quantity"`
+	Add       bool    `json:"add" form:"add"`
+}
+
+// SubmitCountsRequest represents counted quantities sent by a device
+type SubmitCountsRequest struct {
+	Counts []CountEntryRequest `json:"counts" form:"counts" validate:"required"`
+}
+
+// isMagasinierOrAdmin checks if user can run inventory counts
+func (r *InventoryCountController) isMagasinierOrAdmin(ctx http.Context) bool {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return user.Role.Key == "admin" || user.Role.Key == "magasinier"
+}
+
+// Index returns a paginated list of inventory counts
+func (r *InventoryCountController) Index(ctx http.Context) http.Response {
+	if !r.isMagasinierOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier or Admin access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+	searchQuery := ctx.Request().Query("query", "")
+
+	// Parse filter data
+	filterStatus := ctx.Request().Query("filterData[status]", "")
+	filterLocation := ctx.Request().Query("filterData[location_id]", "")
+
+	query := facades.Orm().Query().With("Location").With("OpenedByUser").With("ValidatedByUser")
+
+	// Apply search filter
+	if searchQuery != "" {
+		query = query.Where("count_number LIKE ? OR notes LIKE ?",
+			"%"+searchQuery+"%", "%"+searchQuery+"%")
+	}
+
+	// Apply specific filters
+	if filterStatus != "" {
+		query = query.Where("status", filterStatus)
+	}
+	if filterLocation != "" {
+		query = query.Where("location_id", filterLocation)
+	}
+
+	query = query.OrderBy("opened_at", "desc")
+
+	var counts []models.InventoryCount
+
+	// Get total count
+	total, err := query.Model(&models.InventoryCount{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count inventory counts",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&counts); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve inventory counts",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"inventory_counts": counts,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Show returns a count session with its lines, variances and the movements posted meanwhile
+func (r *InventoryCountController) Show(ctx http.Context) http.Response {
+	if !r.isMagasinierOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier or Admin access required",
+		})
+	}
+
+	count, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	return r.respond(ctx, 200, count, "")
+}
+
+// Store opens a count session and snapshots the expected quantities
+func (r *InventoryCountController) Store(ctx http.Context) http.Response {
+	if !r.isMagasinierOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier or Admin access required",
+		})
+	}
+
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+
+	var request OpenInventoryCountRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	if request.LocationID == 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "location_id is required",
+		})
+	}
+
+	// Verify storage location exists
+	var location models.StorageLocation
+	if err := facades.Orm().Query().Where("id", request.LocationID).FirstOrFail(&location); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid storage location",
+			"message": "The specified storage location does not exist",
+		})
+	}
+
+	// Verify categories exist
+	if len(request.CategoryIDs) > 0 {
+		count, err := facades.Orm().Query().Model(&models.Category{}).Where("id IN ?", request.CategoryIDs).Count()
+		if err != nil || count != int64(len(request.CategoryIDs)) {
+			return ctx.Response().Status(400).Json(http.Json{
+				"error":   "Invalid category",
+				"message": "One or more specified categories do not exist",
+			})
+		}
+	}
+
+	blockMovements := true
+	if request.BlockMovements != nil {
+		blockMovements = *request.BlockMovements
+	}
+
+	count := models.InventoryCount{
+		LocationID:     request.LocationID,
+		BlockMovements: blockMovements,
+		Notes:          request.Notes,
+		OpenedBy:       user.ID,
+	}
+
+	if err := r.inventoryCountService.Open(&count, request.CategoryIDs); err != nil {
+		if errors.Is(err, services.ErrCountAlreadyOpen) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":   "Inventory count already open",
+				"message": "An inventory count is already open on this location",
+			})
+		}
+		var locationErr *services.LocationNotStockableError
+		if errors.As(err, &locationErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":   "Location cannot hold stock",
+				"message": "Storage location " + locationErr.Name + " is not a bin able to hold stock",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to open inventory count",
+		})
+	}
+
+	facades.Orm().Query().With("Location").With("OpenedByUser").Where("id", count.ID).First(&count)
+
+	return r.respond(ctx, 201, count, "Inventory count opened successfully")
+}
+
+// SubmitCounts records counted quantities, possibly from several devices
+func (r *InventoryCountController) SubmitCounts(ctx http.Context) http.Response {
+	if !r.isMagasinierOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier or Admin access required",
+		})
+	}
+
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+
+	var request SubmitCountsRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	if len(request.Counts) == 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "At least one count is required",
+		})
+	}
+
+	entries := make([]services.CountEntry, 0, len(request.Counts))
+	for i, entry := range request.Counts {
+		if entry.ProductID == 0 || entry.Quantity < 0 {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Validation failed",
+				"message": fmt.Sprintf("Count %d requires a product and a non-negative quantity", i+1),
+			})
+		}
+		if entry.VariantID != nil {
+			exists, err := facades.Orm().Query().Model(&models.ProductVariant{}).Where("id", *entry.VariantID).Where("product_id", entry.ProductID).Exists()
+			if err != nil || !exists {
+				return ctx.Response().Status(422).Json(http.Json{
+					"error":   "Invalid variant",
+					"message": fmt.Sprintf("The variant of count %d does not belong to the product", i+1),
+				})
+			}
+		}
+		entries = append(entries, services.CountEntry{
+			ProductID: entry.ProductID,
+			VariantID: entry.VariantID,
+			Quantity:  entry.Quantity,
+			Add:       entry.Add,
+		})
+	}
+
+	count, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	lines, err := r.inventoryCountService.Submit(&count, entries, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrCountNotOpen):
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":   "Inventory count closed",
+				"message": "Counts can only be submitted while the session is open",
+			})
+		case errors.Is(err, services.ErrProductOutOfCountScope):
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Product out of scope",
+				"message": "The product is outside the categories of the inventory count",
+			})
+		case errors.Is(err, errors.OrmRecordNotFound):
+			return ctx.Response().Status(400).Json(http.Json{
+				"error":   "Invalid product",
+				"message": "The specified product does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to record counts",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message": "Counts recorded successfully",
+		"lines":   lines,
+	})
+}
+
+// Validate closes the session and posts the inventory adjustments
+func (r *InventoryCountController) Validate(ctx http.Context) http.Response {
+	if !r.isMagasinierOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier or Admin access required",
+		})
+	}
+
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+
+	count, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	adjustments, err := r.inventoryCountService.Validate(&count, user)
+	if err != nil {
+		if errors.Is(err, services.ErrCountNotOpen) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":   "Inventory count closed",
+				"message": "Only open inventory counts can be validated",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to validate inventory count",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":     "Inventory count validated successfully",
+		"adjustments": adjustments,
+	})
+}
+
+// Cancel abandons an open count session
+func (r *InventoryCountController) Cancel(ctx http.Context) http.Response {
+	if !r.isMagasinierOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier or Admin access required",
+		})
+	}
+
+	count, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	if err := r.inventoryCountService.Cancel(&count); err != nil {
+		if errors.Is(err, services.ErrCountNotOpen) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":   "Inventory count closed",
+				"message": "Only open inventory counts can be cancelled",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to cancel inventory count",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":         "Inventory count cancelled successfully",
+		"inventory_count": count,
+	})
+}
+
+// find loads the inventory count referenced by the route
+func (r *InventoryCountController) find(ctx http.Context) (models.InventoryCount, http.Response) {
+	var count models.InventoryCount
+
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return count, ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Inventory count ID is required",
+		})
+	}
+
+	if err := facades.Orm().Query().With("Location").With("OpenedByUser").With("ValidatedByUser").Where("id", id).FirstOrFail(&count); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return count, ctx.Response().Status(404).Json(http.Json{
+				"error":   "Inventory count not found",
+				"message": "The requested inventory count does not exist",
+			})
+		}
+		return count, ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve inventory count",
+		})
+	}
+
+	return count, nil
+}
+
+// respond renders a count with its lines, variance summary and concurrent movements
+func (r *InventoryCountController) respond(ctx http.Context, status int, count models.InventoryCount, message string) http.Response {
+	lines, err := r.inventoryCountService.Lines(count)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve inventory count lines",
+		})
+	}
+
+	movements, err := r.inventoryCountService.MovementsDuringCount(count)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve movements posted during the count",
+		})
+	}
+
+	counted, withVariance := 0, 0
+	for _, line := range lines {
+		if line.Variance == nil {
+			continue
+		}
+		counted++
+		if *line.Variance != 0 {
+			withVariance++
+		}
+	}
+
+	body := http.Json{
+		"inventory_count":        count,
+		"category_ids":           services.InventoryCountCategories(count),
+		"lines":                  lines,
+		"movements_during_count": movements,
+		"summary": http.Json{
+			"lines":         len(lines),
+			"counted":       counted,
+			"uncounted":     len(lines) - counted,
+			"with_variance": withVariance,
+		},
+	}
+	if message != "" {
+		body["message"] = message
+	}
+
+	return ctx.Response().Status(status).Json(body)
+}
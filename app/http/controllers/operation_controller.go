@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+type OperationController struct {
+	// Dependent services
+}
+
+func NewOperationController() *OperationController {
+	return &OperationController{
+		// Inject services
+	}
+}
+
+// OperationRequest represents the operation creation and update payload
+type OperationRequest struct {
+	Key        string  `json:"key" form:"key" validate:"required|max_len:50"`
+	Title      string  `json:"title" form:"title" validate:"required|max_len:100"`
+	OrderIndex int     `json:"order_index" form:"order_index"`
+	HourlyRate float64 `json:"hourly_rate" form:"hourly_rate"`
+}
+
+// isMethodesOrAdmin checks if the authenticated user may manage operations
+func (r *OperationController) isMethodesOrAdmin(ctx http.Context) bool {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return user.Role.Key == "admin" || user.Role.Key == "ingenieur_methodes"
+}
+
+// Index returns all operations in shop floor order
+func (r *OperationController) Index(ctx http.Context) http.Response {
+	var operations []models.Operation
+	if err := facades.Orm().Query().OrderBy("order_index").Find(&operations); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve operations",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"operations": operations,
+	})
+}
+
+// Store creates an operation (methodes/admin)
+func (r *OperationController) Store(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	var operation models.Operation
+	if response := r.fill(ctx, &operation); response != nil {
+		return response
+	}
+
+	if err := facades.Orm().Query().Create(&operation); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to create operation",
+		})
+	}
+
+	return ctx.Response().Status(201).Json(http.Json{
+		"message":   "Operation created successfully",
+		"operation": operation,
+	})
+}
+
+// Update changes an operation, including the hourly rate its routing time is costed at
+// (methodes/admin)
+func (r *OperationController) Update(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	var operation models.Operation
+	if err := facades.Orm().Query().Where("id", ctx.Request().Route("id")).FirstOrFail(&operation); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Operation not found",
+				"message": "The requested operation does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve operation",
+		})
+	}
+
+	if response := r.fill(ctx, &operation); response != nil {
+		return response
+	}
+
+	if err := facades.Orm().Query().Save(&operation); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to update operation",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":   "Operation updated successfully",
+		"operation": operation,
+	})
+}
+
+// fill validates the request and copies it onto the operation
+func (r *OperationController) fill(ctx http.Context, operation *models.Operation) http.Response {
+	var request OperationRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	if request.Key == "" || request.Title == "" {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "key and title are required",
+		})
+	}
+	if request.HourlyRate < 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "hourly_rate cannot be negative",
+		})
+	}
+
+	exists, err := facades.Orm().Query().Model(&models.Operation{}).Where("key", request.Key).
+		Where("id <> ?", operation.ID).Exists()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to check operation key",
+		})
+	}
+	if exists {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Operation already exists",
+			"message": "An operation with this key already exists",
+		})
+	}
+
+	operation.Key = request.Key
+	operation.Title = request.Title
+	operation.OrderIndex = request.OrderIndex
+	operation.HourlyRate = request.HourlyRate
+
+	return nil
+}
@@ -0,0 +1,1076 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type OrderFabricationController struct {
+	// Dependent services
+	orderFabricationService *services.OrderFabricationService
+	sequenceService         *services.SequenceService
+	reservationService      *services.ReservationService
+}
+
+func NewOrderFabricationController() *OrderFabricationController {
+	return &OrderFabricationController{
+		// Inject services
+		orderFabricationService: services.NewOrderFabricationService(),
+		sequenceService:         services.NewSequenceService(),
+		reservationService:      services.NewReservationService(),
+	}
+}
+
+// CreateOrderFabricationRequest represents the manufacturing order creation request payload
+type CreateOrderFabricationRequest struct {
+	ProductID    uint    `json:"product_id" form:"product_id" validate:"required"`
+	VariantID    *uint   `json:"variant_id" form:"variant_id"`
+	Quantity     float64 `json:"quantity" form:"quantity" validate:"required"`
+	ClientID     uint    `json:"client_id" form:"client_id" validate:"required"`
+	ClientSiteID *uint   `json:"client_site_id" form:"client_site_id"`
+	Priority     string  `json:"priority" form:"priority"`
+	DeadlineDate string  `json:"deadline_date" form:"deadline_date"`
+	Notes        string  `json:"notes" form:"notes"`
+}
+
+// UpdateOrderFabricationRequest represents the manufacturing order update request payload
+type UpdateOrderFabricationRequest struct {
+	ProductID    *uint    `json:"product_id" form:"product_id"`
+	VariantID    *uint    `json:"variant_id" form:"variant_id"`
+	Quantity     *float64 `json:"quantity" form:"quantity"`
+	ClientID     *uint    `json:"client_id" form:"client_id"`
+	ClientSiteID *uint    `json:"client_site_id" form:"client_site_id"`
+	Priority     string   `json:"priority" form:"priority"`
+	DeadlineDate string   `json:"deadline_date" form:"deadline_date"`
+	Notes        string   `json:"notes" form:"notes"`
+}
+
+// CancelOrderFabricationRequest represents the manufacturing order cancellation request payload
+type CancelOrderFabricationRequest struct {
+	Reason string `json:"reason" form:"reason"`
+}
+
+// ConsumeMaterialRequest represents the material consumption request payload
+type ConsumeMaterialRequest struct {
+	Quantity float64 `json:"quantity" form:"quantity" validate:"required"`
+}
+
+// TransitionOrderFabricationRequest represents the manufacturing order status change request payload
+type TransitionOrderFabricationRequest struct {
+	Status string `json:"status" form:"status" validate:"required"`
+	Reason string `json:"reason" form:"reason"`
+}
+
+// orderFabricationSortKeys lists the columns the index may be sorted by
+var orderFabricationSortKeys = map[string]bool{
+	"order_number":  true,
+	"quantity":      true,
+	"status":        true,
+	"priority":      true,
+	"deadline_date": true,
+	"created_at":    true,
+	"updated_at":    true,
+}
+
+// isCommercialOrAdmin checks if the authenticated user is commercial or admin
+func (r *OrderFabricationController) isCommercialOrAdmin(ctx http.Context) bool {
+	var user models.User
+	err := facades.Auth(ctx).User(&user)
+	if err != nil {
+		return false
+	}
+
+	// Load the role relationship
+	if err := facades.Orm().Query().With("Role").Where("id", user.ID).First(&user); err != nil {
+		return false
+	}
+
+	return user.Role.Key == "admin" || user.Role.Key == "commercial"
+}
+
+// authUser returns the authenticated user with its role loaded
+func (r *OrderFabricationController) authUser(ctx http.Context) (models.User, error) {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return user, err
+	}
+
+	if err := facades.Orm().Query().With("Role").Where("id", user.ID).FirstOrFail(&user); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// Index returns a paginated list of manufacturing orders with search and filtering
+func (r *OrderFabricationController) Index(ctx http.Context) http.Response {
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+	searchQuery := ctx.Request().Query("query", "")
+	sortKey := ctx.Request().Query("sort[key]", "created_at")
+	sortOrder := ctx.Request().Query("sort[order]", "desc")
+
+	// Parse filter data
+	filterOrderNumber := ctx.Request().Query("filterData[order_number]", "")
+	filterStatus := ctx.Request().Query("filterData[status]", "")
+	filterPriority := ctx.Request().Query("filterData[priority]", "")
+	filterProduct := ctx.Request().Query("filterData[product_id]", "")
+	filterVariant := ctx.Request().Query("filterData[variant_id]", "")
+	filterClient := ctx.Request().Query("filterData[client_id]", "")
+	filterClientSite := ctx.Request().Query("filterData[client_site_id]", "")
+	filterDeadlineFrom := ctx.Request().Query("filterData[deadline_from]", "")
+	filterDeadlineTo := ctx.Request().Query("filterData[deadline_to]", "")
+
+	query := facades.Orm().Query().With("Product").With("Variant").With("Client").With("ClientSite")
+
+	// Apply search filter
+	if searchQuery != "" {
+		query = query.Where("order_number LIKE ? OR notes LIKE ?",
+			"%"+searchQuery+"%", "%"+searchQuery+"%")
+	}
+
+	// Apply specific filters
+	if filterOrderNumber != "" {
+		query = query.Where("order_number LIKE ?", "%"+filterOrderNumber+"%")
+	}
+	if filterStatus != "" {
+		query = query.Where("status", filterStatus)
+	}
+	if filterPriority != "" {
+		query = query.Where("priority", filterPriority)
+	}
+	if filterProduct != "" {
+		query = query.Where("product_id", filterProduct)
+	}
+	if filterVariant != "" {
+		query = query.Where("variant_id", filterVariant)
+	}
+	if filterClient != "" {
+		query = query.Where("client_id", filterClient)
+	}
+	if filterClientSite != "" {
+		query = query.Where("client_site_id", filterClientSite)
+	}
+	if filterDeadlineFrom != "" {
+		query = query.Where("deadline_date >= ?", filterDeadlineFrom)
+	}
+	if filterDeadlineTo != "" {
+		query = query.Where("deadline_date <= ?", filterDeadlineTo)
+	}
+
+	// Apply sorting
+	if orderFabricationSortKeys[sortKey] && (sortOrder == "asc" || sortOrder == "desc") {
+		query = query.OrderBy(sortKey, sortOrder)
+	} else {
+		query = query.OrderBy("created_at", "desc")
+	}
+
+	var orders []models.OrderFabrication
+
+	// Get total count
+	total, err := query.Model(&models.OrderFabrication{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count manufacturing orders",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&orders); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve manufacturing orders",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"order_fabrications": orders,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Show returns a specific manufacturing order by ID with its relationships
+func (r *OrderFabricationController) Show(ctx http.Context) http.Response {
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Manufacturing order ID is required",
+		})
+	}
+
+	var order models.OrderFabrication
+	if err := facades.Orm().Query().With("Product").With("Variant").With("Client").With("ClientSite").With("Creator").With("RecipeVariant").Where("id", id).FirstOrFail(&order); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Manufacturing order not found",
+				"message": "The requested manufacturing order does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve manufacturing order",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"order_fabrication": order,
+	})
+}
+
+// Store creates a new manufacturing order
+func (r *OrderFabricationController) Store(ctx http.Context) http.Response {
+	if !r.isCommercialOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Commercial or Admin access required",
+		})
+	}
+
+	var request CreateOrderFabricationRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	if request.Priority == "" {
+		request.Priority = "normal"
+	}
+
+	// Validate input
+	validator, err := facades.Validation().Make(map[string]any{
+		"product_id": request.ProductID,
+		"quantity":   request.Quantity,
+		"client_id":  request.ClientID,
+		"priority":   request.Priority,
+	}, map[string]string{
+		"product_id": "required|numeric",
+		"quantity":   "required|numeric",
+		"client_id":  "required|numeric",
+		"priority":   "in:low,normal,high,urgent",
+	})
+
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error": "Validation error",
+		})
+	}
+
+	if validator.Fails() {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":  "Validation failed",
+			"errors": validator.Errors().All(),
+		})
+	}
+
+	if request.Quantity <= 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Quantity must be greater than zero",
+		})
+	}
+
+	deadlineDate, err := r.parseDeadlineDate(request.DeadlineDate)
+	if err != nil {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Deadline date must use the YYYY-MM-DD format",
+		})
+	}
+
+	if response := r.validateReferences(ctx, request.ProductID, request.VariantID, request.ClientID, request.ClientSiteID); response != nil {
+		return response
+	}
+
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+
+	// Create new manufacturing order
+	order := models.OrderFabrication{
+		ProductID:    request.ProductID,
+		VariantID:    request.VariantID,
+		Quantity:     request.Quantity,
+		ClientID:     request.ClientID,
+		ClientSiteID: request.ClientSiteID,
+		Status:       models.OrderFabricationStatusPending,
+		Priority:     request.Priority,
+		DeadlineDate: deadlineDate,
+		Notes:        request.Notes,
+		CreatedBy:    user.ID,
+	}
+
+	// Number and insert the order in one transaction so numbers stay gap free
+	err = facades.Orm().Transaction(func(tx orm.Query) error {
+		orderNumber, err := r.sequenceService.NextTx(tx, services.SequenceOrderFabrication)
+		if err != nil {
+			return err
+		}
+		order.OrderNumber = orderNumber
+
+		return tx.Create(&order)
+	})
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to create manufacturing order",
+		})
+	}
+
+	// Load relationships for response
+	facades.Orm().Query().With("Product").With("Variant").With("Client").With("ClientSite").With("Creator").Where("id", order.ID).First(&order)
+
+	return ctx.Response().Status(201).Json(http.Json{
+		"message":           "Manufacturing order created successfully",
+		"order_fabrication": order,
+	})
+}
+
+// Update modifies an existing manufacturing order
+func (r *OrderFabricationController) Update(ctx http.Context) http.Response {
+	if !r.isCommercialOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Commercial or Admin access required",
+		})
+	}
+
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Manufacturing order ID is required",
+		})
+	}
+
+	var request UpdateOrderFabricationRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	// Find existing manufacturing order
+	var order models.OrderFabrication
+	if err := facades.Orm().Query().Where("id", id).FirstOrFail(&order); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Manufacturing order not found",
+				"message": "The requested manufacturing order does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve manufacturing order",
+		})
+	}
+
+	if order.Status == models.OrderFabricationStatusCompleted || order.Status == models.OrderFabricationStatusCancelled {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Manufacturing order locked",
+			"message": "Completed or cancelled manufacturing orders cannot be modified",
+		})
+	}
+
+	if request.Priority != "" {
+		validator, err := facades.Validation().Make(map[string]any{
+			"priority": request.Priority,
+		}, map[string]string{
+			"priority": "in:low,normal,high,urgent",
+		})
+		if err != nil {
+			return ctx.Response().Status(500).Json(http.Json{
+				"error": "Validation error",
+			})
+		}
+
+		if validator.Fails() {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":  "Validation failed",
+				"errors": validator.Errors().All(),
+			})
+		}
+	}
+
+	if request.Quantity != nil && *request.Quantity <= 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Quantity must be greater than zero",
+		})
+	}
+
+	deadlineDate, err := r.parseDeadlineDate(request.DeadlineDate)
+	if err != nil {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Deadline date must use the YYYY-MM-DD format",
+		})
+	}
+
+	// Changing the product or client invalidates the previous variant or site
+	productID := order.ProductID
+	variantID := order.VariantID
+	if request.ProductID != nil && *request.ProductID != order.ProductID {
+		productID = *request.ProductID
+		variantID = nil
+	}
+	if request.VariantID != nil {
+		variantID = request.VariantID
+	}
+
+	clientID := order.ClientID
+	clientSiteID := order.ClientSiteID
+	if request.ClientID != nil && *request.ClientID != order.ClientID {
+		clientID = *request.ClientID
+		clientSiteID = nil
+	}
+	if request.ClientSiteID != nil {
+		clientSiteID = request.ClientSiteID
+	}
+
+	if response := r.validateReferences(ctx, productID, variantID, clientID, clientSiteID); response != nil {
+		return response
+	}
+
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+
+	// Released orders need their material needs recomputed when the quantity or variant changes
+	materialsChanged := (request.Quantity != nil && *request.Quantity != order.Quantity) || !sameUintPointer(variantID, order.VariantID)
+	refreshMaterials := materialsChanged && r.orderFabricationService.HasMaterials(&order)
+
+	// Another variant is made with its own recipe, pinned again on the refresh
+	if !sameUintPointer(variantID, order.VariantID) {
+		order.RecipeVariantID = nil
+	}
+
+	// Update manufacturing order fields
+	order.ProductID = productID
+	order.VariantID = variantID
+	order.ClientID = clientID
+	order.ClientSiteID = clientSiteID
+	if request.Quantity != nil {
+		order.Quantity = *request.Quantity
+	}
+	if request.Priority != "" {
+		order.Priority = request.Priority
+	}
+	if deadlineDate != nil {
+		order.DeadlineDate = deadlineDate
+	}
+	if request.Notes != "" {
+		order.Notes = request.Notes
+	}
+
+	// Save changes
+	err = facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := tx.Save(&order); err != nil {
+			return err
+		}
+		if refreshMaterials {
+			return r.orderFabricationService.RefreshMaterialsTx(tx, &order, user)
+		}
+
+		return nil
+	})
+	if err != nil {
+		var unitErr *services.UnitConversionError
+		if errors.Is(err, services.ErrOrderWithoutVariant) || errors.Is(err, services.ErrMissingRecipe) || errors.Is(err, services.ErrInvalidRecipeOutput) || errors.As(err, &unitErr) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Cannot compute material requirements",
+				"message": err.Error(),
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to update manufacturing order",
+		})
+	}
+
+	// Load relationships for response
+	facades.Orm().Query().With("Product").With("Variant").With("Client").With("ClientSite").With("Creator").Where("id", order.ID).First(&order)
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":           "Manufacturing order updated successfully",
+		"order_fabrication": order,
+	})
+}
+
+// Cancel cancels a manufacturing order through the status workflow
+func (r *OrderFabricationController) Cancel(ctx http.Context) http.Response {
+	var request CancelOrderFabricationRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	return r.transition(ctx, models.OrderFabricationStatusCancelled, request.Reason)
+}
+
+// Transition moves a manufacturing order to another status if the user's role allows it
+func (r *OrderFabricationController) Transition(ctx http.Context) http.Response {
+	var request TransitionOrderFabricationRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	if request.Status == "" {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Target status is required",
+		})
+	}
+
+	return r.transition(ctx, request.Status, request.Reason)
+}
+
+// History returns the status history of a manufacturing order
+func (r *OrderFabricationController) History(ctx http.Context) http.Response {
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Manufacturing order ID is required",
+		})
+	}
+
+	var order models.OrderFabrication
+	if err := facades.Orm().Query().Where("id", id).FirstOrFail(&order); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Manufacturing order not found",
+				"message": "The requested manufacturing order does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve manufacturing order",
+		})
+	}
+
+	var history []models.ProductionOfHistory
+	if err := facades.Orm().Query().With("User").With("Operation").Where("order_fabrication_id", order.ID).OrderBy("status_at", "asc").Find(&history); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve manufacturing order history",
+		})
+	}
+
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+
+	allowed, err := r.orderFabricationService.AllowedTransitionsTx(facades.Orm().Query(), &order, user.Role.Key)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve manufacturing order history",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"order_fabrication":   order,
+		"history":             history,
+		"allowed_transitions": allowed,
+	})
+}
+
+// Operations returns the routing steps copied onto the order when it was released
+func (r *OrderFabricationController) Operations(ctx http.Context) http.Response {
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Manufacturing order ID is required",
+		})
+	}
+
+	var order models.OrderFabrication
+	if err := facades.Orm().Query().Where("id", id).FirstOrFail(&order); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Manufacturing order not found",
+				"message": "The requested manufacturing order does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve manufacturing order",
+		})
+	}
+
+	var operations []models.OrderFabricationOperation
+	if err := facades.Orm().Query().With("Operation").Where("order_fabrication_id", order.ID).OrderBy("sequence").Find(&operations); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve manufacturing order operations",
+		})
+	}
+
+	plannedMinutes := 0.0
+	for _, operation := range operations {
+		plannedMinutes += operation.PlannedMinutes
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"order_fabrication": order,
+		"operations":        operations,
+		"planned_minutes":   plannedMinutes,
+	})
+}
+
+// Materials returns the material requirements computed when the order was released
+func (r *OrderFabricationController) Materials(ctx http.Context) http.Response {
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Manufacturing order ID is required",
+		})
+	}
+
+	var order models.OrderFabrication
+	if err := facades.Orm().Query().With("Product").With("Variant").With("RecipeVariant").Where("id", id).FirstOrFail(&order); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Manufacturing order not found",
+				"message": "The requested manufacturing order does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve manufacturing order",
+		})
+	}
+
+	var requirements []models.ProductionMaterialRequirement
+	if err := facades.Orm().Query().With("MaterialVariant").With("Reservations.Location").Where("order_fabrication_id", order.ID).OrderBy("id", "asc").Find(&requirements); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve material requirements",
+		})
+	}
+
+	shortages := 0
+	for _, requirement := range requirements {
+		if requirement.RequestQuantity > 0 && requirement.Status != services.RequirementStatusConsumed {
+			shortages++
+		}
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"order_fabrication": order,
+		"requirements":      requirements,
+		"shortage_count":    shortages,
+	})
+}
+
+// RefreshMaterials recomputes the material requirements and the stock requests for their shortages
+func (r *OrderFabricationController) RefreshMaterials(ctx http.Context) http.Response {
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Manufacturing order ID is required",
+		})
+	}
+
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+	if user.Role.Key != "admin" && user.Role.Key != "ingenieur_methodes" && user.Role.Key != "commercial" {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes, Commercial or Admin access required",
+		})
+	}
+
+	var order models.OrderFabrication
+	if err := facades.Orm().Query().Where("id", id).FirstOrFail(&order); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Manufacturing order not found",
+				"message": "The requested manufacturing order does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve manufacturing order",
+		})
+	}
+
+	if !r.orderFabricationService.HasMaterials(&order) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Manufacturing order not released",
+			"message": "Material requirements are only computed for released orders",
+		})
+	}
+
+	if err := r.orderFabricationService.RefreshMaterials(&order, user); err != nil {
+		var unitErr *services.UnitConversionError
+		if errors.Is(err, services.ErrOrderWithoutVariant) || errors.Is(err, services.ErrMissingRecipe) || errors.Is(err, services.ErrInvalidRecipeOutput) || errors.As(err, &unitErr) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Cannot compute material requirements",
+				"message": err.Error(),
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to refresh material requirements",
+		})
+	}
+
+	var requirements []models.ProductionMaterialRequirement
+	facades.Orm().Query().With("MaterialVariant").With("Reservations.Location").Where("order_fabrication_id", order.ID).OrderBy("id", "asc").Find(&requirements)
+
+	var stockRequests []models.StockRequest
+	facades.Orm().Query().Where("order_fabrication_id", order.ID).OrderBy("id", "asc").Find(&stockRequests)
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":        "Material requirements refreshed successfully",
+		"requirements":   requirements,
+		"stock_requests": stockRequests,
+	})
+}
+
+// ConsumeMaterial converts reserved material of a requirement into out movements
+func (r *OrderFabricationController) ConsumeMaterial(ctx http.Context) http.Response {
+	id := ctx.Request().Route("id")
+	requirementID := ctx.Request().Route("requirementId")
+	if id == "" || requirementID == "" {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Manufacturing order ID and requirement ID are required",
+		})
+	}
+
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+	if !slices.Contains([]string{"admin", "ingenieur_methodes", "magasinier", "operateur_decoupe", "operateur_pliage", "operateur_assemblage", "operateur_finition"}, user.Role.Key) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Operator, Magasinier, Methodes or Admin access required",
+		})
+	}
+
+	var request ConsumeMaterialRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	if request.Quantity <= 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Quantity must be greater than zero",
+		})
+	}
+
+	var order models.OrderFabrication
+	if err := facades.Orm().Query().Where("id", id).FirstOrFail(&order); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Manufacturing order not found",
+				"message": "The requested manufacturing order does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve manufacturing order",
+		})
+	}
+
+	var requirement models.ProductionMaterialRequirement
+	if err := facades.Orm().Query().Where("id", requirementID).Where("order_fabrication_id", order.ID).FirstOrFail(&requirement); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Material requirement not found",
+				"message": "The requested material requirement does not exist on this order",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve material requirement",
+		})
+	}
+
+	movements, err := r.reservationService.Consume(&order, &requirement, request.Quantity, user)
+	if err != nil {
+		var reservationErr *services.InsufficientReservationError
+		if errors.As(err, &reservationErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":     "Insufficient reservation",
+				"message":   "Not enough material is reserved for this requirement",
+				"reserved":  reservationErr.Reserved,
+				"requested": reservationErr.Requested,
+			})
+		}
+		var countErr *services.CountInProgressError
+		if errors.As(err, &countErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":        "Inventory count in progress",
+				"message":      "Movements are blocked on the location until inventory count " + countErr.CountNumber + " is closed",
+				"count_number": countErr.CountNumber,
+			})
+		}
+		var unitErr *services.UnitConversionError
+		if errors.As(err, &unitErr) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Invalid unit",
+				"message": "Quantity in " + unitErr.From + " cannot be converted to the stock unit " + unitErr.To,
+			})
+		}
+		var stockErr *services.InsufficientStockError
+		if errors.As(err, &stockErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":     "Insufficient stock",
+				"message":   "The reserved location no longer holds enough stock",
+				"available": stockErr.Available,
+				"requested": stockErr.Requested,
+			})
+		}
+		if errors.Is(err, services.ErrOrderNotConsuming) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":   "Invalid manufacturing order status",
+				"message": "Material can only be consumed on released or in progress orders",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to consume material",
+		})
+	}
+
+	facades.Orm().Query().With("MaterialVariant").With("Reservations.Location").Where("id", requirement.ID).First(&requirement)
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":         "Material consumed successfully",
+		"requirement":     requirement,
+		"stock_movements": movements,
+	})
+}
+
+// transition applies a status change and renders the workflow errors
+func (r *OrderFabricationController) transition(ctx http.Context, status string, reason string) http.Response {
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Manufacturing order ID is required",
+		})
+	}
+
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+
+	// Find existing manufacturing order
+	var order models.OrderFabrication
+	if err := facades.Orm().Query().Where("id", id).FirstOrFail(&order); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Manufacturing order not found",
+				"message": "The requested manufacturing order does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve manufacturing order",
+		})
+	}
+
+	if err := r.orderFabricationService.Transition(&order, status, user, reason); err != nil {
+		var transitionErr *services.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":            "Invalid status transition",
+				"message":          "Manufacturing order cannot move from " + transitionErr.From + " to " + transitionErr.To,
+				"current_status":   transitionErr.From,
+				"allowed_statuses": transitionErr.Allowed,
+			})
+		}
+		var unitErr *services.UnitConversionError
+		if errors.Is(err, services.ErrOrderWithoutVariant) || errors.Is(err, services.ErrMissingRecipe) || errors.Is(err, services.ErrInvalidRecipeOutput) || errors.As(err, &unitErr) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Cannot compute material requirements",
+				"message": err.Error(),
+			})
+		}
+		if errors.Is(err, services.ErrNoFinishedGoodsLocation) || errors.Is(err, services.ErrFractionalSerialQuantity) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Cannot receive finished goods",
+				"message": err.Error(),
+			})
+		}
+		var locationErr *services.LocationNotStockableError
+		if errors.As(err, &locationErr) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Cannot receive finished goods",
+				"message": "Storage location " + locationErr.Name + " is not a bin able to hold stock",
+			})
+		}
+		var countErr *services.CountInProgressError
+		if errors.As(err, &countErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":        "Inventory count in progress",
+				"message":      "Finished goods cannot be received until inventory count " + countErr.CountNumber + " is closed",
+				"count_number": countErr.CountNumber,
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to update manufacturing order status",
+		})
+	}
+
+	// Load relationships for response
+	facades.Orm().Query().With("Product").With("Variant").With("Client").With("ClientSite").With("Creator").Where("id", order.ID).First(&order)
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":           "Manufacturing order status updated successfully",
+		"order_fabrication": order,
+	})
+}
+
+// validateReferences checks that the product, variant, client and site exist and belong together
+func (r *OrderFabricationController) validateReferences(ctx http.Context, productID uint, variantID *uint, clientID uint, clientSiteID *uint) http.Response {
+	// Verify product exists
+	var product models.Product
+	if err := facades.Orm().Query().Where("id", productID).FirstOrFail(&product); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid product",
+			"message": "The specified product does not exist",
+		})
+	}
+
+	// Verify variant belongs to the product
+	if variantID != nil {
+		var variant models.ProductVariant
+		if err := facades.Orm().Query().Where("id", *variantID).FirstOrFail(&variant); err != nil {
+			return ctx.Response().Status(400).Json(http.Json{
+				"error":   "Invalid variant",
+				"message": "The specified variant does not exist",
+			})
+		}
+		if variant.ProductID != productID {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Invalid variant",
+				"message": "The specified variant does not belong to the product",
+			})
+		}
+	}
+
+	// Verify client exists
+	var client models.Client
+	if err := facades.Orm().Query().Where("id", clientID).FirstOrFail(&client); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid client",
+			"message": "The specified client does not exist",
+		})
+	}
+
+	// Verify site belongs to the client
+	if clientSiteID != nil {
+		var site models.ClientSite
+		if err := facades.Orm().Query().Where("id", *clientSiteID).FirstOrFail(&site); err != nil {
+			return ctx.Response().Status(400).Json(http.Json{
+				"error":   "Invalid client site",
+				"message": "The specified client site does not exist",
+			})
+		}
+		if site.ClientID != clientID {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Invalid client site",
+				"message": "The specified site does not belong to the client",
+			})
+		}
+	}
+
+	return nil
+}
+
+// parseDeadlineDate parses an optional YYYY-MM-DD deadline
+func (r *OrderFabricationController) parseDeadlineDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	deadline, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deadline, nil
+}
+
+// sameUintPointer reports whether two optional IDs hold the same value
+func sameUintPointer(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
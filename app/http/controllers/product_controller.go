@@ -10,15 +10,18 @@ import (
 	"github.com/goravel/framework/facades"
 
 	"pms/app/models"
+	"pms/app/services"
 )
 
 type ProductController struct {
 	// Dependent services
+	unitService *services.UnitService
 }
 
 func NewProductController() *ProductController {
 	return &ProductController{
 		// Inject services
+		unitService: services.NewUnitService(),
 	}
 }
 
@@ -34,6 +37,8 @@ type CreateProductRequest struct {
 	PrixVente     float64 `json:"prix_vente" form:"prix_vente"`
 	Unit          string  `json:"unit" form:"unit" validate:"max_len:50"`
 	ImageURL      string  `json:"image_url" form:"image_url" validate:"max_len:500"`
+	IsSerialized  bool    `json:"is_serialized" form:"is_serialized"`
+	SerialPattern string  `json:"serial_pattern" form:"serial_pattern" validate:"max_len:100"`
 }
 
 // UpdateProductRequest represents the product update request payload
@@ -92,6 +97,8 @@ type CompleteProductUpdateRequest struct {
 	Variants      []ProductVariantRequest  `json:"variants"`
 	Images        []ProductImageRequest    `json:"images"`
 	Unit          string                   `json:"unit" validate:"max_len:50"`
+	IsSerialized  bool                     `json:"is_serialized"`
+	SerialPattern string                   `json:"serial_pattern" validate:"max_len:100"`
 }
 
 // CreateAttributeRequest represents the attribute creation request
@@ -124,6 +131,52 @@ type CreateImageRequest struct {
 	IsPrimary  bool   `json:"is_primary" form:"is_primary"`
 }
 
+// checkSerialPattern rejects a serial number pattern, when given, that is too long or
+// does not number units uniquely over the years
+func (r *ProductController) checkSerialPattern(ctx http.Context, pattern string) http.Response {
+	if pattern == "" {
+		return nil
+	}
+
+	message := ""
+	if len(pattern) > 100 {
+		message = "serial_pattern must be at most 100 characters"
+	} else if err := services.ValidateSequencePattern(pattern); err != nil {
+		message = "serial_pattern: " + err.Error()
+	}
+	if message == "" {
+		return nil
+	}
+
+	return ctx.Response().Status(422).Json(http.Json{
+		"error":   "Validation failed",
+		"message": message,
+	})
+}
+
+// checkUnit rejects a unit that is not defined in the units table; an empty unit is allowed
+func (r *ProductController) checkUnit(ctx http.Context, unit string) http.Response {
+	if unit == "" {
+		return nil
+	}
+
+	exists, err := r.unitService.Exists(unit)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to check unit",
+		})
+	}
+	if !exists {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Unit " + unit + " does not exist",
+		})
+	}
+
+	return nil
+}
+
 // isMethodesOrAdmin checks if the authenticated user is methodes or admin
 func (r *ProductController) isMethodesOrAdmin(ctx http.Context) bool {
 	var user models.User
@@ -331,6 +384,14 @@ func (r *ProductController) Store(ctx http.Context) http.Response {
 		})
 	}
 
+	if response := r.checkSerialPattern(ctx, request.SerialPattern); response != nil {
+		return response
+	}
+
+	if response := r.checkUnit(ctx, request.Unit); response != nil {
+		return response
+	}
+
 	// Check if SKU already exists (if provided)
 	if request.SKU != "" {
 		var existingProduct models.Product
@@ -376,6 +437,8 @@ func (r *ProductController) Store(ctx http.Context) http.Response {
 		PrixVente:     request.PrixVente,
 		Unit:          request.Unit,
 		ImageURL:      request.ImageURL,
+		IsSerialized:  request.IsSerialized,
+		SerialPattern: request.SerialPattern,
 	}
 
 	if err := facades.Orm().Query().Create(&product); err != nil {
@@ -423,6 +486,14 @@ func (r *ProductController) Update(ctx http.Context) http.Response {
 		})
 	}
 
+	if response := r.checkSerialPattern(ctx, request.SerialPattern); response != nil {
+		return response
+	}
+
+	if response := r.checkUnit(ctx, request.Unit); response != nil {
+		return response
+	}
+
 	// Find existing product
 	var product models.Product
 	if err := facades.Orm().Query().Where("id", id).FirstOrFail(&product); err != nil {
@@ -489,6 +560,8 @@ func (r *ProductController) Update(ctx http.Context) http.Response {
 	product.PrixVente = request.PrixVente
 	product.IsRawMaterial = request.IsRawMaterial
 	product.Unit = request.Unit
+	product.IsSerialized = request.IsSerialized
+	product.SerialPattern = request.SerialPattern
 	// get first primary image from request.images
 	for _, image := range request.Images {
 		if image.IsPrimary {
@@ -1080,6 +1153,10 @@ func (r *ProductController) CreateVariant(ctx http.Context) http.Response {
 		})
 	}
 
+	if response := r.checkUnit(ctx, request.Unit); response != nil {
+		return response
+	}
+
 	// Check if SKU already exists (if provided)
 	if request.SKU != "" {
 		var existingVariant models.ProductVariant
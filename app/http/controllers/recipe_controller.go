@@ -0,0 +1,668 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type RecipeController struct {
+	// Dependent services
+	recipeService *services.RecipeService
+	bomService    *services.BomService
+}
+
+func NewRecipeController() *RecipeController {
+	return &RecipeController{
+		// Inject services
+		recipeService: services.NewRecipeService(),
+		bomService:    services.NewBomService(),
+	}
+}
+
+// RecipeItemRequest represents a material line of a recipe
+type RecipeItemRequest struct {
+	MaterialVariantID uint    `json:"material_variant_id" form:"material_variant_id" validate:"required"`
+	Quantity          float64 `json:"quantity" form:"quantity" validate:"required"`
+	Unit              string  `json:"unit" form:"unit" validate:"max_len:50"`
+	ScrapPercent      float64 `json:"scrap_percent" form:"scrap_percent"`
+	Notes             string  `json:"notes" form:"notes"`
+}
+
+// RecipeByProductRequest represents a secondary output of a recipe
+type RecipeByProductRequest struct {
+	VariantID uint    `json:"variant_id" form:"variant_id" validate:"required"`
+	Quantity  float64 `json:"quantity" form:"quantity" validate:"required"`
+	Unit      string  `json:"unit" form:"unit" validate:"max_len:50"`
+	Notes     string  `json:"notes" form:"notes"`
+}
+
+// CreateRecipeRequest represents the recipe creation payload
+type CreateRecipeRequest struct {
+	VariantID      uint                     `json:"variant_id" form:"variant_id" validate:"required"`
+	OutputQuantity float64                  `json:"output_quantity" form:"output_quantity" validate:"required"`
+	YieldPercent   float64                  `json:"yield_percent" form:"yield_percent"` // defaults to 100
+	Notes          string                   `json:"notes" form:"notes"`
+	Items          []RecipeItemRequest      `json:"items" form:"items"`
+	ByProducts     []RecipeByProductRequest `json:"by_products" form:"by_products"`
+}
+
+// UpdateRecipeRequest represents the recipe update payload; the items replace the current ones
+type UpdateRecipeRequest struct {
+	OutputQuantity float64                  `json:"output_quantity" form:"output_quantity" validate:"required"`
+	YieldPercent   float64                  `json:"yield_percent" form:"yield_percent"` // defaults to 100
+	Notes          string                   `json:"notes" form:"notes"`
+	Items          []RecipeItemRequest      `json:"items" form:"items"`
+	ByProducts     []RecipeByProductRequest `json:"by_products" form:"by_products"`
+}
+
+// CopyRecipeRequest represents the copy of a recipe onto another variant
+type CopyRecipeRequest struct {
+	TargetVariantID uint `json:"target_variant_id" form:"target_variant_id" validate:"required"`
+	Overwrite       bool `json:"overwrite" form:"overwrite"`
+}
+
+// authUser returns the authenticated user with the role loaded
+func (r *RecipeController) authUser(ctx http.Context) (models.User, bool) {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return user, false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return user, true
+}
+
+// isMethodesOrAdmin checks if the authenticated user may define recipes
+func (r *RecipeController) isMethodesOrAdmin(ctx http.Context) bool {
+	user, ok := r.authUser(ctx)
+	return ok && (user.Role.Key == "admin" || user.Role.Key == "ingenieur_methodes")
+}
+
+// isRecipeViewer checks if the authenticated user may read recipes
+func (r *RecipeController) isRecipeViewer(ctx http.Context) bool {
+	user, ok := r.authUser(ctx)
+	return ok && slices.Contains([]string{"admin", "ingenieur_methodes", "magasinier", "achat", "commercial"}, user.Role.Key)
+}
+
+// Index returns a paginated list of recipes
+func (r *RecipeController) Index(ctx http.Context) http.Response {
+	if !r.isRecipeViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes, Stock or Admin access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+	searchQuery := ctx.Request().Query("query", "")
+
+	// Parse filter data
+	filterProduct := ctx.Request().Query("filterData[product_id]", "")
+	filterVariant := ctx.Request().Query("filterData[variant_id]", "")
+	filterMaterial := ctx.Request().Query("filterData[material_variant_id]", "")
+	filterStatus := ctx.Request().Query("filterData[status]", "")
+
+	query := facades.Orm().Query().With("Product").With("Variant")
+
+	// Apply search filter on the variant title and SKU
+	if searchQuery != "" {
+		query = query.Where("variant_id IN (SELECT id FROM product_variants WHERE title LIKE ? OR sku LIKE ?)",
+			"%"+searchQuery+"%", "%"+searchQuery+"%")
+	}
+
+	// Apply specific filters
+	if filterProduct != "" {
+		query = query.Where("product_id", filterProduct)
+	}
+	if filterVariant != "" {
+		query = query.Where("variant_id", filterVariant)
+	}
+	if filterMaterial != "" {
+		query = query.Where("id IN (SELECT recipe_variant_id FROM recipe_variant_items WHERE material_variant_id = ?)", filterMaterial)
+	}
+	if filterStatus != "" {
+		query = query.Where("status", filterStatus)
+	}
+
+	query = query.OrderBy("id", "desc")
+
+	var recipes []models.RecipeVariant
+
+	// Get total count
+	total, err := query.Model(&models.RecipeVariant{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count recipes",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&recipes); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve recipes",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"recipes": recipes,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Show returns a recipe with its items
+func (r *RecipeController) Show(ctx http.Context) http.Response {
+	if !r.isRecipeViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes, Stock or Admin access required",
+		})
+	}
+
+	recipe, response := r.find(ctx, "id", ctx.Request().Route("id"))
+	if response != nil {
+		return response
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"recipe": recipe,
+	})
+}
+
+// ShowByVariant returns the recipe version of a variant in effect today, with the list of
+// all its versions
+func (r *RecipeController) ShowByVariant(ctx http.Context) http.Response {
+	if !r.isRecipeViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes, Stock or Admin access required",
+		})
+	}
+
+	variantID, err := strconv.ParseUint(ctx.Request().Route("id"), 10, 64)
+	if err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Variant ID must be a valid number",
+		})
+	}
+
+	var versions []models.RecipeVariant
+	if err := facades.Orm().Query().Where("variant_id", variantID).OrderBy("version", "desc").Find(&versions); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve recipe versions",
+		})
+	}
+	if len(versions) == 0 {
+		return ctx.Response().Status(404).Json(http.Json{
+			"error":   "Recipe not found",
+			"message": "The variant has no recipe",
+		})
+	}
+
+	effective, err := r.recipeService.EffectiveTx(facades.Orm().Query(), uint(variantID), time.Now())
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve recipe",
+		})
+	}
+
+	// A variant whose only version is still a draft has no recipe in effect yet
+	var recipe any
+	if effective != nil {
+		found, response := r.find(ctx, "id", effective.ID)
+		if response != nil {
+			return response
+		}
+		recipe = found
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"recipe":   recipe,
+		"versions": versions,
+	})
+}
+
+// Bom returns the indented multi-level explosion of a variant for a quantity, with the total
+// needs of the leaf materials. levels is a depth or "all", qty defaults to one.
+func (r *RecipeController) Bom(ctx http.Context) http.Response {
+	if !r.isRecipeViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes, Stock or Admin access required",
+		})
+	}
+
+	variantID, err := strconv.ParseUint(ctx.Request().Route("id"), 10, 64)
+	if err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Variant ID must be a valid number",
+		})
+	}
+
+	levels := 0
+	if value := ctx.Request().Query("levels", "all"); value != "all" {
+		levels, err = strconv.Atoi(value)
+		if err != nil || levels < 1 {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Validation failed",
+				"message": "levels must be a positive number or all",
+			})
+		}
+	}
+
+	quantity, err := strconv.ParseFloat(ctx.Request().Query("qty", "1"), 64)
+	if err != nil || quantity <= 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "qty must be a number greater than zero",
+		})
+	}
+
+	explosion, err := r.bomService.Explode(uint(variantID), quantity, levels)
+	if err != nil {
+		if errors.Is(err, services.ErrMissingRecipe) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Recipe not found",
+				"message": "The variant has no recipe",
+			})
+		}
+		var cycleErr *services.RecipeCycleError
+		if errors.As(err, &cycleErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":   "Recipe cycle",
+				"message": "The recipes lead back to a variant they are made from",
+				"path":    cycleErr.Path,
+			})
+		}
+		var unitErr *services.UnitConversionError
+		if errors.Is(err, services.ErrInvalidRecipeOutput) || errors.As(err, &unitErr) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Cannot explode recipe",
+				"message": err.Error(),
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to explode recipe",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"bom": explosion,
+	})
+}
+
+// WhereUsed returns the recipes and sub-assemblies depending on a material variant at every
+// level, and the open manufacturing orders impacted by a change of it
+func (r *RecipeController) WhereUsed(ctx http.Context) http.Response {
+	if !r.isRecipeViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes, Stock or Admin access required",
+		})
+	}
+
+	var variant models.ProductVariant
+	if err := facades.Orm().Query().With("Product").Where("id", ctx.Request().Route("id")).FirstOrFail(&variant); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Variant not found",
+				"message": "The requested variant does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve variant",
+		})
+	}
+
+	whereUsed, err := r.bomService.WhereUsed(variant.ID)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve where-used",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"variant":    variant,
+		"where_used": whereUsed,
+	})
+}
+
+// Store defines the recipe of a variant (methodes/admin)
+func (r *RecipeController) Store(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	var request CreateRecipeRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	var variant models.ProductVariant
+	if err := facades.Orm().Query().Where("id", request.VariantID).FirstOrFail(&variant); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid variant",
+			"message": "The specified variant does not exist",
+		})
+	}
+
+	recipe := models.RecipeVariant{
+		ProductID:      variant.ProductID,
+		VariantID:      variant.ID,
+		OutputQuantity: request.OutputQuantity,
+		YieldPercent:   request.YieldPercent,
+		Notes:          request.Notes,
+	}
+	if err := r.recipeService.Save(&recipe, r.items(request.Items), r.byProducts(request.ByProducts)); err != nil {
+		return r.saveError(ctx, err, "Failed to create recipe")
+	}
+
+	created, response := r.find(ctx, "id", recipe.ID)
+	if response != nil {
+		return response
+	}
+
+	return ctx.Response().Status(201).Json(http.Json{
+		"message": "Recipe created successfully",
+		"recipe":  created,
+	})
+}
+
+// Update changes the output quantity and notes of a draft recipe version and replaces its
+// items (methodes/admin)
+func (r *RecipeController) Update(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	recipe, response := r.find(ctx, "id", ctx.Request().Route("id"))
+	if response != nil {
+		return response
+	}
+
+	var request UpdateRecipeRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	recipe.OutputQuantity = request.OutputQuantity
+	recipe.YieldPercent = request.YieldPercent
+	recipe.Notes = request.Notes
+	if err := r.recipeService.Save(&recipe, r.items(request.Items), r.byProducts(request.ByProducts)); err != nil {
+		return r.saveError(ctx, err, "Failed to update recipe")
+	}
+
+	updated, response := r.find(ctx, "id", recipe.ID)
+	if response != nil {
+		return response
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message": "Recipe updated successfully",
+		"recipe":  updated,
+	})
+}
+
+// Destroy deletes a draft recipe version and its items (methodes/admin)
+func (r *RecipeController) Destroy(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	recipe, response := r.find(ctx, "id", ctx.Request().Route("id"))
+	if response != nil {
+		return response
+	}
+
+	if err := r.recipeService.Delete(recipe); err != nil {
+		if errors.Is(err, services.ErrRecipeNotDraft) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":   "Cannot delete recipe",
+				"message": "Only draft recipe versions can be deleted",
+			})
+		}
+		if errors.Is(err, services.ErrRecipeHasChangeOrders) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":   "Cannot delete recipe",
+				"message": "The recipe version is referenced by engineering change orders",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to delete recipe",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message": "Recipe deleted successfully",
+	})
+}
+
+// Copy duplicates a recipe onto another variant (methodes/admin)
+func (r *RecipeController) Copy(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	source, response := r.find(ctx, "id", ctx.Request().Route("id"))
+	if response != nil {
+		return response
+	}
+
+	var request CopyRecipeRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	var target models.ProductVariant
+	if err := facades.Orm().Query().Where("id", request.TargetVariantID).FirstOrFail(&target); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid variant",
+			"message": "The target variant does not exist",
+		})
+	}
+	if target.ID == source.VariantID {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "The target variant must differ from the source variant",
+		})
+	}
+
+	recipe, err := r.recipeService.Copy(source, target, request.Overwrite)
+	if err != nil {
+		return r.saveError(ctx, err, "Failed to copy recipe")
+	}
+
+	copied, response := r.find(ctx, "id", recipe.ID)
+	if response != nil {
+		return response
+	}
+
+	return ctx.Response().Status(201).Json(http.Json{
+		"message": "Recipe copied successfully",
+		"recipe":  copied,
+	})
+}
+
+// NewVersion starts a draft version of the variant recipe from a version (methodes/admin)
+func (r *RecipeController) NewVersion(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	source, response := r.find(ctx, "id", ctx.Request().Route("id"))
+	if response != nil {
+		return response
+	}
+
+	recipe, err := r.recipeService.NewVersion(source)
+	if err != nil {
+		return r.saveError(ctx, err, "Failed to create recipe version")
+	}
+
+	created, response := r.find(ctx, "id", recipe.ID)
+	if response != nil {
+		return response
+	}
+
+	return ctx.Response().Status(201).Json(http.Json{
+		"message": "Recipe version created successfully",
+		"recipe":  created,
+	})
+}
+
+// items converts the request items to recipe items
+func (r *RecipeController) items(requests []RecipeItemRequest) []models.RecipeVariantItem {
+	items := make([]models.RecipeVariantItem, 0, len(requests))
+	for _, item := range requests {
+		items = append(items, models.RecipeVariantItem{
+			MaterialVariantID: item.MaterialVariantID,
+			Quantity:          item.Quantity,
+			Unit:              item.Unit,
+			ScrapPercent:      item.ScrapPercent,
+			Notes:             item.Notes,
+		})
+	}
+
+	return items
+}
+
+// byProducts converts the request by-products to recipe by-products
+func (r *RecipeController) byProducts(requests []RecipeByProductRequest) []models.RecipeVariantByProduct {
+	byProducts := make([]models.RecipeVariantByProduct, 0, len(requests))
+	for _, byProduct := range requests {
+		byProducts = append(byProducts, models.RecipeVariantByProduct{
+			VariantID: byProduct.VariantID,
+			Quantity:  byProduct.Quantity,
+			Unit:      byProduct.Unit,
+			Notes:     byProduct.Notes,
+		})
+	}
+
+	return byProducts
+}
+
+// saveError maps the errors of saving a recipe to a response
+func (r *RecipeController) saveError(ctx http.Context, err error, message string) http.Response {
+	if errors.Is(err, services.ErrRecipeExists) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Recipe already exists",
+			"message": "The variant already has a recipe",
+		})
+	}
+	if errors.Is(err, services.ErrRecipeNotDraft) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Recipe not editable",
+			"message": "Only draft recipe versions can be changed; start a new version instead",
+		})
+	}
+	if errors.Is(err, services.ErrChangeOrderPending) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Recipe not editable",
+			"message": "The draft recipe version is under review by a pending change order",
+		})
+	}
+	if errors.Is(err, services.ErrRecipeDraftExists) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Draft already exists",
+			"message": "The variant already has a draft recipe version",
+		})
+	}
+	var cycleErr *services.RecipeCycleError
+	if errors.As(err, &cycleErr) {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Recipe cycle",
+			"message": "The recipe would make the variant a material of itself",
+			"path":    cycleErr.Path,
+		})
+	}
+	var itemErr *services.InvalidRecipeItemError
+	var byProductErr *services.InvalidRecipeByProductError
+	if errors.Is(err, services.ErrInvalidRecipeOutput) || errors.Is(err, services.ErrInvalidRecipeYield) ||
+		errors.Is(err, services.ErrEmptyRecipe) || errors.Is(err, services.ErrRecipeSelfReference) ||
+		errors.As(err, &itemErr) || errors.As(err, &byProductErr) {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Response().Status(500).Json(http.Json{
+		"error":   "Database error",
+		"message": message,
+	})
+}
+
+// find loads a recipe with its items by the given column
+func (r *RecipeController) find(ctx http.Context, column string, value any) (models.RecipeVariant, http.Response) {
+	var recipe models.RecipeVariant
+	if err := facades.Orm().Query().With("Product").With("Variant").With("RecipeVariantItems.MaterialVariant.Product").
+		With("ByProducts.Variant").Where(column, value).FirstOrFail(&recipe); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return recipe, ctx.Response().Status(404).Json(http.Json{
+				"error":   "Recipe not found",
+				"message": "The requested recipe does not exist",
+			})
+		}
+		return recipe, ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve recipe",
+		})
+	}
+
+	return recipe, nil
+}
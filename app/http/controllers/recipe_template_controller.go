@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"slices"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type RecipeTemplateController struct {
+	// Dependent services
+	recipeTemplateService *services.RecipeTemplateService
+}
+
+func NewRecipeTemplateController() *RecipeTemplateController {
+	return &RecipeTemplateController{
+		// Inject services
+		recipeTemplateService: services.NewRecipeTemplateService(),
+	}
+}
+
+// RecipeTemplateLineRequest represents a product-level recipe line and its generation rules
+type RecipeTemplateLineRequest struct {
+	MaterialProductID uint    `json:"material_product_id" form:"material_product_id" validate:"required"`
+	MaterialVariantID *uint   `json:"material_variant_id" form:"material_variant_id"`
+	Quantity          float64 `json:"quantity" form:"quantity" validate:"required"`
+	Unit              string  `json:"unit" form:"unit" validate:"max_len:50"`
+	ScrapPercent      float64 `json:"scrap_percent" form:"scrap_percent"`
+	MatchAttribute    string  `json:"match_attribute" form:"match_attribute" validate:"max_len:100"`
+	MaterialAttribute string  `json:"material_attribute" form:"material_attribute" validate:"max_len:100"`
+	ScaleAttribute    string  `json:"scale_attribute" form:"scale_attribute" validate:"max_len:100"`
+	ScaleFactor       float64 `json:"scale_factor" form:"scale_factor"` // defaults to 1
+	Notes             string  `json:"notes" form:"notes"`
+}
+
+// UpdateRecipeTemplateRequest represents the template payload; the lines replace the current ones
+type UpdateRecipeTemplateRequest struct {
+	Lines []RecipeTemplateLineRequest `json:"lines" form:"lines"`
+}
+
+// GenerateRecipesRequest represents the recipe generation payload
+type GenerateRecipesRequest struct {
+	DryRun bool `json:"dry_run" form:"dry_run"`
+}
+
+// authUser returns the authenticated user with the role loaded
+func (r *RecipeTemplateController) authUser(ctx http.Context) (models.User, bool) {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return user, false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return user, true
+}
+
+// isMethodesOrAdmin checks if the authenticated user may define recipes
+func (r *RecipeTemplateController) isMethodesOrAdmin(ctx http.Context) bool {
+	user, ok := r.authUser(ctx)
+	return ok && (user.Role.Key == "admin" || user.Role.Key == "ingenieur_methodes")
+}
+
+// isRecipeViewer checks if the authenticated user may read recipes
+func (r *RecipeTemplateController) isRecipeViewer(ctx http.Context) bool {
+	user, ok := r.authUser(ctx)
+	return ok && slices.Contains([]string{"admin", "ingenieur_methodes", "magasinier", "achat", "commercial"}, user.Role.Key)
+}
+
+// Show returns the recipe template lines of a product
+func (r *RecipeTemplateController) Show(ctx http.Context) http.Response {
+	if !r.isRecipeViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes, Stock or Admin access required",
+		})
+	}
+
+	product, response := r.findProduct(ctx)
+	if response != nil {
+		return response
+	}
+
+	return r.respond(ctx, 200, product, "")
+}
+
+// Update replaces the recipe template lines of a product (methodes/admin)
+func (r *RecipeTemplateController) Update(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	product, response := r.findProduct(ctx)
+	if response != nil {
+		return response
+	}
+
+	var request UpdateRecipeTemplateRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	lines := make([]models.RecipeProduct, 0, len(request.Lines))
+	for _, line := range request.Lines {
+		lines = append(lines, models.RecipeProduct{
+			MaterialProductID: line.MaterialProductID,
+			MaterialVariantID: line.MaterialVariantID,
+			Quantity:          line.Quantity,
+			Unit:              line.Unit,
+			ScrapPercent:      line.ScrapPercent,
+			MatchAttribute:    line.MatchAttribute,
+			MaterialAttribute: line.MaterialAttribute,
+			ScaleAttribute:    line.ScaleAttribute,
+			ScaleFactor:       line.ScaleFactor,
+			Notes:             line.Notes,
+		})
+	}
+
+	if err := r.recipeTemplateService.Save(product, lines); err != nil {
+		var lineErr *services.InvalidTemplateLineError
+		if errors.As(err, &lineErr) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Validation failed",
+				"message": err.Error(),
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to save recipe template",
+		})
+	}
+
+	return r.respond(ctx, 200, product, "Recipe template updated successfully")
+}
+
+// Generate builds the recipes of all variants of a product from its template (methodes/admin).
+// A dry run only reports what would change.
+func (r *RecipeTemplateController) Generate(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	product, response := r.findProduct(ctx)
+	if response != nil {
+		return response
+	}
+
+	var request GenerateRecipesRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	generation, err := r.recipeTemplateService.Generate(product.ID, request.DryRun)
+	if err != nil {
+		if errors.Is(err, services.ErrEmptyRecipeTemplate) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Empty recipe template",
+				"message": "The product has no recipe template lines",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to generate recipes",
+		})
+	}
+
+	message := "Recipes generated successfully"
+	if request.DryRun {
+		message = "Dry run: no recipe was changed"
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":    message,
+		"generation": generation,
+	})
+}
+
+// findProduct loads the product referenced by the route
+func (r *RecipeTemplateController) findProduct(ctx http.Context) (models.Product, http.Response) {
+	var product models.Product
+	if err := facades.Orm().Query().Where("id", ctx.Request().Route("id")).FirstOrFail(&product); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return product, ctx.Response().Status(404).Json(http.Json{
+				"error":   "Product not found",
+				"message": "The requested product does not exist",
+			})
+		}
+		return product, ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve product",
+		})
+	}
+
+	return product, nil
+}
+
+// respond renders the template lines of a product, with a message when given
+func (r *RecipeTemplateController) respond(ctx http.Context, status int, product models.Product, message string) http.Response {
+	var lines []models.RecipeProduct
+	if err := facades.Orm().Query().With("MaterialProduct").With("MaterialVariant").
+		Where("product_id", product.ID).OrderBy("id").Find(&lines); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve recipe template",
+		})
+	}
+
+	body := http.Json{
+		"product_id": product.ID,
+		"lines":      lines,
+	}
+	if message != "" {
+		body["message"] = message
+	}
+
+	return ctx.Response().Status(status).Json(body)
+}
@@ -0,0 +1,373 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type ReorderRuleController struct {
+	// Dependent services
+	reorderService *services.ReorderService
+}
+
+func NewReorderRuleController() *ReorderRuleController {
+	return &ReorderRuleController{
+		// Inject services
+		reorderService: services.NewReorderService(),
+	}
+}
+
+// ReorderRuleRequest represents the reorder rule create and update payload
+type ReorderRuleRequest struct {
+	VariantID    uint    `json:"variant_id" form:"variant_id" validate:"required"`
+	LocationID   *uint   `json:"location_id" form:"location_id"`
+	MinQuantity  float64 `json:"min_quantity" form:"min_quantity"`
+	MaxQuantity  float64 `json:"max_quantity" form:"max_quantity"`
+	ReorderPoint float64 `json:"reorder_point" form:"reorder_point"`
+	IsActive     *bool   `json:"is_active" form:"is_active"`
+}
+
+// isStockPlanner checks if user can manage reorder rules
+func (r *ReorderRuleController) isStockPlanner(ctx http.Context) bool {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return slices.Contains([]string{"admin", "magasinier", "achat", "ingenieur_methodes"}, user.Role.Key)
+}
+
+// Index returns a paginated list of reorder rules
+func (r *ReorderRuleController) Index(ctx http.Context) http.Response {
+	if !r.isStockPlanner(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+
+	// Parse filter data
+	filterVariant := ctx.Request().Query("filterData[variant_id]", "")
+	filterLocation := ctx.Request().Query("filterData[location_id]", "")
+	filterActive := ctx.Request().Query("filterData[is_active]", "")
+
+	query := facades.Orm().Query().With("Variant.Product").With("Location")
+
+	// Apply specific filters
+	if filterVariant != "" {
+		query = query.Where("variant_id", filterVariant)
+	}
+	if filterLocation != "" {
+		query = query.Where("location_id", filterLocation)
+	}
+	if filterActive != "" {
+		query = query.Where("is_active", filterActive == "true")
+	}
+
+	query = query.OrderBy("variant_id", "asc")
+
+	var rules []models.ReorderRule
+
+	// Get total count
+	total, err := query.Model(&models.ReorderRule{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count reorder rules",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&rules); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve reorder rules",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"reorder_rules": rules,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Store creates a reorder rule on a raw material variant
+func (r *ReorderRuleController) Store(ctx http.Context) http.Response {
+	if !r.isStockPlanner(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	var request ReorderRuleRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	if response := r.validate(ctx, request, 0); response != nil {
+		return response
+	}
+
+	rule := models.ReorderRule{
+		VariantID:    request.VariantID,
+		LocationID:   request.LocationID,
+		MinQuantity:  request.MinQuantity,
+		MaxQuantity:  request.MaxQuantity,
+		ReorderPoint: request.ReorderPoint,
+		IsActive:     request.IsActive == nil || *request.IsActive,
+	}
+
+	if err := facades.Orm().Query().Create(&rule); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to create reorder rule",
+		})
+	}
+
+	facades.Orm().Query().With("Variant.Product").With("Location").Where("id", rule.ID).First(&rule)
+
+	return ctx.Response().Status(201).Json(http.Json{
+		"message":      "Reorder rule created successfully",
+		"reorder_rule": rule,
+	})
+}
+
+// Update updates a reorder rule
+func (r *ReorderRuleController) Update(ctx http.Context) http.Response {
+	if !r.isStockPlanner(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	rule, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	var request ReorderRuleRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	if response := r.validate(ctx, request, rule.ID); response != nil {
+		return response
+	}
+
+	rule.VariantID = request.VariantID
+	rule.LocationID = request.LocationID
+	rule.MinQuantity = request.MinQuantity
+	rule.MaxQuantity = request.MaxQuantity
+	rule.ReorderPoint = request.ReorderPoint
+	if request.IsActive != nil {
+		rule.IsActive = *request.IsActive
+	}
+	rule.Variant = models.ProductVariant{}
+	rule.Location = nil
+
+	if err := facades.Orm().Query().Save(&rule); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to update reorder rule",
+		})
+	}
+
+	facades.Orm().Query().With("Variant.Product").With("Location").Where("id", rule.ID).First(&rule)
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":      "Reorder rule updated successfully",
+		"reorder_rule": rule,
+	})
+}
+
+// Destroy deletes a reorder rule
+func (r *ReorderRuleController) Destroy(ctx http.Context) http.Response {
+	if !r.isStockPlanner(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	rule, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	// Keep the rule while its suggestions reference it, deactivating it instead
+	used, err := facades.Orm().Query().Model(&models.ReplenishmentSuggestion{}).Where("reorder_rule_id", rule.ID).Exists()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to check reorder rule usage",
+		})
+	}
+	if used {
+		if _, err := facades.Orm().Query().Model(&models.ReorderRule{}).Where("id", rule.ID).Update("is_active", false); err != nil {
+			return ctx.Response().Status(500).Json(http.Json{
+				"error":   "Database error",
+				"message": "Failed to deactivate reorder rule",
+			})
+		}
+		return ctx.Response().Status(200).Json(http.Json{
+			"message": "Reorder rule has suggestions and was deactivated instead of deleted",
+		})
+	}
+
+	if _, err := facades.Orm().Query().Delete(&rule); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to delete reorder rule",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message": "Reorder rule deleted successfully",
+	})
+}
+
+// LowStock returns the variants currently at or below their reorder point without
+// touching the stored suggestions
+func (r *ReorderRuleController) LowStock(ctx http.Context) http.Response {
+	if !r.isStockPlanner(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	low, rules, err := r.reorderService.Evaluate(facades.Orm().Query())
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to compute low-stock report",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"low_stock":     low,
+		"rules_checked": len(rules),
+	})
+}
+
+// validate checks a reorder rule payload
+func (r *ReorderRuleController) validate(ctx http.Context, request ReorderRuleRequest, ruleID uint) http.Response {
+	if request.VariantID == 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "variant_id is required",
+		})
+	}
+	if request.MinQuantity < 0 || request.ReorderPoint < request.MinQuantity || request.MaxQuantity < request.ReorderPoint {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Quantities must satisfy 0 <= min_quantity <= reorder_point <= max_quantity",
+		})
+	}
+
+	// Reorder points only apply to raw materials
+	var variant models.ProductVariant
+	if err := facades.Orm().Query().With("Product").Where("id", request.VariantID).FirstOrFail(&variant); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid variant",
+			"message": "The specified variant does not exist",
+		})
+	}
+	if !variant.Product.IsRawMaterial {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Invalid variant",
+			"message": "Reorder rules can only be defined on raw material variants",
+		})
+	}
+
+	if request.LocationID != nil {
+		var location models.StorageLocation
+		if err := facades.Orm().Query().Where("id", *request.LocationID).FirstOrFail(&location); err != nil {
+			return ctx.Response().Status(400).Json(http.Json{
+				"error":   "Invalid storage location",
+				"message": "The specified storage location does not exist",
+			})
+		}
+	}
+
+	// One rule per variant and location
+	query := facades.Orm().Query().Model(&models.ReorderRule{}).Where("variant_id", request.VariantID).Where("id <> ?", ruleID)
+	if request.LocationID != nil {
+		query = query.Where("location_id", *request.LocationID)
+	} else {
+		query = query.WhereNull("location_id")
+	}
+	exists, err := query.Exists()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to check existing reorder rules",
+		})
+	}
+	if exists {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Reorder rule already exists",
+			"message": "A reorder rule already exists for this variant and location",
+		})
+	}
+
+	return nil
+}
+
+// find loads the reorder rule referenced by the route
+func (r *ReorderRuleController) find(ctx http.Context) (models.ReorderRule, http.Response) {
+	var rule models.ReorderRule
+
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return rule, ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Reorder rule ID is required",
+		})
+	}
+
+	if err := facades.Orm().Query().Where("id", id).FirstOrFail(&rule); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return rule, ctx.Response().Status(404).Json(http.Json{
+				"error":   "Reorder rule not found",
+				"message": "The requested reorder rule does not exist",
+			})
+		}
+		return rule, ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve reorder rule",
+		})
+	}
+
+	return rule, nil
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type ReplenishmentSuggestionController struct {
+	// Dependent services
+}
+
+func NewReplenishmentSuggestionController() *ReplenishmentSuggestionController {
+	return &ReplenishmentSuggestionController{
+		// Inject services
+	}
+}
+
+// UpdateReplenishmentSuggestionRequest represents the suggestion handling payload
+type UpdateReplenishmentSuggestionRequest struct {
+	Status string `json:"status" form:"status" validate:"required"`
+}
+
+// isAchatOrAdmin checks if user handles replenishment
+func (r *ReplenishmentSuggestionController) isAchatOrAdmin(ctx http.Context) bool {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return user.Role.Key == "admin" || user.Role.Key == "achat"
+}
+
+// Index returns a paginated list of replenishment suggestions
+func (r *ReplenishmentSuggestionController) Index(ctx http.Context) http.Response {
+	if !r.isAchatOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Achat or Admin access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+
+	// Parse filter data
+	filterStatus := ctx.Request().Query("filterData[status]", services.SuggestionStatusDraft)
+	filterVariant := ctx.Request().Query("filterData[variant_id]", "")
+	filterCritical := ctx.Request().Query("filterData[is_critical]", "")
+
+	query := facades.Orm().Query().With("Product").With("Variant").With("Location").With("HandledByUser")
+
+	// Apply specific filters
+	if filterStatus != "" {
+		query = query.Where("status", filterStatus)
+	}
+	if filterVariant != "" {
+		query = query.Where("variant_id", filterVariant)
+	}
+	if filterCritical != "" {
+		query = query.Where("is_critical", filterCritical == "true")
+	}
+
+	query = query.OrderBy("is_critical", "desc").OrderBy("updated_at", "desc")
+
+	var suggestions []models.ReplenishmentSuggestion
+
+	// Get total count
+	total, err := query.Model(&models.ReplenishmentSuggestion{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count replenishment suggestions",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&suggestions); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve replenishment suggestions",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"replenishment_suggestions": suggestions,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Update marks a draft suggestion as ordered or dismissed
+func (r *ReplenishmentSuggestionController) Update(ctx http.Context) http.Response {
+	if !r.isAchatOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Achat or Admin access required",
+		})
+	}
+
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Replenishment suggestion ID is required",
+		})
+	}
+
+	var request UpdateReplenishmentSuggestionRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	validator, err := facades.Validation().Make(map[string]any{
+		"status": request.Status,
+	}, map[string]string{
+		"status": "required|in:ordered,dismissed",
+	})
+
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error": "Validation error",
+		})
+	}
+
+	if validator.Fails() {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":  "Validation failed",
+			"errors": validator.Errors().All(),
+		})
+	}
+
+	var suggestion models.ReplenishmentSuggestion
+	if err := facades.Orm().Query().Where("id", id).FirstOrFail(&suggestion); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Replenishment suggestion not found",
+				"message": "The requested replenishment suggestion does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve replenishment suggestion",
+		})
+	}
+
+	if suggestion.Status != services.SuggestionStatusDraft {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Suggestion already handled",
+			"message": "Only draft suggestions can be handled",
+		})
+	}
+
+	now := time.Now()
+	suggestion.Status = request.Status
+	suggestion.HandledBy = &user.ID
+	suggestion.HandledAt = &now
+
+	if err := facades.Orm().Query().Save(&suggestion); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to update replenishment suggestion",
+		})
+	}
+
+	facades.Orm().Query().With("Product").With("Variant").With("Location").With("HandledByUser").Where("id", suggestion.ID).First(&suggestion)
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":                  "Replenishment suggestion updated successfully",
+		"replenishment_suggestion": suggestion,
+	})
+}
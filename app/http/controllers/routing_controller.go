@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type RoutingController struct {
+	// Dependent services
+	routingService *services.RoutingService
+}
+
+func NewRoutingController() *RoutingController {
+	return &RoutingController{
+		// Inject services
+		routingService: services.NewRoutingService(),
+	}
+}
+
+// RoutingStepRequest represents an operation of a routing
+type RoutingStepRequest struct {
+	OperationID  uint    `json:"operation_id" form:"operation_id" validate:"required"`
+	Sequence     int     `json:"sequence" form:"sequence"` // defaults to the position in the list, by 10
+	SetupMinutes float64 `json:"setup_minutes" form:"setup_minutes"`
+	RunMinutes   float64 `json:"run_minutes" form:"run_minutes"` // per unit
+	Workstation  string  `json:"workstation" form:"workstation" validate:"max_len:100"`
+	RequiredRole string  `json:"required_role" form:"required_role" validate:"max_len:50"`
+	Notes        string  `json:"notes" form:"notes"`
+}
+
+// UpdateRoutingRequest represents the routing payload; the steps replace the current ones
+// of the product, or of the variant when given
+type UpdateRoutingRequest struct {
+	VariantID *uint                `json:"variant_id" form:"variant_id"`
+	Steps     []RoutingStepRequest `json:"steps" form:"steps"`
+}
+
+// authUser returns the authenticated user with its role loaded
+func (r *RoutingController) authUser(ctx http.Context) (models.User, bool) {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return user, false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return user, true
+}
+
+// isMethodesOrAdmin checks if the authenticated user may define routings
+func (r *RoutingController) isMethodesOrAdmin(ctx http.Context) bool {
+	user, ok := r.authUser(ctx)
+	return ok && (user.Role.Key == "admin" || user.Role.Key == "ingenieur_methodes")
+}
+
+// isRoutingViewer checks if the authenticated user may read routings
+func (r *RoutingController) isRoutingViewer(ctx http.Context) bool {
+	user, ok := r.authUser(ctx)
+	return ok && slices.Contains([]string{"admin", "ingenieur_methodes", "magasinier", "achat", "commercial"}, user.Role.Key)
+}
+
+// Show returns the routing of a product, or the one a variant follows (?variant_id=)
+func (r *RoutingController) Show(ctx http.Context) http.Response {
+	if !r.isRoutingViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes, Stock or Admin access required",
+		})
+	}
+
+	product, response := r.findProduct(ctx)
+	if response != nil {
+		return response
+	}
+
+	var variantID *uint
+	if value := ctx.Request().Query("variant_id", ""); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Validation failed",
+				"message": "variant_id must be a number",
+			})
+		}
+		variant := uint(id)
+		variantID = &variant
+	}
+
+	return r.respond(ctx, 200, product, variantID, "")
+}
+
+// Update replaces the routing of a product or of one of its variants (methodes/admin)
+func (r *RoutingController) Update(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	product, response := r.findProduct(ctx)
+	if response != nil {
+		return response
+	}
+
+	var request UpdateRoutingRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	steps := make([]models.RoutingStep, 0, len(request.Steps))
+	for _, step := range request.Steps {
+		steps = append(steps, models.RoutingStep{
+			OperationID:  step.OperationID,
+			Sequence:     step.Sequence,
+			SetupMinutes: step.SetupMinutes,
+			RunMinutes:   step.RunMinutes,
+			Workstation:  step.Workstation,
+			RequiredRole: step.RequiredRole,
+			Notes:        step.Notes,
+		})
+	}
+
+	if err := r.routingService.Save(product, request.VariantID, steps); err != nil {
+		var stepErr *services.InvalidRoutingStepError
+		if errors.As(err, &stepErr) || errors.Is(err, services.ErrRoutingVariantMismatch) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Validation failed",
+				"message": err.Error(),
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to save routing",
+		})
+	}
+
+	return r.respond(ctx, 200, product, request.VariantID, "Routing updated successfully")
+}
+
+// findProduct loads the product referenced by the route
+func (r *RoutingController) findProduct(ctx http.Context) (models.Product, http.Response) {
+	var product models.Product
+	if err := facades.Orm().Query().Where("id", ctx.Request().Route("id")).FirstOrFail(&product); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return product, ctx.Response().Status(404).Json(http.Json{
+				"error":   "Product not found",
+				"message": "The requested product does not exist",
+			})
+		}
+		return product, ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve product",
+		})
+	}
+
+	return product, nil
+}
+
+// respond renders the routing a product or variant follows, with a message when given.
+// variant_routing tells whether the steps are specific to the variant.
+func (r *RoutingController) respond(ctx http.Context, status int, product models.Product, variantID *uint, message string) http.Response {
+	steps, err := r.routingService.StepsFor(facades.Orm().Query(), product.ID, variantID)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve routing",
+		})
+	}
+
+	body := http.Json{
+		"product_id":      product.ID,
+		"variant_id":      variantID,
+		"variant_routing": len(steps) > 0 && steps[0].VariantID != nil,
+		"steps":           steps,
+	}
+	if message != "" {
+		body["message"] = message
+	}
+
+	return ctx.Response().Status(status).Json(body)
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type SerialNumberController struct {
+	// Dependent services
+	serialService *services.SerialService
+}
+
+func NewSerialNumberController() *SerialNumberController {
+	return &SerialNumberController{
+		// Inject services
+		serialService: services.NewSerialService(),
+	}
+}
+
+// isTraceViewer checks if user can trace serial numbers
+func (r *SerialNumberController) isTraceViewer(ctx http.Context) bool {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return slices.Contains([]string{"admin", "magasinier", "commercial", "ingenieur_methodes"}, user.Role.Key)
+}
+
+// Index returns a paginated list of serial numbers
+func (r *SerialNumberController) Index(ctx http.Context) http.Response {
+	if !r.isTraceViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Commercial, Methodes or Admin access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+	searchQuery := ctx.Request().Query("query", "")
+
+	// Parse filter data
+	filterProduct := ctx.Request().Query("filterData[product_id]", "")
+	filterOrder := ctx.Request().Query("filterData[order_fabrication_id]", "")
+	filterDelivery := ctx.Request().Query("filterData[delivery_id]", "")
+	filterStatus := ctx.Request().Query("filterData[status]", "")
+
+	query := facades.Orm().Query().With("Product").With("Variant").With("OrderFabrication").With("Location").With("Delivery.ClientSite")
+
+	// Apply search filter
+	if searchQuery != "" {
+		query = query.Where("serial_number LIKE ?", "%"+searchQuery+"%")
+	}
+
+	// Apply specific filters
+	if filterProduct != "" {
+		query = query.Where("product_id", filterProduct)
+	}
+	if filterOrder != "" {
+		query = query.Where("order_fabrication_id", filterOrder)
+	}
+	if filterDelivery != "" {
+		query = query.Where("delivery_id", filterDelivery)
+	}
+	if filterStatus != "" {
+		query = query.Where("status", filterStatus)
+	}
+
+	query = query.OrderBy("id", "desc")
+
+	var serials []models.SerialNumber
+
+	// Get total count
+	total, err := query.Model(&models.SerialNumber{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count serial numbers",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&serials); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve serial numbers",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"serial_numbers": serials,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Trace returns a serial number, looked up by its number, with the order that made it,
+// the operators, the material lots consumed and the delivery to the client site
+func (r *SerialNumberController) Trace(ctx http.Context) http.Response {
+	if !r.isTraceViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Commercial, Methodes or Admin access required",
+		})
+	}
+
+	var serial models.SerialNumber
+	if err := facades.Orm().Query().With("Product").With("Variant").With("Location").With("Movement").
+		With("OrderFabrication.Client").With("OrderFabrication.ClientSite").
+		With("Delivery.Client").With("Delivery.ClientSite").With("DeliveryMovement").
+		Where("serial_number", ctx.Request().Route("serial")).FirstOrFail(&serial); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Serial number not found",
+				"message": "The requested serial number does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve serial number",
+		})
+	}
+
+	trace, err := r.serialService.Trace(serial)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to trace serial number",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"trace": trace,
+	})
+}
@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type StandardCostController struct {
+	// Dependent services
+	costService *services.CostService
+}
+
+func NewStandardCostController() *StandardCostController {
+	return &StandardCostController{
+		// Inject services
+		costService: services.NewCostService(),
+	}
+}
+
+// RollUpStandardCostsRequest represents the bulk roll-up payload
+type RollUpStandardCostsRequest struct {
+	ProductID *uint  `json:"product_id" form:"product_id"`
+	Source    string `json:"source" form:"source"`
+}
+
+// authUser returns the authenticated user with its role loaded
+func (r *StandardCostController) authUser(ctx http.Context) (models.User, bool) {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return user, false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return user, true
+}
+
+// isMethodesOrAdmin checks if the authenticated user may compute standard costs
+func (r *StandardCostController) isMethodesOrAdmin(ctx http.Context) bool {
+	user, ok := r.authUser(ctx)
+	return ok && (user.Role.Key == "admin" || user.Role.Key == "ingenieur_methodes")
+}
+
+// isCostViewer checks if the authenticated user may read costs and margins
+func (r *StandardCostController) isCostViewer(ctx http.Context) bool {
+	user, ok := r.authUser(ctx)
+	return ok && slices.Contains([]string{"admin", "ingenieur_methodes", "achat", "commercial"}, user.Role.Key)
+}
+
+// Index returns the margin report: the stored standard costs against the selling prices
+func (r *StandardCostController) Index(ctx http.Context) http.Response {
+	if !r.isCostViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes, Achat, Commercial or Admin access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+	searchQuery := ctx.Request().Query("query", "")
+
+	// Parse filter data
+	filterProduct := ctx.Request().Query("filterData[product_id]", "")
+	filterSource := ctx.Request().Query("filterData[cost_source]", "")
+
+	query := facades.Orm().Query().With("Product").With("Variant").With("RecipeVariant")
+
+	// Apply search filter on the variant title and SKU
+	if searchQuery != "" {
+		query = query.Where("variant_id IN (SELECT id FROM product_variants WHERE title LIKE ? OR sku LIKE ?)",
+			"%"+searchQuery+"%", "%"+searchQuery+"%")
+	}
+
+	// Apply specific filters
+	if filterProduct != "" {
+		query = query.Where("product_id", filterProduct)
+	}
+	if filterSource != "" {
+		query = query.Where("cost_source", filterSource)
+	}
+
+	query = query.OrderBy("variant_id", "asc")
+
+	var costs []models.StandardCost
+
+	// Get total count
+	total, err := query.Model(&models.StandardCost{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count standard costs",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&costs); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve standard costs",
+		})
+	}
+
+	margins := make([]services.MarginLine, 0, len(costs))
+	for _, cost := range costs {
+		margins = append(margins, r.costService.Margin(cost))
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"margins": margins,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// RollUp returns the cost breakdown of one unit of a variant without storing it (?source=)
+func (r *StandardCostController) RollUp(ctx http.Context) http.Response {
+	if !r.isCostViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes, Achat, Commercial or Admin access required",
+		})
+	}
+
+	variant, response := r.findVariant(ctx)
+	if response != nil {
+		return response
+	}
+
+	rollUp, err := r.costService.RollUp(variant.ID, ctx.Request().Query("source", ""))
+	if err != nil {
+		return r.rollUpError(ctx, err)
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"cost_roll_up": rollUp,
+	})
+}
+
+// Store rolls up the cost of a variant and stores it as its standard cost (methodes/admin)
+func (r *StandardCostController) Store(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	variant, response := r.findVariant(ctx)
+	if response != nil {
+		return response
+	}
+
+	cost, rollUp, err := r.costService.Save(variant.ID, ctx.Request().Input("source", ""))
+	if err != nil {
+		return r.rollUpError(ctx, err)
+	}
+
+	facades.Orm().Query().With("Product").With("Variant").With("RecipeVariant").Where("id", cost.ID).First(cost)
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":      "Standard cost updated successfully",
+		"margin":       r.costService.Margin(*cost),
+		"cost_roll_up": rollUp,
+	})
+}
+
+// RollUpAll recomputes the standard costs of all variants with a recipe, or of one product
+// (methodes/admin)
+func (r *StandardCostController) RollUpAll(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	var request RollUpStandardCostsRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	costs, failures, err := r.costService.RollUpAll(request.ProductID, request.Source)
+	if err != nil {
+		return r.rollUpError(ctx, err)
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":  "Standard costs updated successfully",
+		"updated":  len(costs),
+		"failures": failures,
+	})
+}
+
+// findVariant loads the variant referenced by the route
+func (r *StandardCostController) findVariant(ctx http.Context) (models.ProductVariant, http.Response) {
+	var variant models.ProductVariant
+	if err := facades.Orm().Query().Where("id", ctx.Request().Route("id")).FirstOrFail(&variant); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return variant, ctx.Response().Status(404).Json(http.Json{
+				"error":   "Variant not found",
+				"message": "The requested variant does not exist",
+			})
+		}
+		return variant, ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve variant",
+		})
+	}
+
+	return variant, nil
+}
+
+// rollUpError renders the errors returned by the cost roll-up
+func (r *StandardCostController) rollUpError(ctx http.Context, err error) http.Response {
+	if errors.Is(err, services.ErrInvalidCostSource) {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "source must be one of purchase, valuation",
+		})
+	}
+	if errors.Is(err, services.ErrMissingRecipe) {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Missing recipe",
+			"message": "The variant has no recipe in effect",
+		})
+	}
+	var cycleErr *services.RecipeCycleError
+	if errors.As(err, &cycleErr) {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Recipe cycle",
+			"message": "The recipes lead back to a variant they are made from",
+			"path":    cycleErr.Path,
+		})
+	}
+	var unitErr *services.UnitConversionError
+	if errors.Is(err, services.ErrInvalidRecipeOutput) || errors.As(err, &unitErr) {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Response().Status(500).Json(http.Json{
+		"error":   "Database error",
+		"message": "Failed to roll up costs",
+	})
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type StockHistoryController struct {
+	// Dependent services
+	historyService *services.StockHistoryService
+}
+
+func NewStockHistoryController() *StockHistoryController {
+	return &StockHistoryController{
+		// Inject services
+		historyService: services.NewStockHistoryService(),
+	}
+}
+
+// authUser returns the authenticated user with the role loaded
+func (r *StockHistoryController) authUser(ctx http.Context) (models.User, bool) {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return user, false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return user, true
+}
+
+// isStockViewer checks if user can read the stock history
+func (r *StockHistoryController) isStockViewer(ctx http.Context) bool {
+	user, ok := r.authUser(ctx)
+	return ok && slices.Contains([]string{"admin", "magasinier", "achat", "ingenieur_methodes"}, user.Role.Key)
+}
+
+// Snapshot returns the quantities per product or variant and location at a point in time,
+// replayed from the movement ledger
+func (r *StockHistoryController) Snapshot(ctx http.Context) http.Response {
+	if !r.isStockViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	at, ok := parseStockTime(ctx.Request().Query("at", ""))
+	if !ok {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "at must be a YYYY-MM-DD date or an RFC3339 timestamp",
+		})
+	}
+
+	// Parse filter data
+	var filter services.StockSnapshotFilter
+	for key, target := range map[string]**uint{
+		"product_id":  &filter.ProductID,
+		"variant_id":  &filter.VariantID,
+		"location_id": &filter.LocationID,
+	} {
+		value := ctx.Request().Query("filterData["+key+"]", "")
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Validation failed",
+				"message": key + " must be numeric",
+			})
+		}
+		parsed := uint(id)
+		*target = &parsed
+	}
+
+	lines, err := r.historyService.Snapshot(at, filter)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to replay stock movements",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"at":    at,
+		"stock": lines,
+	})
+}
+
+// Consistency compares the stock levels with the movement ledger
+func (r *StockHistoryController) Consistency(ctx http.Context) http.Response {
+	if !r.isStockViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	drifts, err := r.historyService.CheckConsistency()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to check stock consistency",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"consistent": len(drifts) == 0,
+		"drifts":     drifts,
+	})
+}
+
+// Repair realigns drifted stock levels, cost layers and lot balances on the movement ledger (admin only)
+func (r *StockHistoryController) Repair(ctx http.Context) http.Response {
+	user, ok := r.authUser(ctx)
+	if !ok || user.Role.Key != "admin" {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Admin access required",
+		})
+	}
+
+	repaired, err := r.historyService.Repair()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":    "Database error",
+			"message":  "Failed to repair stock levels",
+			"repaired": repaired,
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":  "Stock levels realigned on the movement ledger",
+		"repaired": repaired,
+	})
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type StockLevelController struct {
+	// Dependent services
+	reservationService *services.ReservationService
+}
+
+func NewStockLevelController() *StockLevelController {
+	return &StockLevelController{
+		// Inject services
+		reservationService: services.NewReservationService(),
+	}
+}
+
+// stockLevelSortKeys lists the columns the index may be sorted by
+var stockLevelSortKeys = map[string]bool{
+	"quantity":    true,
+	"product_id":  true,
+	"location_id": true,
+	"updated_at":  true,
+}
+
+// isStockViewer checks if user can read stock levels
+func (r *StockLevelController) isStockViewer(ctx http.Context) bool {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return slices.Contains([]string{"admin", "magasinier", "achat", "ingenieur_methodes"}, user.Role.Key)
+}
+
+// Index returns paginated stock levels with their reserved and available quantities
+func (r *StockLevelController) Index(ctx http.Context) http.Response {
+	if !r.isStockViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+	searchQuery := ctx.Request().Query("query", "")
+	sortKey := ctx.Request().Query("sort[key]", "product_id")
+	sortOrder := ctx.Request().Query("sort[order]", "asc")
+
+	// Parse filter data
+	filterProduct := ctx.Request().Query("filterData[product_id]", "")
+	filterVariant := ctx.Request().Query("filterData[variant_id]", "")
+	filterLocation := ctx.Request().Query("filterData[location_id]", "")
+	filterInStock := ctx.Request().Query("filterData[in_stock]", "")
+
+	query := facades.Orm().Query().With("Product").With("Variant").With("Location")
+
+	// Apply search filter on the product
+	if searchQuery != "" {
+		query = query.Where("product_id IN (SELECT id FROM products WHERE title LIKE ? OR sku LIKE ?)",
+			"%"+searchQuery+"%", "%"+searchQuery+"%")
+	}
+
+	// Apply specific filters
+	if filterProduct != "" {
+		query = query.Where("product_id", filterProduct)
+	}
+	if filterVariant != "" {
+		query = query.Where("variant_id", filterVariant)
+	}
+	if filterLocation != "" {
+		query = query.Where("location_id", filterLocation)
+	}
+	if filterInStock == "true" {
+		query = query.Where("quantity > ?", 0)
+	}
+
+	// Apply sorting
+	if stockLevelSortKeys[sortKey] && (sortOrder == "asc" || sortOrder == "desc") {
+		query = query.OrderBy(sortKey, sortOrder)
+	} else {
+		query = query.OrderBy("product_id", "asc")
+	}
+
+	var levels []models.StockLevel
+
+	// Get total count
+	total, err := query.Model(&models.StockLevel{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count stock levels",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&levels); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve stock levels",
+		})
+	}
+
+	if err := r.reservationService.WithAvailability(levels); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to compute available quantities",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"stock_levels": levels,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
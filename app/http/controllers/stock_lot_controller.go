@@ -0,0 +1,323 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type StockLotController struct {
+	// Dependent services
+	lotService *services.LotService
+}
+
+func NewStockLotController() *StockLotController {
+	return &StockLotController{
+		// Inject services
+		lotService: services.NewLotService(),
+	}
+}
+
+// UpdateStockLotRequest represents the mill certificate details of a lot
+type UpdateStockLotRequest struct {
+	HeatNumber     string `json:"heat_number" form:"heat_number" validate:"max_len:100"`
+	Supplier       string `json:"supplier" form:"supplier" validate:"max_len:255"`
+	CertificateURL string `json:"certificate_url" form:"certificate_url" validate:"max_len:500"`
+	Notes          string `json:"notes" form:"notes"`
+}
+
+// stockLotSortKeys lists the columns the index may be sorted by
+var stockLotSortKeys = map[string]bool{
+	"lot_number":  true,
+	"heat_number": true,
+	"received_at": true,
+	"created_at":  true,
+}
+
+// authUser returns the authenticated user with the role loaded
+func (r *StockLotController) authUser(ctx http.Context) (models.User, bool) {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return user, false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return user, true
+}
+
+// isStockViewer checks if user can read lots and their genealogy
+func (r *StockLotController) isStockViewer(ctx http.Context) bool {
+	user, ok := r.authUser(ctx)
+	return ok && slices.Contains([]string{"admin", "magasinier", "achat", "ingenieur_methodes", "commercial"}, user.Role.Key)
+}
+
+// Index returns a paginated list of lots
+func (r *StockLotController) Index(ctx http.Context) http.Response {
+	if !r.isStockViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Stock or sales access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+	searchQuery := ctx.Request().Query("query", "")
+	sortKey := ctx.Request().Query("sort[key]", "received_at")
+	sortOrder := ctx.Request().Query("sort[order]", "desc")
+
+	// Parse filter data
+	filterProduct := ctx.Request().Query("filterData[product_id]", "")
+	filterVariant := ctx.Request().Query("filterData[variant_id]", "")
+	filterLocation := ctx.Request().Query("filterData[location_id]", "")
+	filterInStock := ctx.Request().Query("filterData[in_stock]", "")
+
+	query := facades.Orm().Query().With("Product").With("Variant").With("Balances.Location")
+
+	// Apply search filter on lot, heat number and supplier
+	if searchQuery != "" {
+		query = query.Where("lot_number LIKE ? OR heat_number LIKE ? OR supplier LIKE ?",
+			"%"+searchQuery+"%", "%"+searchQuery+"%", "%"+searchQuery+"%")
+	}
+
+	// Apply specific filters
+	if filterProduct != "" {
+		query = query.Where("product_id", filterProduct)
+	}
+	if filterVariant != "" {
+		query = query.Where("variant_id", filterVariant)
+	}
+	if filterLocation != "" {
+		query = query.Where("id IN (SELECT lot_id FROM stock_lot_balances WHERE location_id = ? AND quantity > 0)", filterLocation)
+	}
+	if filterInStock == "true" {
+		query = query.Where("id IN (SELECT lot_id FROM stock_lot_balances WHERE quantity > 0)")
+	}
+
+	// Apply sorting
+	if stockLotSortKeys[sortKey] && (sortOrder == "asc" || sortOrder == "desc") {
+		query = query.OrderBy(sortKey, sortOrder)
+	} else {
+		query = query.OrderBy("received_at", "desc")
+	}
+
+	var lots []models.StockLot
+
+	// Get total count
+	total, err := query.Model(&models.StockLot{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count lots",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&lots); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve lots",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"stock_lots": lots,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Show returns a lot with its balances per location
+func (r *StockLotController) Show(ctx http.Context) http.Response {
+	if !r.isStockViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Stock or sales access required",
+		})
+	}
+
+	lot, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"stock_lot": lot,
+	})
+}
+
+// Update completes the mill certificate details of a lot (magasinier/achat/admin)
+func (r *StockLotController) Update(ctx http.Context) http.Response {
+	user, ok := r.authUser(ctx)
+	if !ok || !slices.Contains([]string{"admin", "magasinier", "achat"}, user.Role.Key) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat or Admin access required",
+		})
+	}
+
+	lot, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	var request UpdateStockLotRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	validator, err := facades.Validation().Make(map[string]any{
+		"heat_number":     request.HeatNumber,
+		"supplier":        request.Supplier,
+		"certificate_url": request.CertificateURL,
+	}, map[string]string{
+		"heat_number":     "max_len:100",
+		"supplier":        "max_len:255",
+		"certificate_url": "max_len:500",
+	})
+
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error": "Validation error",
+		})
+	}
+
+	if validator.Fails() {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":  "Validation failed",
+			"errors": validator.Errors().All(),
+		})
+	}
+
+	if _, err := facades.Orm().Query().Model(&models.StockLot{}).Where("id", lot.ID).Update(map[string]any{
+		"heat_number":     request.HeatNumber,
+		"supplier":        request.Supplier,
+		"certificate_url": request.CertificateURL,
+		"notes":           request.Notes,
+	}); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to update lot",
+		})
+	}
+
+	lot, response = r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":   "Lot updated successfully",
+		"stock_lot": lot,
+	})
+}
+
+// Genealogy returns the movements of a lot and the manufacturing orders and clients that received it
+func (r *StockLotController) Genealogy(ctx http.Context) http.Response {
+	if !r.isStockViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Stock or sales access required",
+		})
+	}
+
+	lot, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	genealogy, err := r.lotService.Genealogy(lot)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to trace lot",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"genealogy": genealogy,
+	})
+}
+
+// OrderLots returns the lots consumed by a manufacturing order
+func (r *StockLotController) OrderLots(ctx http.Context) http.Response {
+	if !r.isStockViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Stock or sales access required",
+		})
+	}
+
+	var order models.OrderFabrication
+	if err := facades.Orm().Query().With("Client").With("ClientSite").Where("id", ctx.Request().Route("id")).FirstOrFail(&order); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Order fabrication not found",
+				"message": "The requested order fabrication does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve order fabrication",
+		})
+	}
+
+	lots, err := r.lotService.OrderLots(order)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to trace order lots",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"order_fabrication": order,
+		"lots":              lots,
+	})
+}
+
+// find loads the lot referenced by the route
+func (r *StockLotController) find(ctx http.Context) (models.StockLot, http.Response) {
+	var lot models.StockLot
+
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return lot, ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Lot ID is required",
+		})
+	}
+
+	if err := facades.Orm().Query().With("Product").With("Variant").With("Creator").With("Balances.Location").
+		Where("id", id).FirstOrFail(&lot); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return lot, ctx.Response().Status(404).Json(http.Json{
+				"error":   "Lot not found",
+				"message": "The requested lot does not exist",
+			})
+		}
+		return lot, ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve lot",
+		})
+	}
+
+	return lot, nil
+}
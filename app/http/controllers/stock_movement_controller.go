@@ -0,0 +1,499 @@
+package controllers
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type StockMovementController struct {
+	// Dependent services
+	stockService       *services.StockService
+	reservationService *services.ReservationService
+	lotService         *services.LotService
+}
+
+func NewStockMovementController() *StockMovementController {
+	return &StockMovementController{
+		// Inject services
+		stockService:       services.NewStockService(),
+		reservationService: services.NewReservationService(),
+		lotService:         services.NewLotService(),
+	}
+}
+
+// CreateStockMovementRequest represents the stock movement posting payload
+type CreateStockMovementRequest struct {
+	ProductID     uint     `json:"product_id" form:"product_id" validate:"required"`
+	VariantID     *uint    `json:"variant_id" form:"variant_id"`
+	LocationID    uint     `json:"location_id" form:"location_id" validate:"required"`
+	MovementType  string   `json:"movement_type" form:"movement_type" validate:"required"`
+	Quantity      float64  `json:"quantity" form:"quantity" validate:"required"`
+	UnitCost      *float64 `json:"unit_cost" form:"unit_cost"`
+	Unit          string   `json:"unit" form:"unit" validate:"max_len:50"`
+	ReferenceType string   `json:"reference_type" form:"reference_type" validate:"max_len:50"`
+	ReferenceID   *uint    `json:"reference_id" form:"reference_id"`
+	Notes         string   `json:"notes" form:"notes"`
+
+	// Lot of a raw material variant: an existing lot, or the mill certificate details of
+	// the lot created by a receipt
+	LotID          *uint  `json:"lot_id" form:"lot_id"`
+	LotNumber      string `json:"lot_number" form:"lot_number" validate:"max_len:100"`
+	HeatNumber     string `json:"heat_number" form:"heat_number" validate:"max_len:100"`
+	Supplier       string `json:"supplier" form:"supplier" validate:"max_len:255"`
+	CertificateURL string `json:"certificate_url" form:"certificate_url" validate:"max_len:500"`
+}
+
+// stockMovementSortKeys lists the columns the ledger may be sorted by
+var stockMovementSortKeys = map[string]bool{
+	"created_at":    true,
+	"movement_type": true,
+	"quantity":      true,
+	"product_id":    true,
+	"location_id":   true,
+}
+
+// isStockViewer checks if user can read the stock ledger
+func (r *StockMovementController) isStockViewer(ctx http.Context) bool {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return slices.Contains([]string{"admin", "magasinier", "achat", "ingenieur_methodes"}, user.Role.Key)
+}
+
+// isMagasinierOrAdmin checks if user can post stock movements
+func (r *StockMovementController) isMagasinierOrAdmin(ctx http.Context) bool {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return user.Role.Key == "admin" || user.Role.Key == "magasinier"
+}
+
+// Index returns the paginated stock movement ledger with filtering
+func (r *StockMovementController) Index(ctx http.Context) http.Response {
+	if !r.isStockViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+	searchQuery := ctx.Request().Query("query", "")
+	sortKey := ctx.Request().Query("sort[key]", "created_at")
+	sortOrder := ctx.Request().Query("sort[order]", "desc")
+
+	// Parse filter data
+	filterProduct := ctx.Request().Query("filterData[product_id]", "")
+	filterVariant := ctx.Request().Query("filterData[variant_id]", "")
+	filterLocation := ctx.Request().Query("filterData[location_id]", "")
+	filterType := ctx.Request().Query("filterData[movement_type]", "")
+	filterReferenceType := ctx.Request().Query("filterData[reference_type]", "")
+	filterReferenceID := ctx.Request().Query("filterData[reference_id]", "")
+	filterDateFrom := ctx.Request().Query("filterData[date_from]", "")
+	filterDateTo := ctx.Request().Query("filterData[date_to]", "")
+
+	query := facades.Orm().Query().With("Product").With("Variant").With("Location").With("Creator")
+
+	// Apply search filter
+	if searchQuery != "" {
+		query = query.Where("notes LIKE ? OR reference_type LIKE ?",
+			"%"+searchQuery+"%", "%"+searchQuery+"%")
+	}
+
+	// Apply specific filters
+	if filterProduct != "" {
+		query = query.Where("product_id", filterProduct)
+	}
+	if filterVariant != "" {
+		query = query.Where("variant_id", filterVariant)
+	}
+	if filterLocation != "" {
+		query = query.Where("location_id", filterLocation)
+	}
+	if filterType != "" {
+		query = query.Where("movement_type", filterType)
+	}
+	if filterReferenceType != "" {
+		query = query.Where("reference_type", filterReferenceType)
+	}
+	if filterReferenceID != "" {
+		query = query.Where("reference_id", filterReferenceID)
+	}
+	if filterDateFrom != "" {
+		dateFrom, err := time.Parse("2006-01-02", filterDateFrom)
+		if err != nil {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Validation failed",
+				"message": "date_from must be in YYYY-MM-DD format",
+			})
+		}
+		query = query.Where("created_at >= ?", dateFrom)
+	}
+	if filterDateTo != "" {
+		dateTo, err := time.Parse("2006-01-02", filterDateTo)
+		if err != nil {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Validation failed",
+				"message": "date_to must be in YYYY-MM-DD format",
+			})
+		}
+		// The end date is inclusive
+		query = query.Where("created_at < ?", dateTo.AddDate(0, 0, 1))
+	}
+
+	// Apply sorting
+	if stockMovementSortKeys[sortKey] && (sortOrder == "asc" || sortOrder == "desc") {
+		query = query.OrderBy(sortKey, sortOrder).OrderBy("id", sortOrder)
+	} else {
+		query = query.OrderBy("created_at", "desc").OrderBy("id", "desc")
+	}
+
+	var movements []models.StockMovement
+
+	// Get total count
+	total, err := query.Model(&models.StockMovement{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count stock movements",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&movements); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve stock movements",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"stock_movements": movements,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Show returns a specific stock movement by ID
+func (r *StockMovementController) Show(ctx http.Context) http.Response {
+	if !r.isStockViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Stock movement ID is required",
+		})
+	}
+
+	var movement models.StockMovement
+	if err := facades.Orm().Query().With("Product").With("Variant").With("Location").With("Creator").With("Lots.Lot").Where("id", id).FirstOrFail(&movement); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Stock movement not found",
+				"message": "The requested stock movement does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve stock movement",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"stock_movement": movement,
+	})
+}
+
+// Store posts a stock movement and updates the matching stock level
+func (r *StockMovementController) Store(ctx http.Context) http.Response {
+	if !r.isMagasinierOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier or Admin access required",
+		})
+	}
+
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+
+	var request CreateStockMovementRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	// Validate input
+	validator, err := facades.Validation().Make(map[string]any{
+		"product_id":      request.ProductID,
+		"location_id":     request.LocationID,
+		"movement_type":   request.MovementType,
+		"quantity":        request.Quantity,
+		"unit":            request.Unit,
+		"reference_type":  request.ReferenceType,
+		"lot_number":      request.LotNumber,
+		"heat_number":     request.HeatNumber,
+		"supplier":        request.Supplier,
+		"certificate_url": request.CertificateURL,
+	}, map[string]string{
+		"product_id":      "required|numeric",
+		"location_id":     "required|numeric",
+		"movement_type":   "required|in:in,out,adjustment",
+		"quantity":        "required|numeric",
+		"unit":            "max_len:50",
+		"reference_type":  "max_len:50",
+		"lot_number":      "max_len:100",
+		"heat_number":     "max_len:100",
+		"supplier":        "max_len:255",
+		"certificate_url": "max_len:500",
+	})
+
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error": "Validation error",
+		})
+	}
+
+	if validator.Fails() {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":  "Validation failed",
+			"errors": validator.Errors().All(),
+		})
+	}
+
+	// Adjustments are signed, in and out movements are always positive
+	if request.Quantity == 0 || (request.MovementType != services.MovementTypeAdjustment && request.Quantity < 0) {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Quantity must be greater than zero, or non-zero for adjustments",
+		})
+	}
+
+	if request.UnitCost != nil && *request.UnitCost < 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "unit_cost must be zero or greater",
+		})
+	}
+
+	if request.ReferenceID != nil && request.ReferenceType == "" {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "reference_type is required when reference_id is set",
+		})
+	}
+
+	// Verify product exists
+	var product models.Product
+	if err := facades.Orm().Query().Where("id", request.ProductID).FirstOrFail(&product); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid product",
+			"message": "The specified product does not exist",
+		})
+	}
+
+	// Verify variant belongs to the product
+	if request.VariantID != nil {
+		var variant models.ProductVariant
+		if err := facades.Orm().Query().Where("id", *request.VariantID).Where("product_id", request.ProductID).FirstOrFail(&variant); err != nil {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Invalid variant",
+				"message": "The specified variant does not belong to the product",
+			})
+		}
+		if request.Unit == "" {
+			request.Unit = variant.Unit
+		}
+	}
+	if request.Unit == "" {
+		request.Unit = product.Unit
+	}
+
+	// Verify storage location exists
+	var location models.StorageLocation
+	if err := facades.Orm().Query().Where("id", request.LocationID).FirstOrFail(&location); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid storage location",
+			"message": "The specified storage location does not exist",
+		})
+	}
+
+	movement := models.StockMovement{
+		ProductID:     request.ProductID,
+		VariantID:     request.VariantID,
+		LocationID:    request.LocationID,
+		MovementType:  request.MovementType,
+		Quantity:      request.Quantity,
+		UnitCost:      request.UnitCost,
+		Unit:          request.Unit,
+		ReferenceType: request.ReferenceType,
+		ReferenceID:   request.ReferenceID,
+		Notes:         request.Notes,
+		CreatedBy:     user.ID,
+	}
+
+	// Raw material variants are held per lot
+	tracked, err := r.stockService.IsLotTracked(facades.Orm().Query(), request.ProductID, request.VariantID)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to check lot tracking",
+		})
+	}
+	newLot := request.LotNumber != "" || request.HeatNumber != "" || request.Supplier != "" || request.CertificateURL != ""
+	if !tracked && (request.LotID != nil || newLot) {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Lots are only tracked on raw material variants",
+		})
+	}
+	if request.LotID != nil && newLot {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Give either lot_id or the details of a new lot, not both",
+		})
+	}
+	if newLot && request.MovementType != services.MovementTypeIn {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Lots are created by in movements only",
+		})
+	}
+	if request.LotNumber != "" {
+		exists, err := facades.Orm().Query().Model(&models.StockLot{}).Where("lot_number", request.LotNumber).Exists()
+		if err != nil {
+			return ctx.Response().Status(500).Json(http.Json{
+				"error":   "Database error",
+				"message": "Failed to check lot number",
+			})
+		}
+		if exists {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":   "Lot already exists",
+				"message": "A lot with this number already exists, post the movement with its lot_id",
+			})
+		}
+	}
+
+	var level *models.StockLevel
+	switch {
+	case tracked && request.LotID == nil && request.MovementType == services.MovementTypeIn:
+		lot := models.StockLot{
+			LotNumber:      request.LotNumber,
+			HeatNumber:     request.HeatNumber,
+			Supplier:       request.Supplier,
+			CertificateURL: request.CertificateURL,
+			Notes:          request.Notes,
+		}
+		level, err = r.lotService.Receive(&lot, &movement)
+	case request.LotID != nil:
+		movement.Lots = []models.StockMovementLot{{LotID: *request.LotID, Quantity: math.Abs(request.Quantity)}}
+		level, err = r.stockService.PostMovement(&movement)
+	default:
+		level, err = r.stockService.PostMovement(&movement)
+	}
+	if err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) || errors.Is(err, services.ErrLotMismatch) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Invalid lot",
+				"message": "The specified lot does not exist for this variant",
+			})
+		}
+
+		var lotErr *services.InsufficientLotError
+		if errors.As(err, &lotErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":     "Insufficient lot stock",
+				"message":   "Lot " + lotErr.LotNumber + " does not hold enough stock at this location",
+				"available": lotErr.Available,
+				"requested": lotErr.Requested,
+			})
+		}
+
+		var countErr *services.CountInProgressError
+		if errors.As(err, &countErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":        "Inventory count in progress",
+				"message":      "Movements are blocked on the location until inventory count " + countErr.CountNumber + " is closed",
+				"count_number": countErr.CountNumber,
+			})
+		}
+
+		var unitErr *services.UnitConversionError
+		if errors.As(err, &unitErr) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Invalid unit",
+				"message": "Quantity in " + unitErr.From + " cannot be converted to the stock unit " + unitErr.To,
+			})
+		}
+
+		var locationErr *services.LocationNotStockableError
+		if errors.As(err, &locationErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":   "Location cannot hold stock",
+				"message": "Storage location " + locationErr.Name + " is not a bin able to hold stock",
+			})
+		}
+
+		var stockErr *services.InsufficientStockError
+		if errors.As(err, &stockErr) {
+			return ctx.Response().Status(409).Json(http.Json{
+				"error":     "Insufficient stock",
+				"message":   "The movement would take the stock level below zero",
+				"available": stockErr.Available,
+				"requested": stockErr.Requested,
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to post stock movement",
+		})
+	}
+
+	// Load relationships for response
+	facades.Orm().Query().With("Product").With("Variant").With("Location").With("Creator").With("Lots.Lot").Where("id", movement.ID).First(&movement)
+	levels := []models.StockLevel{*level}
+	r.reservationService.WithAvailability(levels)
+
+	return ctx.Response().Status(201).Json(http.Json{
+		"message":        "Stock movement posted successfully",
+		"stock_movement": movement,
+		"stock_level":    levels[0],
+	})
+}
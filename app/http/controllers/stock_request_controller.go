@@ -0,0 +1,551 @@
+package controllers
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type StockRequestController struct {
+	// Dependent services
+	stockRequestService *services.StockRequestService
+	unitService         *services.UnitService
+}
+
+func NewStockRequestController() *StockRequestController {
+	return &StockRequestController{
+		// Inject services
+		stockRequestService: services.NewStockRequestService(),
+		unitService:         services.NewUnitService(),
+	}
+}
+
+// CreateStockRequestRequest represents the stock request creation payload
+type CreateStockRequestRequest struct {
+	ProductID          uint    `json:"product_id" form:"product_id" validate:"required"`
+	VariantID          *uint   `json:"variant_id" form:"variant_id"`
+	LocationID         uint    `json:"location_id" form:"location_id" validate:"required"`
+	OrderFabricationID *uint   `json:"order_fabrication_id" form:"order_fabrication_id"`
+	Quantity           float64 `json:"quantity" form:"quantity" validate:"required"`
+	Unit               string  `json:"unit" form:"unit" validate:"max_len:50"`
+	Priority           string  `json:"priority" form:"priority"`
+	Notes              string  `json:"notes" form:"notes"`
+}
+
+// RejectStockRequestRequest represents the stock request rejection payload
+type RejectStockRequestRequest struct {
+	Reason string `json:"reason" form:"reason" validate:"required"`
+	Notes  string `json:"notes" form:"notes"`
+}
+
+// stockRequestSortKeys lists the columns the index may be sorted by
+var stockRequestSortKeys = map[string]bool{
+	"request_number": true,
+	"quantity":       true,
+	"status":         true,
+	"priority":       true,
+	"requested_at":   true,
+	"approved_at":    true,
+	"created_at":     true,
+}
+
+// authUser returns the authenticated user with its role loaded
+func (r *StockRequestController) authUser(ctx http.Context) (models.User, error) {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return user, err
+	}
+
+	if err := facades.Orm().Query().With("Role").Where("id", user.ID).FirstOrFail(&user); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// Index returns a paginated list of stock requests with search and filtering
+func (r *StockRequestController) Index(ctx http.Context) http.Response {
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+	if !slices.Contains([]string{"admin", "magasinier", "achat", "ingenieur_methodes"}, user.Role.Key) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+	searchQuery := ctx.Request().Query("query", "")
+	sortKey := ctx.Request().Query("sort[key]", "requested_at")
+	sortOrder := ctx.Request().Query("sort[order]", "desc")
+
+	// Parse filter data
+	filterStatus := ctx.Request().Query("filterData[status]", "")
+	filterPriority := ctx.Request().Query("filterData[priority]", "")
+	filterProduct := ctx.Request().Query("filterData[product_id]", "")
+	filterVariant := ctx.Request().Query("filterData[variant_id]", "")
+	filterLocation := ctx.Request().Query("filterData[location_id]", "")
+	filterOrder := ctx.Request().Query("filterData[order_fabrication_id]", "")
+	filterRejectionReason := ctx.Request().Query("filterData[rejection_reason]", "")
+
+	query := facades.Orm().Query().With("Product").With("Variant").With("Location").With("OrderFabrication").With("RequestedByUser")
+
+	// Achat only handles requests rejected because of a shortage
+	if user.Role.Key == "achat" {
+		query = query.Where("status", services.StockRequestStatusRejected).Where("rejection_reason", services.RejectionReasonShortage)
+	}
+
+	// Apply search filter
+	if searchQuery != "" {
+		query = query.Where("request_number LIKE ? OR notes LIKE ?",
+			"%"+searchQuery+"%", "%"+searchQuery+"%")
+	}
+
+	// Apply specific filters
+	if filterStatus != "" {
+		query = query.Where("status", filterStatus)
+	}
+	if filterPriority != "" {
+		query = query.Where("priority", filterPriority)
+	}
+	if filterProduct != "" {
+		query = query.Where("product_id", filterProduct)
+	}
+	if filterVariant != "" {
+		query = query.Where("variant_id", filterVariant)
+	}
+	if filterLocation != "" {
+		query = query.Where("location_id", filterLocation)
+	}
+	if filterOrder != "" {
+		query = query.Where("order_fabrication_id", filterOrder)
+	}
+	if filterRejectionReason != "" {
+		query = query.Where("rejection_reason", filterRejectionReason)
+	}
+
+	// Apply sorting
+	if stockRequestSortKeys[sortKey] && (sortOrder == "asc" || sortOrder == "desc") {
+		query = query.OrderBy(sortKey, sortOrder)
+	} else {
+		query = query.OrderBy("requested_at", "desc")
+	}
+
+	var stockRequests []models.StockRequest
+
+	// Get total count
+	total, err := query.Model(&models.StockRequest{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count stock requests",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&stockRequests); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve stock requests",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"stock_requests": stockRequests,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Show returns a specific stock request by ID
+func (r *StockRequestController) Show(ctx http.Context) http.Response {
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+	if !slices.Contains([]string{"admin", "magasinier", "achat", "ingenieur_methodes"}, user.Role.Key) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	stockRequest, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	if user.Role.Key == "achat" && (stockRequest.Status != services.StockRequestStatusRejected || stockRequest.RejectionReason != services.RejectionReasonShortage) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Achat only has access to requests rejected for shortage",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"stock_request": stockRequest,
+	})
+}
+
+// Store creates a new stock request
+func (r *StockRequestController) Store(ctx http.Context) http.Response {
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+	if !slices.Contains([]string{"admin", "magasinier", "ingenieur_methodes"}, user.Role.Key) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Methodes or Admin access required",
+		})
+	}
+
+	var request CreateStockRequestRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	if request.Priority == "" {
+		request.Priority = "medium"
+	}
+
+	// Validate input
+	validator, err := facades.Validation().Make(map[string]any{
+		"product_id":  request.ProductID,
+		"location_id": request.LocationID,
+		"quantity":    request.Quantity,
+		"unit":        request.Unit,
+		"priority":    request.Priority,
+	}, map[string]string{
+		"product_id":  "required|numeric",
+		"location_id": "required|numeric",
+		"quantity":    "required|numeric",
+		"unit":        "max_len:50",
+		"priority":    "in:low,medium,high,urgent",
+	})
+
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error": "Validation error",
+		})
+	}
+
+	if validator.Fails() {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":  "Validation failed",
+			"errors": validator.Errors().All(),
+		})
+	}
+
+	if request.Quantity <= 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Quantity must be greater than zero",
+		})
+	}
+
+	// Verify product exists
+	var product models.Product
+	if err := facades.Orm().Query().Where("id", request.ProductID).FirstOrFail(&product); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid product",
+			"message": "The specified product does not exist",
+		})
+	}
+
+	// Verify variant belongs to the product
+	stockUnit := product.Unit
+	if request.VariantID != nil {
+		var variant models.ProductVariant
+		if err := facades.Orm().Query().Where("id", *request.VariantID).Where("product_id", request.ProductID).FirstOrFail(&variant); err != nil {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Invalid variant",
+				"message": "The specified variant does not belong to the product",
+			})
+		}
+		if variant.Unit != "" {
+			stockUnit = variant.Unit
+		}
+	}
+	if request.Unit == "" {
+		request.Unit = stockUnit
+	}
+
+	// A request in another unit must be convertible to the stock unit when it is served
+	if _, err := r.unitService.Convert(facades.Orm().Query(), request.ProductID, request.VariantID, request.Quantity, request.Unit, stockUnit); err != nil {
+		var unitErr *services.UnitConversionError
+		if errors.As(err, &unitErr) {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Invalid unit",
+				"message": "Quantity in " + unitErr.From + " cannot be converted to the stock unit " + unitErr.To,
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to check request unit",
+		})
+	}
+
+	// Verify storage location exists
+	var location models.StorageLocation
+	if err := facades.Orm().Query().Where("id", request.LocationID).FirstOrFail(&location); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid storage location",
+			"message": "The specified storage location does not exist",
+		})
+	}
+
+	// Verify manufacturing order exists if provided
+	if request.OrderFabricationID != nil {
+		var order models.OrderFabrication
+		if err := facades.Orm().Query().Where("id", *request.OrderFabricationID).FirstOrFail(&order); err != nil {
+			return ctx.Response().Status(400).Json(http.Json{
+				"error":   "Invalid manufacturing order",
+				"message": "The specified manufacturing order does not exist",
+			})
+		}
+	}
+
+	stockRequest := models.StockRequest{
+		OrderFabricationID: request.OrderFabricationID,
+		ProductID:          request.ProductID,
+		VariantID:          request.VariantID,
+		LocationID:         request.LocationID,
+		Quantity:           request.Quantity,
+		Unit:               request.Unit,
+		Priority:           request.Priority,
+		Notes:              request.Notes,
+		RequestedBy:        user.ID,
+	}
+
+	if err := r.stockRequestService.Create(&stockRequest); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to create stock request",
+		})
+	}
+
+	// Load relationships for response
+	facades.Orm().Query().With("Product").With("Variant").With("Location").With("OrderFabrication").With("RequestedByUser").Where("id", stockRequest.ID).First(&stockRequest)
+
+	return ctx.Response().Status(201).Json(http.Json{
+		"message":       "Stock request created successfully",
+		"stock_request": stockRequest,
+	})
+}
+
+// Approve approves a pending stock request
+func (r *StockRequestController) Approve(ctx http.Context) http.Response {
+	user, response := r.magasinier(ctx)
+	if response != nil {
+		return response
+	}
+
+	stockRequest, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	if err := r.stockRequestService.Approve(&stockRequest, user); err != nil {
+		return r.workflowError(ctx, err, "Failed to approve stock request")
+	}
+
+	return r.respond(ctx, stockRequest.ID, "Stock request approved successfully")
+}
+
+// Reject rejects a pending stock request
+func (r *StockRequestController) Reject(ctx http.Context) http.Response {
+	user, response := r.magasinier(ctx)
+	if response != nil {
+		return response
+	}
+
+	var request RejectStockRequestRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	validator, err := facades.Validation().Make(map[string]any{
+		"reason": request.Reason,
+	}, map[string]string{
+		"reason": "required|in:shortage,other",
+	})
+
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error": "Validation error",
+		})
+	}
+
+	if validator.Fails() {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":  "Validation failed",
+			"errors": validator.Errors().All(),
+		})
+	}
+
+	stockRequest, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	if err := r.stockRequestService.Reject(&stockRequest, user, request.Reason, request.Notes); err != nil {
+		return r.workflowError(ctx, err, "Failed to reject stock request")
+	}
+
+	return r.respond(ctx, stockRequest.ID, "Stock request rejected successfully")
+}
+
+// Fulfil serves an approved stock request from its location
+func (r *StockRequestController) Fulfil(ctx http.Context) http.Response {
+	user, response := r.magasinier(ctx)
+	if response != nil {
+		return response
+	}
+
+	stockRequest, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	if err := r.stockRequestService.Fulfil(&stockRequest, user); err != nil {
+		return r.workflowError(ctx, err, "Failed to fulfil stock request")
+	}
+
+	return r.respond(ctx, stockRequest.ID, "Stock request fulfilled successfully")
+}
+
+// magasinier returns the authenticated user if it may approve and fulfil requests
+func (r *StockRequestController) magasinier(ctx http.Context) (models.User, http.Response) {
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return user, ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+	if user.Role.Key != "admin" && user.Role.Key != "magasinier" {
+		return user, ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier or Admin access required",
+		})
+	}
+
+	return user, nil
+}
+
+// find loads the stock request referenced by the route
+func (r *StockRequestController) find(ctx http.Context) (models.StockRequest, http.Response) {
+	var stockRequest models.StockRequest
+
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return stockRequest, ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Stock request ID is required",
+		})
+	}
+
+	if err := facades.Orm().Query().With("Product").With("Variant").With("Location").With("OrderFabrication").With("Requirement").With("RequestedByUser").With("ApprovedByUser").Where("id", id).FirstOrFail(&stockRequest); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return stockRequest, ctx.Response().Status(404).Json(http.Json{
+				"error":   "Stock request not found",
+				"message": "The requested stock request does not exist",
+			})
+		}
+		return stockRequest, ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve stock request",
+		})
+	}
+
+	return stockRequest, nil
+}
+
+// respond reloads a stock request and renders it with a message
+func (r *StockRequestController) respond(ctx http.Context, id uint, message string) http.Response {
+	var stockRequest models.StockRequest
+	facades.Orm().Query().With("Product").With("Variant").With("Location").With("OrderFabrication").With("RequestedByUser").With("ApprovedByUser").Where("id", id).First(&stockRequest)
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message":       message,
+		"stock_request": stockRequest,
+	})
+}
+
+// workflowError renders the errors returned by the stock request workflow
+func (r *StockRequestController) workflowError(ctx http.Context, err error, message string) http.Response {
+	var statusErr *services.InvalidRequestStatusError
+	if errors.As(err, &statusErr) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Invalid stock request status",
+			"message": "Stock request must be " + statusErr.Expected + " but is " + statusErr.Status,
+		})
+	}
+
+	var countErr *services.CountInProgressError
+	if errors.As(err, &countErr) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":        "Inventory count in progress",
+			"message":      "Movements are blocked on the location until inventory count " + countErr.CountNumber + " is closed",
+			"count_number": countErr.CountNumber,
+		})
+	}
+
+	var unitErr *services.UnitConversionError
+	if errors.As(err, &unitErr) {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Invalid unit",
+			"message": "Quantity in " + unitErr.From + " cannot be converted to the stock unit " + unitErr.To,
+		})
+	}
+
+	var stockErr *services.InsufficientStockError
+	if errors.As(err, &stockErr) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":     "Insufficient stock",
+			"message":   "Not enough stock at the request location",
+			"available": stockErr.Available,
+			"requested": stockErr.Requested,
+		})
+	}
+
+	return ctx.Response().Status(500).Json(http.Json{
+		"error":   "Database error",
+		"message": message,
+	})
+}
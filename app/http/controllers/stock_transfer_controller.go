@@ -0,0 +1,452 @@
+package controllers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type StockTransferController struct {
+	// Dependent services
+	stockTransferService *services.StockTransferService
+}
+
+func NewStockTransferController() *StockTransferController {
+	return &StockTransferController{
+		// Inject services
+		stockTransferService: services.NewStockTransferService(),
+	}
+}
+
+// StockTransferItemRequest represents one line of a transfer
+type StockTransferItemRequest struct {
+	ProductID uint    `json:"product_id" form:"product_id"`
+	VariantID *uint   `json:"variant_id" form:"variant_id"`
+	Quantity  float64 `json:"quantity" form:"quantity"`
+	Unit      string  `json:"unit" form:"unit"`
+}
+
+// CreateStockTransferRequest represents the stock transfer creation payload
+type CreateStockTransferRequest struct {
+	FromLocationID uint                       `json:"from_location_id" form:"from_location_id" validate:"required"`
+	ToLocationID   uint                       `json:"to_location_id" form:"to_location_id" validate:"required"`
+	Notes          string                     `json:"notes" form:"notes"`
+	Items          []StockTransferItemRequest `json:"items" form:"items" validate:"required"`
+}
+
+// stockTransferSortKeys lists the columns the index may be sorted by
+var stockTransferSortKeys = map[string]bool{
+	"transfer_number": true,
+	"status":          true,
+	"dispatched_at":   true,
+	"received_at":     true,
+	"created_at":      true,
+}
+
+// authUser returns the authenticated user with its role loaded
+func (r *StockTransferController) authUser(ctx http.Context) (models.User, error) {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return user, err
+	}
+
+	if err := facades.Orm().Query().With("Role").Where("id", user.ID).FirstOrFail(&user); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// Index returns a paginated list of stock transfers with search and filtering
+func (r *StockTransferController) Index(ctx http.Context) http.Response {
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+	if !slices.Contains([]string{"admin", "magasinier", "achat", "ingenieur_methodes"}, user.Role.Key) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	// Parse query parameters
+	pageIndex, _ := strconv.Atoi(ctx.Request().Query("pageIndex", "1"))
+	pageSize, _ := strconv.Atoi(ctx.Request().Query("pageSize", "10"))
+	searchQuery := ctx.Request().Query("query", "")
+	sortKey := ctx.Request().Query("sort[key]", "created_at")
+	sortOrder := ctx.Request().Query("sort[order]", "desc")
+
+	// Parse filter data
+	filterStatus := ctx.Request().Query("filterData[status]", "")
+	filterFromLocation := ctx.Request().Query("filterData[from_location_id]", "")
+	filterToLocation := ctx.Request().Query("filterData[to_location_id]", "")
+
+	query := facades.Orm().Query().With("FromLocation").With("ToLocation").With("Creator")
+
+	// Apply search filter
+	if searchQuery != "" {
+		query = query.Where("transfer_number LIKE ? OR notes LIKE ?",
+			"%"+searchQuery+"%", "%"+searchQuery+"%")
+	}
+
+	// Apply specific filters
+	if filterStatus != "" {
+		query = query.Where("status", filterStatus)
+	}
+	if filterFromLocation != "" {
+		query = query.Where("from_location_id", filterFromLocation)
+	}
+	if filterToLocation != "" {
+		query = query.Where("to_location_id", filterToLocation)
+	}
+
+	// Apply sorting
+	if stockTransferSortKeys[sortKey] && (sortOrder == "asc" || sortOrder == "desc") {
+		query = query.OrderBy(sortKey, sortOrder)
+	} else {
+		query = query.OrderBy("created_at", "desc")
+	}
+
+	var transfers []models.StockTransfer
+
+	// Get total count
+	total, err := query.Model(&models.StockTransfer{}).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to count stock transfers",
+		})
+	}
+
+	// Get paginated results
+	offset := (pageIndex - 1) * pageSize
+	if err := query.Offset(offset).Limit(pageSize).Find(&transfers); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve stock transfers",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"stock_transfers": transfers,
+		"pagination": http.Json{
+			"current_page": pageIndex,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// Show returns a specific stock transfer with its lines
+func (r *StockTransferController) Show(ctx http.Context) http.Response {
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+	if !slices.Contains([]string{"admin", "magasinier", "achat", "ingenieur_methodes"}, user.Role.Key) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	transfer, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"stock_transfer": transfer,
+	})
+}
+
+// Store creates a draft stock transfer
+func (r *StockTransferController) Store(ctx http.Context) http.Response {
+	user, response := r.magasinier(ctx)
+	if response != nil {
+		return response
+	}
+
+	var request CreateStockTransferRequest
+
+	// Validate request
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	if request.FromLocationID == 0 || request.ToLocationID == 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "from_location_id and to_location_id are required",
+		})
+	}
+	if request.FromLocationID == request.ToLocationID {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "Source and destination locations must differ",
+		})
+	}
+	if len(request.Items) == 0 {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "At least one item is required",
+		})
+	}
+
+	// Verify both storage locations exist
+	count, err := facades.Orm().Query().Model(&models.StorageLocation{}).Where("id IN ?", []uint{request.FromLocationID, request.ToLocationID}).Count()
+	if err != nil || count != 2 {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid storage location",
+			"message": "The specified storage locations do not exist",
+		})
+	}
+
+	items := make([]models.StockTransferItem, 0, len(request.Items))
+	for i, item := range request.Items {
+		if item.ProductID == 0 || item.Quantity <= 0 {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Validation failed",
+				"message": fmt.Sprintf("Item %d requires a product and a quantity greater than zero", i+1),
+			})
+		}
+
+		var product models.Product
+		if err := facades.Orm().Query().Where("id", item.ProductID).FirstOrFail(&product); err != nil {
+			return ctx.Response().Status(400).Json(http.Json{
+				"error":   "Invalid product",
+				"message": fmt.Sprintf("The product of item %d does not exist", i+1),
+			})
+		}
+
+		unit := item.Unit
+		if item.VariantID != nil {
+			var variant models.ProductVariant
+			if err := facades.Orm().Query().Where("id", *item.VariantID).Where("product_id", item.ProductID).FirstOrFail(&variant); err != nil {
+				return ctx.Response().Status(422).Json(http.Json{
+					"error":   "Invalid variant",
+					"message": fmt.Sprintf("The variant of item %d does not belong to the product", i+1),
+				})
+			}
+			if unit == "" {
+				unit = variant.Unit
+			}
+		}
+		if unit == "" {
+			unit = product.Unit
+		}
+
+		items = append(items, models.StockTransferItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Unit:      unit,
+		})
+	}
+
+	transfer := models.StockTransfer{
+		FromLocationID: request.FromLocationID,
+		ToLocationID:   request.ToLocationID,
+		Notes:          request.Notes,
+		CreatedBy:      user.ID,
+	}
+
+	if err := r.stockTransferService.Create(&transfer, items); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to create stock transfer",
+		})
+	}
+
+	return r.respond(ctx, 201, transfer.ID, "Stock transfer created successfully")
+}
+
+// Dispatch posts the out movements at the source location and puts the transfer in transit
+func (r *StockTransferController) Dispatch(ctx http.Context) http.Response {
+	user, response := r.magasinier(ctx)
+	if response != nil {
+		return response
+	}
+
+	transfer, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	if err := r.stockTransferService.Dispatch(&transfer, user); err != nil {
+		return r.workflowError(ctx, err, "Failed to dispatch stock transfer")
+	}
+
+	return r.respond(ctx, 200, transfer.ID, "Stock transfer dispatched successfully")
+}
+
+// Receive posts the in movements at the destination location
+func (r *StockTransferController) Receive(ctx http.Context) http.Response {
+	user, response := r.magasinier(ctx)
+	if response != nil {
+		return response
+	}
+
+	transfer, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	if err := r.stockTransferService.Receive(&transfer, user); err != nil {
+		return r.workflowError(ctx, err, "Failed to receive stock transfer")
+	}
+
+	return r.respond(ctx, 200, transfer.ID, "Stock transfer received successfully")
+}
+
+// Cancel cancels a draft or in transit transfer
+func (r *StockTransferController) Cancel(ctx http.Context) http.Response {
+	user, response := r.magasinier(ctx)
+	if response != nil {
+		return response
+	}
+
+	transfer, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	if err := r.stockTransferService.Cancel(&transfer, user); err != nil {
+		return r.workflowError(ctx, err, "Failed to cancel stock transfer")
+	}
+
+	return r.respond(ctx, 200, transfer.ID, "Stock transfer cancelled successfully")
+}
+
+// magasinier returns the authenticated user if it may manage transfers
+func (r *StockTransferController) magasinier(ctx http.Context) (models.User, http.Response) {
+	user, err := r.authUser(ctx)
+	if err != nil {
+		return user, ctx.Response().Status(401).Json(http.Json{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+	}
+	if user.Role.Key != "admin" && user.Role.Key != "magasinier" {
+		return user, ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier or Admin access required",
+		})
+	}
+
+	return user, nil
+}
+
+// find loads the stock transfer referenced by the route
+func (r *StockTransferController) find(ctx http.Context) (models.StockTransfer, http.Response) {
+	var transfer models.StockTransfer
+
+	id := ctx.Request().Route("id")
+	if id == "" {
+		return transfer, ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request",
+			"message": "Stock transfer ID is required",
+		})
+	}
+
+	if err := facades.Orm().Query().With("FromLocation").With("ToLocation").With("Creator").With("DispatchedByUser").With("ReceivedByUser").
+		With("StockTransferItems.Product").With("StockTransferItems.Variant").Where("id", id).FirstOrFail(&transfer); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return transfer, ctx.Response().Status(404).Json(http.Json{
+				"error":   "Stock transfer not found",
+				"message": "The requested stock transfer does not exist",
+			})
+		}
+		return transfer, ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve stock transfer",
+		})
+	}
+
+	return transfer, nil
+}
+
+// respond reloads a stock transfer and renders it with a message
+func (r *StockTransferController) respond(ctx http.Context, status int, id uint, message string) http.Response {
+	var transfer models.StockTransfer
+	facades.Orm().Query().With("FromLocation").With("ToLocation").With("Creator").With("DispatchedByUser").With("ReceivedByUser").
+		With("StockTransferItems.Product").With("StockTransferItems.Variant").Where("id", id).First(&transfer)
+
+	return ctx.Response().Status(status).Json(http.Json{
+		"message":        message,
+		"stock_transfer": transfer,
+	})
+}
+
+// workflowError renders the errors returned by the transfer workflow
+func (r *StockTransferController) workflowError(ctx http.Context, err error, message string) http.Response {
+	var statusErr *services.InvalidTransferStatusError
+	if errors.As(err, &statusErr) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":            "Invalid stock transfer status",
+			"message":          "The stock transfer is " + statusErr.Status,
+			"current_status":   statusErr.Status,
+			"allowed_statuses": statusErr.Expected,
+		})
+	}
+
+	var countErr *services.CountInProgressError
+	if errors.As(err, &countErr) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":        "Inventory count in progress",
+			"message":      "Movements are blocked on the location until inventory count " + countErr.CountNumber + " is closed",
+			"count_number": countErr.CountNumber,
+		})
+	}
+
+	var unitErr *services.UnitConversionError
+	if errors.As(err, &unitErr) {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Invalid unit",
+			"message": "Quantity in " + unitErr.From + " cannot be converted to the stock unit " + unitErr.To,
+		})
+	}
+
+	var locationErr *services.LocationNotStockableError
+	if errors.As(err, &locationErr) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Location cannot hold stock",
+			"message": "Storage location " + locationErr.Name + " is not a bin able to hold stock",
+		})
+	}
+
+	var stockErr *services.InsufficientStockError
+	if errors.As(err, &stockErr) {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":      "Insufficient stock",
+			"message":    "Not enough stock at the source location",
+			"product_id": stockErr.ProductID,
+			"variant_id": stockErr.VariantID,
+			"available":  stockErr.Available,
+			"requested":  stockErr.Requested,
+		})
+	}
+
+	return ctx.Response().Status(500).Json(http.Json{
+		"error":   "Database error",
+		"message": message,
+	})
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"slices"
+	"time"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type StockValuationController struct {
+	// Dependent services
+	valuationService *services.ValuationService
+}
+
+func NewStockValuationController() *StockValuationController {
+	return &StockValuationController{
+		// Inject services
+		valuationService: services.NewValuationService(),
+	}
+}
+
+// isStockViewer checks if user can read the stock valuation
+func (r *StockValuationController) isStockViewer(ctx http.Context) bool {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return slices.Contains([]string{"admin", "magasinier", "achat", "ingenieur_methodes"}, user.Role.Key)
+}
+
+// Index returns the stock value per location, category and variant at a point in time
+func (r *StockValuationController) Index(ctx http.Context) http.Response {
+	if !r.isStockViewer(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Magasinier, Achat, Methodes or Admin access required",
+		})
+	}
+
+	at, ok := parseStockTime(ctx.Request().Query("at", ""))
+	if !ok {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "at must be a YYYY-MM-DD date or an RFC3339 timestamp",
+		})
+	}
+
+	report, err := r.valuationService.Report(at)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to compute stock valuation",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"valuation": report,
+	})
+}
+
+// parseStockTime reads the point in time of a stock report, defaulting to now. A date
+// alone stands for the end of that day.
+func parseStockTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Now(), true
+	}
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, true
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return date.AddDate(0, 0, 1).Add(-time.Nanosecond), true
+}
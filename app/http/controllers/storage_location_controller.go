@@ -8,15 +8,18 @@ import (
 	"github.com/goravel/framework/facades"
 
 	"pms/app/models"
+	"pms/app/services"
 )
 
 type StorageLocationController struct {
 	// Dependent services
+	locationService *services.LocationService
 }
 
 func NewStorageLocationController() *StorageLocationController {
 	return &StorageLocationController{
 		// Inject services
+		locationService: services.NewLocationService(),
 	}
 }
 
@@ -24,12 +27,16 @@ func NewStorageLocationController() *StorageLocationController {
 type CreateStorageLocationRequest struct {
 	Name        string `json:"name" form:"name" validate:"required|min_len:2|max_len:255"`
 	Description string `json:"description" form:"description"`
+	ParentID    *uint  `json:"parent_id" form:"parent_id"`
+	Type        string `json:"type" form:"type"`
 }
 
 // UpdateStorageLocationRequest represents the storage location update request payload
 type UpdateStorageLocationRequest struct {
 	Name        string `json:"name" form:"name" validate:"min_len:2|max_len:255"`
 	Description string `json:"description" form:"description"`
+	ParentID    *uint  `json:"parent_id" form:"parent_id"`
+	Type        string `json:"type" form:"type"`
 }
 
 // isMethodesOrAdmin checks if the authenticated user is methodes or admin
@@ -66,6 +73,8 @@ func (r *StorageLocationController) Index(ctx http.Context) http.Response {
 
 	// Parse filter data
 	filterName := ctx.Request().Query("filterData[name]", "")
+	filterParent := ctx.Request().Query("filterData[parent_id]", "")
+	filterType := ctx.Request().Query("filterData[type]", "")
 
 	query := facades.Orm().Query()
 
@@ -79,6 +88,14 @@ func (r *StorageLocationController) Index(ctx http.Context) http.Response {
 	if filterName != "" {
 		query = query.Where("name LIKE ?", "%"+filterName+"%")
 	}
+	if filterParent == "root" {
+		query = query.WhereNull("parent_id")
+	} else if filterParent != "" {
+		query = query.Where("parent_id", filterParent)
+	}
+	if filterType != "" {
+		query = query.Where("type", filterType)
+	}
 
 	// Apply sorting
 	if sortKey != "" && (sortOrder == "asc" || sortOrder == "desc") {
@@ -136,7 +153,7 @@ func (r *StorageLocationController) Show(ctx http.Context) http.Response {
 	}
 
 	var storageLocation models.StorageLocation
-	if err := facades.Orm().Query().Where("id", id).First(&storageLocation); err != nil {
+	if err := facades.Orm().Query().With("Parent").With("Children").Where("id", id).FirstOrFail(&storageLocation); err != nil {
 		if errors.Is(err, errors.OrmRecordNotFound) {
 			return ctx.Response().Status(404).Json(http.Json{
 				"error":   "Storage location not found",
@@ -204,10 +221,19 @@ func (r *StorageLocationController) Store(ctx http.Context) http.Response {
 		})
 	}
 
+	if request.Type == "" {
+		request.Type = models.StorageLocationTypeBin
+	}
+	if response := r.placementResponse(ctx, r.locationService.ValidatePlacement(0, request.Type, request.ParentID)); response != nil {
+		return response
+	}
+
 	// Create new storage location
 	storageLocation := models.StorageLocation{
 		Name:        request.Name,
 		Description: request.Description,
+		ParentID:    request.ParentID,
+		Type:        request.Type,
 	}
 
 	if err := facades.Orm().Query().Create(&storageLocation); err != nil {
@@ -305,6 +331,17 @@ func (r *StorageLocationController) Update(ctx http.Context) http.Response {
 		}
 	}
 
+	// The parent only changes when parent_id is sent; null moves the location to the root
+	if request.Type == "" {
+		request.Type = storageLocation.Type
+	}
+	if _, ok := ctx.Request().All()["parent_id"]; !ok {
+		request.ParentID = storageLocation.ParentID
+	}
+	if response := r.placementResponse(ctx, r.locationService.ValidatePlacement(storageLocation.ID, request.Type, request.ParentID)); response != nil {
+		return response
+	}
+
 	// Update storage location fields
 	if request.Name != "" {
 		storageLocation.Name = request.Name
@@ -312,6 +349,8 @@ func (r *StorageLocationController) Update(ctx http.Context) http.Response {
 	if request.Description != "" {
 		storageLocation.Description = request.Description
 	}
+	storageLocation.ParentID = request.ParentID
+	storageLocation.Type = request.Type
 
 	// Save changes
 	if err := facades.Orm().Query().Save(&storageLocation); err != nil {
@@ -359,6 +398,21 @@ func (r *StorageLocationController) Destroy(ctx http.Context) http.Response {
 		})
 	}
 
+	// Check if storage location has any child locations
+	childCount, err := facades.Orm().Query().Model(&models.StorageLocation{}).Where("parent_id", id).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to check storage location children",
+		})
+	}
+	if childCount > 0 {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Cannot delete storage location",
+			"message": "Storage location has child locations and cannot be deleted",
+		})
+	}
+
 	// Check if storage location has any products
 	productCount, err := facades.Orm().Query().Model(&models.Product{}).Where("location_id", id).Count()
 	if err != nil {
@@ -452,3 +506,88 @@ func (r *StorageLocationController) Select(ctx http.Context) http.Response {
 		"options": options,
 	})
 }
+
+// Tree returns the storage locations nested from the warehouses down to the bins, with the
+// number of products held and the stock value of every subtree
+func (r *StorageLocationController) Tree(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	tree, err := r.locationService.Tree()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve storage location tree",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"storage_locations": tree,
+	})
+}
+
+// Stock returns the stock held by a storage location and all the locations below it
+func (r *StorageLocationController) Stock(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	var storageLocation models.StorageLocation
+	if err := facades.Orm().Query().Where("id", ctx.Request().Route("id")).FirstOrFail(&storageLocation); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Storage location not found",
+				"message": "The requested storage location does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve storage location",
+		})
+	}
+
+	lines, err := r.locationService.SubtreeStock(storageLocation.ID)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve storage location stock",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"storage_location": storageLocation,
+		"stock":            lines,
+	})
+}
+
+// placementResponse maps the errors of the location tree checks to a response
+func (r *StorageLocationController) placementResponse(ctx http.Context, err error) http.Response {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, services.ErrInvalidLocationType), errors.Is(err, services.ErrLocationTypeOrder),
+		errors.Is(err, services.ErrLocationParentMissing):
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": err.Error(),
+		})
+	case errors.Is(err, services.ErrLocationCycle), errors.Is(err, services.ErrParentHoldsStock),
+		errors.Is(err, services.ErrLocationChildType), errors.Is(err, services.ErrLocationHoldsStock):
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Invalid location hierarchy",
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Response().Status(500).Json(http.Json{
+		"error":   "Database error",
+		"message": "Failed to check storage location hierarchy",
+	})
+}
@@ -0,0 +1,415 @@
+package controllers
+
+import (
+	"slices"
+
+	"github.com/goravel/framework/contracts/http"
+	"github.com/goravel/framework/errors"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+	"pms/app/services"
+)
+
+type UnitController struct {
+	// Dependent services
+	unitService *services.UnitService
+}
+
+func NewUnitController() *UnitController {
+	return &UnitController{
+		// Inject services
+		unitService: services.NewUnitService(),
+	}
+}
+
+// UnitRequest represents the unit creation and update payload
+type UnitRequest struct {
+	Code     string  `json:"code" form:"code" validate:"required|max_len:50"`
+	Name     string  `json:"name" form:"name" validate:"required|max_len:100"`
+	Category string  `json:"category" form:"category" validate:"required"`
+	Factor   float64 `json:"factor" form:"factor"`
+}
+
+// ProductUnitConversionRequest represents a product conversion, e.g. 1 sheet = 31.4 kg
+type ProductUnitConversionRequest struct {
+	VariantID *uint   `json:"variant_id" form:"variant_id"`
+	FromUnit  string  `json:"from_unit" form:"from_unit" validate:"required"`
+	ToUnit    string  `json:"to_unit" form:"to_unit" validate:"required"`
+	Factor    float64 `json:"factor" form:"factor" validate:"required"`
+	Notes     string  `json:"notes" form:"notes"`
+}
+
+// isMethodesOrAdmin checks if the authenticated user may manage units
+func (r *UnitController) isMethodesOrAdmin(ctx http.Context) bool {
+	var user models.User
+	if err := facades.Auth(ctx).User(&user); err != nil {
+		return false
+	}
+
+	facades.Orm().Query().With("Role").Where("id", user.ID).First(&user)
+	return user.Role.Key == "admin" || user.Role.Key == "ingenieur_methodes"
+}
+
+// Index returns all units, optionally of one category
+func (r *UnitController) Index(ctx http.Context) http.Response {
+	query := facades.Orm().Query()
+	if category := ctx.Request().Query("filterData[category]", ""); category != "" {
+		query = query.Where("category", category)
+	}
+
+	var units []models.Unit
+	if err := query.OrderBy("category").OrderBy("factor").Find(&units); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve units",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"units":      units,
+		"categories": services.UnitCategories,
+	})
+}
+
+// Store creates a unit (methodes/admin)
+func (r *UnitController) Store(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	var request UnitRequest
+	if response := r.bindUnit(ctx, &request); response != nil {
+		return response
+	}
+
+	exists, err := r.unitService.Exists(request.Code)
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to check unit",
+		})
+	}
+	if exists {
+		return ctx.Response().Status(409).Json(http.Json{
+			"error":   "Unit code already exists",
+			"message": "A unit with this code already exists",
+		})
+	}
+
+	unit := models.Unit{
+		Code:     request.Code,
+		Name:     request.Name,
+		Category: request.Category,
+		Factor:   request.Factor,
+	}
+	if err := facades.Orm().Query().Create(&unit); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to create unit",
+		})
+	}
+
+	return ctx.Response().Status(201).Json(http.Json{
+		"message": "Unit created successfully",
+		"unit":    unit,
+	})
+}
+
+// Update changes the name, category or factor of a unit. The code is fixed since products
+// and stock refer to it.
+func (r *UnitController) Update(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	unit, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	var request UnitRequest
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+	request.Code = unit.Code
+	if response := r.validateUnit(ctx, &request); response != nil {
+		return response
+	}
+
+	unit.Name = request.Name
+	unit.Category = request.Category
+	unit.Factor = request.Factor
+	if err := facades.Orm().Query().Save(&unit); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to update unit",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message": "Unit updated successfully",
+		"unit":    unit,
+	})
+}
+
+// Destroy deletes a unit no product or variant is kept in
+func (r *UnitController) Destroy(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	unit, response := r.find(ctx)
+	if response != nil {
+		return response
+	}
+
+	productCount, err := facades.Orm().Query().Model(&models.Product{}).Where("unit", unit.Code).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to check unit usage",
+		})
+	}
+	variantCount, err := facades.Orm().Query().Model(&models.ProductVariant{}).Where("unit", unit.Code).Count()
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to check unit usage",
+		})
+	}
+	if productCount > 0 || variantCount > 0 {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Cannot delete unit",
+			"message": "Unit is used by products and cannot be deleted",
+		})
+	}
+
+	if _, err := facades.Orm().Query().Delete(&unit); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to delete unit",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message": "Unit deleted successfully",
+	})
+}
+
+// Conversions returns the unit conversions of a product
+func (r *UnitController) Conversions(ctx http.Context) http.Response {
+	var conversions []models.ProductUnitConversion
+	if err := facades.Orm().Query().With("Variant").Where("product_id", ctx.Request().Route("id")).
+		OrderBy("id").Find(&conversions); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve unit conversions",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"unit_conversions": conversions,
+	})
+}
+
+// StoreConversion adds a unit conversion to a product or one of its variants (methodes/admin)
+func (r *UnitController) StoreConversion(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	var product models.Product
+	if err := facades.Orm().Query().Where("id", ctx.Request().Route("id")).FirstOrFail(&product); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Product not found",
+				"message": "The requested product does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve product",
+		})
+	}
+
+	var request ProductUnitConversionRequest
+	if err := ctx.Request().Bind(&request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	if request.Factor <= 0 || request.FromUnit == "" || request.ToUnit == "" || request.FromUnit == request.ToUnit {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "from_unit and to_unit must differ and factor must be greater than zero",
+		})
+	}
+	for _, code := range []string{request.FromUnit, request.ToUnit} {
+		exists, err := r.unitService.Exists(code)
+		if err != nil {
+			return ctx.Response().Status(500).Json(http.Json{
+				"error":   "Database error",
+				"message": "Failed to check unit",
+			})
+		}
+		if !exists {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Validation failed",
+				"message": "Unit " + code + " does not exist",
+			})
+		}
+	}
+
+	if request.VariantID != nil {
+		exists, err := facades.Orm().Query().Model(&models.ProductVariant{}).Where("id", *request.VariantID).Where("product_id", product.ID).Exists()
+		if err != nil || !exists {
+			return ctx.Response().Status(422).Json(http.Json{
+				"error":   "Invalid variant",
+				"message": "The specified variant does not belong to the product",
+			})
+		}
+	}
+
+	conversion := models.ProductUnitConversion{
+		ProductID: product.ID,
+		VariantID: request.VariantID,
+		FromUnit:  request.FromUnit,
+		ToUnit:    request.ToUnit,
+		Factor:    request.Factor,
+		Notes:     request.Notes,
+	}
+	if err := facades.Orm().Query().Create(&conversion); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to create unit conversion",
+		})
+	}
+
+	return ctx.Response().Status(201).Json(http.Json{
+		"message":         "Unit conversion created successfully",
+		"unit_conversion": conversion,
+	})
+}
+
+// DestroyConversion removes a unit conversion of a product (methodes/admin)
+func (r *UnitController) DestroyConversion(ctx http.Context) http.Response {
+	if !r.isMethodesOrAdmin(ctx) {
+		return ctx.Response().Status(403).Json(http.Json{
+			"error":   "Forbidden",
+			"message": "Methodes or Admin access required",
+		})
+	}
+
+	var conversion models.ProductUnitConversion
+	if err := facades.Orm().Query().Where("id", ctx.Request().Route("conversion")).
+		Where("product_id", ctx.Request().Route("id")).FirstOrFail(&conversion); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return ctx.Response().Status(404).Json(http.Json{
+				"error":   "Unit conversion not found",
+				"message": "The requested unit conversion does not exist",
+			})
+		}
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve unit conversion",
+		})
+	}
+
+	if _, err := facades.Orm().Query().Delete(&conversion); err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to delete unit conversion",
+		})
+	}
+
+	return ctx.Response().Status(200).Json(http.Json{
+		"message": "Unit conversion deleted successfully",
+	})
+}
+
+// bindUnit binds and validates a unit payload
+func (r *UnitController) bindUnit(ctx http.Context, request *UnitRequest) http.Response {
+	if err := ctx.Request().Bind(request); err != nil {
+		return ctx.Response().Status(400).Json(http.Json{
+			"error":   "Invalid request data",
+			"message": err.Error(),
+		})
+	}
+
+	return r.validateUnit(ctx, request)
+}
+
+// validateUnit checks the fields of a unit payload
+func (r *UnitController) validateUnit(ctx http.Context, request *UnitRequest) http.Response {
+	validator, err := facades.Validation().Make(map[string]any{
+		"code":     request.Code,
+		"name":     request.Name,
+		"category": request.Category,
+	}, map[string]string{
+		"code":     "required|max_len:50",
+		"name":     "required|max_len:100",
+		"category": "required",
+	})
+
+	if err != nil {
+		return ctx.Response().Status(500).Json(http.Json{
+			"error": "Validation error",
+		})
+	}
+
+	if validator.Fails() {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":  "Validation failed",
+			"errors": validator.Errors().All(),
+		})
+	}
+
+	if !slices.Contains(services.UnitCategories, request.Category) {
+		return ctx.Response().Status(422).Json(http.Json{
+			"error":   "Validation failed",
+			"message": "category must be mass, length, area, volume or count",
+		})
+	}
+	if request.Factor <= 0 {
+		request.Factor = 1
+	}
+
+	return nil
+}
+
+// find loads the unit referenced by the route
+func (r *UnitController) find(ctx http.Context) (models.Unit, http.Response) {
+	var unit models.Unit
+	if err := facades.Orm().Query().Where("id", ctx.Request().Route("id")).FirstOrFail(&unit); err != nil {
+		if errors.Is(err, errors.OrmRecordNotFound) {
+			return unit, ctx.Response().Status(404).Json(http.Json{
+				"error":   "Unit not found",
+				"message": "The requested unit does not exist",
+			})
+		}
+		return unit, ctx.Response().Status(500).Json(http.Json{
+			"error":   "Database error",
+			"message": "Failed to retrieve unit",
+		})
+	}
+
+	return unit, nil
+}
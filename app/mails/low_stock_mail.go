@@ -0,0 +1,64 @@
+package mails
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/goravel/framework/contracts/mail"
+
+	"pms/app/models"
+)
+
+var lowStockTemplate = template.Must(template.New("low_stock").Parse(`<h2>Low stock report</h2>
+<p>{{len .}} raw material(s) reached their reorder point. Draft replenishment suggestions are waiting in the application.</p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Product</th><th>Variant</th><th>Location</th><th>On hand</th><th>Reserved</th><th>Available</th><th>Suggested</th></tr>
+{{range .}}<tr{{if .IsCritical}} style="color:#b00020"{{end}}><td>{{.Product.Title}}</td><td>{{.Variant.Title}} ({{.Variant.SKU}})</td><td>{{if .Location}}{{.Location.Name}}{{else}}All locations{{end}}</td><td>{{printf "%.3f" .OnHand}}</td><td>{{printf "%.3f" .Reserved}}</td><td>{{printf "%.3f" .Available}}</td><td>{{printf "%.3f" .SuggestedQuantity}} {{.Unit}}</td></tr>
+{{end}}</table>
+<p>Rows in red are below their minimum quantity.</p>`))
+
+// LowStockMail notifies the achat role of variants below their reorder point
+type LowStockMail struct {
+	recipients  []string
+	suggestions []models.ReplenishmentSuggestion
+}
+
+func NewLowStockMail(recipients []string, suggestions []models.ReplenishmentSuggestion) *LowStockMail {
+	return &LowStockMail{
+		recipients:  recipients,
+		suggestions: suggestions,
+	}
+}
+
+// Attachments set the attachments of Mailable.
+func (m *LowStockMail) Attachments() []string {
+	return []string{}
+}
+
+// Content set the content of Mailable.
+func (m *LowStockMail) Content() *mail.Content {
+	var body bytes.Buffer
+	if err := lowStockTemplate.Execute(&body, m.suggestions); err != nil {
+		return &mail.Content{Html: "Low stock report could not be rendered: " + template.HTMLEscapeString(err.Error())}
+	}
+
+	return &mail.Content{Html: body.String()}
+}
+
+// Envelope set the envelope of Mailable.
+func (m *LowStockMail) Envelope() *mail.Envelope {
+	return &mail.Envelope{
+		Subject: "Low stock report",
+		To:      m.recipients,
+	}
+}
+
+// Headers adds custom headers to the Mail.
+func (m *LowStockMail) Headers() map[string]string {
+	return map[string]string{}
+}
+
+// Queue set the queue of Mailable.
+func (m *LowStockMail) Queue() *mail.Queue {
+	return &mail.Queue{}
+}
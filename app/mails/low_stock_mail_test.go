@@ -0,0 +1,44 @@
+package mails
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"pms/app/models"
+)
+
+func TestLowStockMailRendersSuggestions(t *testing.T) {
+	location := models.StorageLocation{Name: "Rack <A>"}
+	suggestions := []models.ReplenishmentSuggestion{
+		{
+			Product:           models.Product{Title: "Steel sheet"},
+			Variant:           models.ProductVariant{Title: "2 mm", SKU: "STS-2"},
+			Location:          &location,
+			OnHand:            12.5,
+			Reserved:          4,
+			Available:         8.5,
+			SuggestedQuantity: 20,
+			Unit:              "kg",
+			IsCritical:        true,
+		},
+		{
+			Product:           models.Product{Title: "Round bar"},
+			Variant:           models.ProductVariant{Title: "Ø20", SKU: "RB-20"},
+			Available:         3,
+			SuggestedQuantity: 6,
+			Unit:              "m",
+		},
+	}
+
+	mail := NewLowStockMail([]string{"achat@example.com"}, suggestions)
+	assert.Equal(t, []string{"achat@example.com"}, mail.Envelope().To)
+	assert.Equal(t, "Low stock report", mail.Envelope().Subject)
+
+	html := mail.Content().Html
+	assert.Contains(t, html, "2 raw material(s) reached their reorder point")
+	assert.Contains(t, html, `<tr style="color:#b00020"><td>Steel sheet</td><td>2 mm (STS-2)</td><td>Rack &lt;A&gt;</td>`)
+	assert.Contains(t, html, "<td>12.500</td><td>4.000</td><td>8.500</td><td>20.000 kg</td>")
+	assert.Contains(t, html, "<tr><td>Round bar</td><td>Ø20 (RB-20)</td><td>All locations</td>")
+	assert.Contains(t, html, "<td>6.000 m</td>")
+}
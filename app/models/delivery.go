@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/goravel/framework/database/orm"
+)
+
+type Delivery struct {
+	orm.Model
+	DeliveryNumber string    `gorm:"size:100;unique;not null"`
+	ClientID       uint      `gorm:"not null;index"`
+	ClientSiteID   uint      `gorm:"not null;index"`
+	DeliveredAt    time.Time `gorm:"not null"`
+	Notes          string    `gorm:"type:text"`
+	CreatedBy      uint      `gorm:"not null;index"`
+
+	// Relationships
+	Client        Client         `gorm:"foreignKey:ClientID"`
+	ClientSite    ClientSite     `gorm:"foreignKey:ClientSiteID"`
+	Creator       User           `gorm:"foreignKey:CreatedBy"`
+	SerialNumbers []SerialNumber `gorm:"foreignKey:DeliveryID"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"github.com/goravel/framework/database/orm"
+)
+
+type DocumentSequence struct {
+	orm.Model
+	DocumentType string `gorm:"size:50;not null;uniqueIndex:idx_document_sequence_period"`
+	Period       string `gorm:"size:10;not null;uniqueIndex:idx_document_sequence_period"`
+	LastValue    int64  `gorm:"not null;default:0"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/goravel/framework/database/orm"
+)
+
+// Engineering change order statuses
+const (
+	EngineeringChangeOrderStatusPending  = "pending"
+	EngineeringChangeOrderStatusApproved = "approved"
+	EngineeringChangeOrderStatusRejected = "rejected"
+)
+
+// EngineeringChangeOrder records why a new recipe version replaces the previous one and who
+// approved it
+type EngineeringChangeOrder struct {
+	orm.Model
+	EcoNumber        string     `gorm:"size:100;uniqueIndex;not null"`
+	VariantID        uint       `gorm:"not null;index"`
+	RecipeVariantID  uint       `gorm:"not null;index"` // the draft version put into effect
+	PreviousRecipeID *uint      // the version in effect when the change was requested
+	Reason           string     `gorm:"type:text;not null"`
+	Status           string     `gorm:"size:20;not null;default:'pending';index"` // pending, approved, rejected
+	EffectiveFrom    *time.Time // nil means on approval
+	RequestedBy      uint       `gorm:"not null;index"`
+	ApprovedBy       *uint      // the reviewer, also of a rejection
+	ApprovedAt       *time.Time
+	RejectionReason  string `gorm:"type:text"`
+
+	// Relationships
+	Variant        ProductVariant `gorm:"foreignKey:VariantID"`
+	RecipeVariant  RecipeVariant  `gorm:"foreignKey:RecipeVariantID"`
+	PreviousRecipe *RecipeVariant `gorm:"foreignKey:PreviousRecipeID"`
+	Requester      User           `gorm:"foreignKey:RequestedBy"`
+	Approver       *User          `gorm:"foreignKey:ApprovedBy"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/goravel/framework/database/orm"
+)
+
+type InventoryCount struct {
+	orm.Model
+	CountNumber    string `gorm:"size:100;unique;not null"`
+	LocationID     uint   `gorm:"not null;index"`
+	CategoryIDs    string `gorm:"type:json"`                             // JSON array of counted categories, empty for the whole location
+	Status         string `gorm:"size:20;not null;default:'open';index"` // open, validated, cancelled
+	BlockMovements bool   `gorm:"not null;default:true"`
+	Notes          string `gorm:"type:text"`
+	OpenedBy       uint   `gorm:"not null;index"`
+	OpenedAt       time.Time
+	ValidatedBy    *uint `gorm:"index"`
+	ValidatedAt    *time.Time
+
+	// Relationships
+	Location            StorageLocation      `gorm:"foreignKey:LocationID"`
+	OpenedByUser        User                 `gorm:"foreignKey:OpenedBy"`
+	ValidatedByUser     *User                `gorm:"foreignKey:ValidatedBy"`
+	InventoryCountLines []InventoryCountLine `gorm:"foreignKey:InventoryCountID"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/goravel/framework/database/orm"
+)
+
+type InventoryCountLine struct {
+	orm.Model
+	InventoryCountID uint     `gorm:"not null;index"`
+	ProductID        uint     `gorm:"not null;index"`
+	VariantID        *uint    `gorm:"index"`
+	ExpectedQuantity float64  `gorm:"type:decimal(10,3);not null;default:0"`
+	CountedQuantity  *float64 `gorm:"type:decimal(10,3)"`
+	Unit             string   `gorm:"size:50"`
+	CountedBy        *uint    `gorm:"index"`
+	CountedAt        *time.Time
+	Variance         *float64 `gorm:"-"` // counted minus expected, nil until counted
+
+	// Relationships
+	InventoryCount InventoryCount  `gorm:"foreignKey:InventoryCountID"`
+	Product        Product         `gorm:"foreignKey:ProductID"`
+	Variant        *ProductVariant `gorm:"foreignKey:VariantID"`
+	CountedByUser  *User           `gorm:"foreignKey:CountedBy"`
+}
@@ -6,7 +6,8 @@ import (
 
 type Operation struct {
 	orm.Model
-	Key        string `gorm:"uniqueIndex;size:50;not null"`
-	Title      string `gorm:"size:100;not null"`
-	OrderIndex int    `gorm:"not null;index"`
+	Key        string  `gorm:"uniqueIndex;size:50;not null"`
+	Title      string  `gorm:"size:100;not null"`
+	OrderIndex int     `gorm:"not null;index"`
+	HourlyRate float64 `gorm:"type:decimal(12,4);not null;default:0"` // cost of an hour spent on the operation
 }
\ No newline at end of file
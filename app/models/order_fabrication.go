@@ -6,24 +6,36 @@ import (
 	"github.com/goravel/framework/database/orm"
 )
 
+// Manufacturing order statuses
+const (
+	OrderFabricationStatusPending    = "pending"
+	OrderFabricationStatusReleased   = "released"
+	OrderFabricationStatusInProgress = "in_progress"
+	OrderFabricationStatusOnHold     = "on_hold"
+	OrderFabricationStatusCompleted  = "completed"
+	OrderFabricationStatusCancelled  = "cancelled"
+)
+
 type OrderFabrication struct {
 	orm.Model
-	OrderNumber  string     `gorm:"size:100;uniqueIndex;not null"`
-	ProductID    uint       `gorm:"not null;index"`
-	VariantID    *uint      `gorm:"index"`
-	Quantity     float64    `gorm:"not null"`
-	ClientID     uint       `gorm:"not null;index"`
-	ClientSiteID *uint      `gorm:"index"`
-	Status       string     `gorm:"size:50;not null;default:'pending';index"` // pending, in_progress, completed, cancelled, on_hold
-	Priority     string     `gorm:"size:20;not null;default:'normal';index"`  // low, normal, high, urgent
-	DeadlineDate *time.Time `gorm:"index"`
-	Notes        string     `gorm:"type:text"`
-	CreatedBy    uint       `gorm:"not null;index"`
+	OrderNumber     string     `gorm:"size:100;uniqueIndex;not null"`
+	ProductID       uint       `gorm:"not null;index"`
+	VariantID       *uint      `gorm:"index"`
+	RecipeVariantID *uint      `gorm:"index"` // recipe version pinned at release
+	Quantity        float64    `gorm:"type:decimal(10,3);not null"`
+	ClientID        uint       `gorm:"not null;index"`
+	ClientSiteID    *uint      `gorm:"index"`
+	Status          string     `gorm:"size:50;not null;default:'pending';index"` // pending, released, in_progress, on_hold, completed, cancelled
+	Priority        string     `gorm:"size:20;not null;default:'normal';index"`  // low, normal, high, urgent
+	DeadlineDate    *time.Time `gorm:"index"`
+	Notes           string     `gorm:"type:text"`
+	CreatedBy       uint       `gorm:"not null;index"`
 
 	// Relationships
-	Product    Product         `gorm:"foreignKey:ProductID"`
-	Variant    *ProductVariant `gorm:"foreignKey:VariantID"`
-	Client     Client          `gorm:"foreignKey:ClientID"`
-	ClientSite *ClientSite     `gorm:"foreignKey:ClientSiteID"`
-	Creator    User            `gorm:"foreignKey:CreatedBy"`
+	Product       Product         `gorm:"foreignKey:ProductID"`
+	Variant       *ProductVariant `gorm:"foreignKey:VariantID"`
+	RecipeVariant *RecipeVariant  `gorm:"foreignKey:RecipeVariantID"`
+	Client        Client          `gorm:"foreignKey:ClientID"`
+	ClientSite    *ClientSite     `gorm:"foreignKey:ClientSiteID"`
+	Creator       User            `gorm:"foreignKey:CreatedBy"`
 }
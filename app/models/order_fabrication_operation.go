@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/goravel/framework/database/orm"
+)
+
+// OrderFabricationOperation is a routing step copied onto an order at release, so later
+// routing changes leave the steps of released orders untouched
+type OrderFabricationOperation struct {
+	orm.Model
+	OrderFabricationID uint    `gorm:"not null;index"`
+	OperationID        uint    `gorm:"not null;index"`
+	RoutingStepID      *uint   // step the operation was copied from
+	Sequence           int     `gorm:"not null"`
+	SetupMinutes       float64 `gorm:"type:decimal(10,2);not null;default:0"`
+	RunMinutes         float64 `gorm:"type:decimal(10,3);not null;default:0"` // per unit
+	PlannedMinutes     float64 `gorm:"type:decimal(12,2);not null;default:0"` // setup plus run time of the order quantity
+	Workstation        string  `gorm:"size:100"`
+	RequiredRole       string  `gorm:"size:50"`
+	Notes              string  `gorm:"type:text"`
+
+	// Relationships
+	OrderFabrication OrderFabrication `gorm:"foreignKey:OrderFabricationID"`
+	Operation        Operation        `gorm:"foreignKey:OperationID"`
+}
@@ -16,6 +16,8 @@ type Product struct {
 	PrixVente     float64 `gorm:"type:decimal(10,2)"`
 	Unit          string  `gorm:"size:50"`
 	ImageURL      string  `gorm:"size:500"`
+	IsSerialized  bool    `gorm:"not null;default:false"`
+	SerialPattern string  `gorm:"size:100"` // e.g. SN-{YYYY}-{seq:6}, app.sequences.serial_number when empty
 
 	// Relationships
 	Category          *Category          `gorm:"foreignKey:CategoryID"`
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/goravel/framework/database/orm"
+)
+
+// ProductUnitConversion states that one FromUnit of a product weighs or measures Factor ToUnit,
+// e.g. 1 sheet = 31.4 kg. Without a variant it applies to all variants of the product.
+type ProductUnitConversion struct {
+	orm.Model
+	ProductID uint    `gorm:"not null;index"`
+	VariantID *uint   `gorm:"index"`
+	FromUnit  string  `gorm:"size:50;not null"`
+	ToUnit    string  `gorm:"size:50;not null"`
+	Factor    float64 `gorm:"type:decimal(18,6);not null"`
+	Notes     string  `gorm:"type:text"`
+
+	// Relationships
+	Product Product         `gorm:"foreignKey:ProductID"`
+	Variant *ProductVariant `gorm:"foreignKey:VariantID"`
+}
@@ -11,10 +11,12 @@ type ProductionMaterialRequirement struct {
 	RequiredQuantity   float64 `gorm:"type:decimal(10,3);not null"`
 	StockQuantity      float64 `gorm:"type:decimal(10,3);default:0"`
 	RequestQuantity    float64 `gorm:"type:decimal(10,3);default:0"`
+	ConsumedQuantity   float64 `gorm:"type:decimal(10,3);default:0"`
 	Unit               string  `gorm:"size:50"`
 	Status             string  `gorm:"size:20;not null;default:'pending';index"` // pending, requested, available, consumed
 
 	// Relationships
-	OrderFabrication OrderFabrication `gorm:"foreignKey:OrderFabricationID"`
-	MaterialVariant  ProductVariant   `gorm:"foreignKey:MaterialVariantID"`
+	OrderFabrication OrderFabrication   `gorm:"foreignKey:OrderFabricationID"`
+	MaterialVariant  ProductVariant     `gorm:"foreignKey:MaterialVariantID"`
+	Reservations     []StockReservation `gorm:"foreignKey:RequirementID"`
 }
@@ -9,14 +9,20 @@ import (
 type ProductionOfHistory struct {
 	orm.Model
 	OrderFabricationID uint      `gorm:"not null;index"`
-	OperationID        uint      `gorm:"not null;index"`
+	OperationID        *uint     `gorm:"index"`
 	UserID             uint      `gorm:"not null;index"`
+	PreviousStatus     string    `gorm:"size:50"`
 	Status             string    `gorm:"size:50;not null;index"`
 	Notes              string    `gorm:"type:text"`
 	StatusAt           time.Time `gorm:"not null;index"`
 
 	// Relationships
 	OrderFabrication OrderFabrication `gorm:"foreignKey:OrderFabricationID"`
-	Operation        Operation        `gorm:"foreignKey:OperationID"`
+	Operation        *Operation       `gorm:"foreignKey:OperationID"`
 	User             User             `gorm:"foreignKey:UserID"`
-}
\ No newline at end of file
+}
+
+// TableName returns the table created by the production_of_history migration
+func (r *ProductionOfHistory) TableName() string {
+	return "production_of_history"
+}
@@ -6,11 +6,20 @@ import (
 
 type RecipeProduct struct {
 	orm.Model
-	ProductID         uint   `gorm:"not null;index"`
-	MaterialProductID uint   `gorm:"not null;index"`
-	Notes             string `gorm:"type:text"`
+	ProductID         uint    `gorm:"not null;index"`
+	MaterialProductID uint    `gorm:"not null;index"`
+	MaterialVariantID *uint   `gorm:"index"` // fixed material variant, when not matched on an attribute
+	Quantity          float64 `gorm:"type:decimal(10,3);not null;default:0"`
+	Unit              string  `gorm:"size:50"`
+	ScrapPercent      float64 `gorm:"type:decimal(5,2);not null;default:0"`
+	MatchAttribute    string  `gorm:"size:100"`                              // variant attribute selecting the material variant
+	MaterialAttribute string  `gorm:"size:100"`                              // material attribute compared with it, the same key when empty
+	ScaleAttribute    string  `gorm:"size:100"`                              // numeric variant attribute multiplying the quantity
+	ScaleFactor       float64 `gorm:"type:decimal(18,6);not null;default:1"` // applied with the scale attribute, e.g. mm to m
+	Notes             string  `gorm:"type:text"`
 
 	// Relationships
-	Product         Product `gorm:"foreignKey:product_id"`
-	MaterialProduct Product `gorm:"foreignKey:material_product_id"`
+	Product         Product         `gorm:"foreignKey:product_id"`
+	MaterialProduct Product         `gorm:"foreignKey:material_product_id"`
+	MaterialVariant *ProductVariant `gorm:"foreignKey:MaterialVariantID"`
 }
@@ -1,18 +1,32 @@
 package models
 
 import (
+	"time"
+
 	"github.com/goravel/framework/database/orm"
 )
 
+// Recipe version statuses
+const (
+	RecipeStatusDraft    = "draft"
+	RecipeStatusActive   = "active"
+	RecipeStatusObsolete = "obsolete"
+)
+
 type RecipeVariant struct {
 	orm.Model
-	ProductID      uint    `gorm:"not null;index"`
-	VariantID      uint    `gorm:"not null;index"`
-	OutputQuantity float64 `gorm:"type:decimal(10,3);not null"`
-	Notes          string  `gorm:"type:text"`
+	ProductID      uint       `gorm:"not null;index"`
+	VariantID      uint       `gorm:"not null;index"`
+	Version        int        `gorm:"not null;default:1"`
+	Status         string     `gorm:"size:20;not null;default:'active';index"` // draft, active, obsolete
+	EffectiveFrom  *time.Time // nil means since always
+	OutputQuantity float64    `gorm:"type:decimal(10,3);not null"`
+	YieldPercent   float64    `gorm:"type:decimal(5,2);not null;default:100"` // share of the output that is good
+	Notes          string     `gorm:"type:text"`
 
 	// Relationships
-	Product            Product             `gorm:"foreignKey:ProductID"`
-	Variant            ProductVariant      `gorm:"foreignKey:VariantID"`
-	RecipeVariantItems []RecipeVariantItem `gorm:"foreignKey:RecipeVariantID"`
+	Product            Product                  `gorm:"foreignKey:ProductID"`
+	Variant            ProductVariant           `gorm:"foreignKey:VariantID"`
+	RecipeVariantItems []RecipeVariantItem      `gorm:"foreignKey:RecipeVariantID"`
+	ByProducts         []RecipeVariantByProduct `gorm:"foreignKey:RecipeVariantID"`
 }
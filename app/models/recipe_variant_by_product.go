@@ -0,0 +1,20 @@
+package models
+
+import (
+	"github.com/goravel/framework/database/orm"
+)
+
+// RecipeVariantByProduct is a secondary output of a recipe, such as a reusable offcut,
+// received into stock when an order made with the recipe completes
+type RecipeVariantByProduct struct {
+	orm.Model
+	RecipeVariantID uint    `gorm:"not null;index"`
+	VariantID       uint    `gorm:"not null;index"`
+	Quantity        float64 `gorm:"type:decimal(10,3);not null"` // per output quantity of the recipe
+	Unit            string  `gorm:"size:50"`                     // empty means the unit of the variant
+	Notes           string  `gorm:"type:text"`
+
+	// Relationships
+	RecipeVariant RecipeVariant  `gorm:"foreignKey:RecipeVariantID"`
+	Variant       ProductVariant `gorm:"foreignKey:VariantID"`
+}
@@ -9,6 +9,8 @@ type RecipeVariantItem struct {
 	RecipeVariantID   uint    `gorm:"not null;index"`
 	MaterialVariantID uint    `gorm:"not null;index"`
 	Quantity          float64 `gorm:"type:decimal(10,3);not null"`
+	Unit              string  `gorm:"size:50"`                              // empty means the unit of the material
+	ScrapPercent      float64 `gorm:"type:decimal(5,2);not null;default:0"` // material lost on top of the quantity
 	Notes             string  `gorm:"type:text"`
 
 	// Relationships
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"github.com/goravel/framework/database/orm"
+)
+
+type ReorderRule struct {
+	orm.Model
+	VariantID    uint    `gorm:"not null;index"`
+	LocationID   *uint   `gorm:"index"` // nil applies to the stock of all locations
+	MinQuantity  float64 `gorm:"type:decimal(10,3);default:0"`
+	MaxQuantity  float64 `gorm:"type:decimal(10,3);default:0"`
+	ReorderPoint float64 `gorm:"type:decimal(10,3);default:0"`
+	IsActive     bool    `gorm:"not null;default:true;index"`
+
+	// Relationships
+	Variant  ProductVariant   `gorm:"foreignKey:VariantID"`
+	Location *StorageLocation `gorm:"foreignKey:LocationID"`
+}
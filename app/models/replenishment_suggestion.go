@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/goravel/framework/database/orm"
+)
+
+type ReplenishmentSuggestion struct {
+	orm.Model
+	ReorderRuleID     uint    `gorm:"not null;index"`
+	ProductID         uint    `gorm:"not null;index"`
+	VariantID         uint    `gorm:"not null;index"`
+	LocationID        *uint   `gorm:"index"`
+	OnHand            float64 `gorm:"type:decimal(10,3);default:0"`
+	Reserved          float64 `gorm:"type:decimal(10,3);default:0"`
+	Available         float64 `gorm:"type:decimal(10,3);default:0"`
+	SuggestedQuantity float64 `gorm:"type:decimal(10,3);default:0"`
+	Unit              string  `gorm:"size:50"`
+	Status            string  `gorm:"size:20;not null;default:'draft';index"` // draft, ordered, dismissed, resolved
+	IsCritical        bool    `gorm:"not null;default:false"`                 // available below the minimum quantity
+	NotifiedAt        *time.Time
+	HandledBy         *uint `gorm:"index"`
+	HandledAt         *time.Time
+
+	// Relationships
+	ReorderRule   ReorderRule      `gorm:"foreignKey:ReorderRuleID"`
+	Product       Product          `gorm:"foreignKey:ProductID"`
+	Variant       ProductVariant   `gorm:"foreignKey:VariantID"`
+	Location      *StorageLocation `gorm:"foreignKey:LocationID"`
+	HandledByUser *User            `gorm:"foreignKey:HandledBy"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/goravel/framework/database/orm"
+)
+
+// RoutingStep is an operation a product goes through, in sequence. Steps without variant
+// make the routing of the product; a variant with steps of its own follows those instead.
+type RoutingStep struct {
+	orm.Model
+	ProductID    uint    `gorm:"not null;index"`
+	VariantID    *uint   `gorm:"index"`
+	OperationID  uint    `gorm:"not null;index"`
+	Sequence     int     `gorm:"not null"`
+	SetupMinutes float64 `gorm:"type:decimal(10,2);not null;default:0"` // once per order
+	RunMinutes   float64 `gorm:"type:decimal(10,3);not null;default:0"` // per unit produced
+	Workstation  string  `gorm:"size:100"`
+	RequiredRole string  `gorm:"size:50"` // role key of the operators allowed on the step
+	Notes        string  `gorm:"type:text"`
+
+	// Relationships
+	Product   Product         `gorm:"foreignKey:ProductID"`
+	Variant   *ProductVariant `gorm:"foreignKey:VariantID"`
+	Operation Operation       `gorm:"foreignKey:OperationID"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/goravel/framework/database/orm"
+)
+
+type SerialNumber struct {
+	orm.Model
+	SerialNumber       string `gorm:"size:100;unique;not null"`
+	ProductID          uint   `gorm:"not null;index"`
+	VariantID          *uint  `gorm:"index"`
+	OrderFabricationID uint   `gorm:"not null;index"`
+	MovementID         uint   `gorm:"not null;index"` // receipt of the finished goods
+	LocationID         *uint  `gorm:"index"`
+	Status             string `gorm:"size:20;not null;default:'in_stock';index"` // in_stock, delivered
+	DeliveryID         *uint  `gorm:"index"`
+	DeliveryMovementID *uint  `gorm:"index"`
+
+	// Relationships
+	Product          Product          `gorm:"foreignKey:ProductID"`
+	Variant          *ProductVariant  `gorm:"foreignKey:VariantID"`
+	OrderFabrication OrderFabrication `gorm:"foreignKey:OrderFabricationID"`
+	Movement         StockMovement    `gorm:"foreignKey:MovementID"`
+	Location         *StorageLocation `gorm:"foreignKey:LocationID"`
+	Delivery         *Delivery        `gorm:"foreignKey:DeliveryID"`
+	DeliveryMovement *StockMovement   `gorm:"foreignKey:DeliveryMovementID"`
+}
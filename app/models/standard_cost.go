@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/goravel/framework/database/orm"
+)
+
+// StandardCost is the rolled-up cost of one unit of a variant, from the recipe version in
+// effect when it was computed
+type StandardCost struct {
+	orm.Model
+	ProductID       uint      `gorm:"not null;index"`
+	VariantID       uint      `gorm:"not null;uniqueIndex"`
+	RecipeVariantID *uint     `gorm:"index"`            // nil for variants costed without a recipe
+	CostSource      string    `gorm:"size:20;not null"` // purchase, valuation
+	MaterialCost    float64   `gorm:"type:decimal(14,4);not null;default:0"`
+	LabourCost      float64   `gorm:"type:decimal(14,4);not null;default:0"`
+	TotalCost       float64   `gorm:"type:decimal(14,4);not null;default:0"`
+	ComputedAt      time.Time `gorm:"not null"`
+
+	// Relationships
+	Product       Product        `gorm:"foreignKey:ProductID"`
+	Variant       ProductVariant `gorm:"foreignKey:VariantID"`
+	RecipeVariant *RecipeVariant `gorm:"foreignKey:RecipeVariantID"`
+}
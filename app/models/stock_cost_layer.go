@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/goravel/framework/database/orm"
+)
+
+type StockCostLayer struct {
+	orm.Model
+	StockLevelID      uint    `gorm:"not null;index"`
+	MovementID        uint    `gorm:"not null;index"`
+	Quantity          float64 `gorm:"type:decimal(10,3);not null"`
+	RemainingQuantity float64 `gorm:"type:decimal(10,3);not null"`
+	UnitCost          float64 `gorm:"type:decimal(14,4);not null"`
+
+	// Relationships
+	StockLevel StockLevel    `gorm:"foreignKey:StockLevelID"`
+	Movement   StockMovement `gorm:"foreignKey:MovementID"`
+}
@@ -6,11 +6,15 @@ import (
 
 type StockLevel struct {
 	orm.Model
-	ProductID  uint    `gorm:"not null;index"`
-	VariantID  *uint   `gorm:"index"`
-	LocationID uint    `gorm:"not null;index"`
-	Quantity   float64 `gorm:"not null;index"`
-	Unit       string  `gorm:"size:50"`
+	ProductID   uint    `gorm:"not null;index"`
+	VariantID   *uint   `gorm:"index"`
+	LocationID  uint    `gorm:"not null;index"`
+	Quantity    float64 `gorm:"type:decimal(10,3);not null;index"`
+	Unit        string  `gorm:"size:50"`
+	AverageCost float64 `gorm:"type:decimal(14,4);default:0"`
+	TotalValue  float64 `gorm:"type:decimal(16,4);default:0"`
+	Reserved    float64 `gorm:"-"` // quantity held by active reservations
+	Available   float64 `gorm:"-"` // on hand minus reserved
 
 	// Relationships
 	Product  Product         `gorm:"foreignKey:ProductID"`
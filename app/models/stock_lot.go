@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/goravel/framework/database/orm"
+)
+
+type StockLot struct {
+	orm.Model
+	LotNumber      string     `gorm:"size:100;unique;not null"`
+	ProductID      uint       `gorm:"not null;index"`
+	VariantID      uint       `gorm:"not null;index"`
+	HeatNumber     string     `gorm:"size:100;index"` // heat (coulée) number of the mill certificate
+	Supplier       string     `gorm:"size:255"`
+	CertificateURL string     `gorm:"size:500"`
+	ReceivedAt     *time.Time `gorm:"index"`
+	Notes          string     `gorm:"type:text"`
+	CreatedBy      uint       `gorm:"not null;index"`
+
+	// Relationships
+	Product  Product           `gorm:"foreignKey:ProductID"`
+	Variant  ProductVariant    `gorm:"foreignKey:VariantID"`
+	Creator  User              `gorm:"foreignKey:CreatedBy"`
+	Balances []StockLotBalance `gorm:"foreignKey:LotID"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"github.com/goravel/framework/database/orm"
+)
+
+type StockLotBalance struct {
+	orm.Model
+	LotID      uint    `gorm:"not null;index"`
+	LocationID uint    `gorm:"not null;index"`
+	Quantity   float64 `gorm:"type:decimal(10,3);not null;default:0"`
+
+	// Relationships
+	Lot      StockLot        `gorm:"foreignKey:LotID"`
+	Location StorageLocation `gorm:"foreignKey:LocationID"`
+}
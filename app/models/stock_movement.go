@@ -6,20 +6,25 @@ import (
 
 type StockMovement struct {
 	orm.Model
-	ProductID     uint    `gorm:"not null;index"`
-	VariantID     *uint   `gorm:"index"`
-	LocationID    uint    `gorm:"not null;index"`
-	MovementType  string  `gorm:"size:20;not null;index"` // in, out, adjustment
-	Quantity      float64 `gorm:"not null"`
-	Unit          string  `gorm:"size:50"`
-	ReferenceType string  `gorm:"size:50;index"`
-	ReferenceID   *uint   `gorm:"index"`
-	Notes         string  `gorm:"type:text"`
-	CreatedBy     uint    `gorm:"not null;index"`
+	ProductID       uint     `gorm:"not null;index"`
+	VariantID       *uint    `gorm:"index"`
+	LocationID      uint     `gorm:"not null;index"`
+	MovementType    string   `gorm:"size:20;not null;index"` // in, out, adjustment
+	Quantity        float64  `gorm:"type:decimal(10,3);not null"`
+	Unit            string   `gorm:"size:50"`
+	EnteredQuantity *float64 `gorm:"type:decimal(12,3)"` // quantity as entered, when converted to the stock unit
+	EnteredUnit     string   `gorm:"size:50"`
+	UnitCost        *float64 `gorm:"type:decimal(14,4)"`           // cost per unit, given on receipts and computed on issues
+	TotalCost       float64  `gorm:"type:decimal(16,4);default:0"` // signed change of the stock value
+	ReferenceType   string   `gorm:"size:50;index"`
+	ReferenceID     *uint    `gorm:"index"`
+	Notes           string   `gorm:"type:text"`
+	CreatedBy       uint     `gorm:"not null;index"`
 
 	// Relationships
-	Product  Product         `gorm:"foreignKey:ProductID"`
-	Variant  *ProductVariant `gorm:"foreignKey:VariantID"`
-	Location StorageLocation `gorm:"foreignKey:LocationID"`
-	Creator  User            `gorm:"foreignKey:CreatedBy"`
+	Product  Product            `gorm:"foreignKey:ProductID"`
+	Variant  *ProductVariant    `gorm:"foreignKey:VariantID"`
+	Location StorageLocation    `gorm:"foreignKey:LocationID"`
+	Creator  User               `gorm:"foreignKey:CreatedBy"`
+	Lots     []StockMovementLot `gorm:"foreignKey:MovementID"` // lots drawn or filled, for lot-tracked variants
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"github.com/goravel/framework/database/orm"
+)
+
+type StockMovementLot struct {
+	orm.Model
+	MovementID uint    `gorm:"not null;index"`
+	LotID      uint    `gorm:"not null;index"`
+	Quantity   float64 `gorm:"type:decimal(10,3);not null"` // always positive, the direction is the movement's
+
+	// Relationships
+	Movement StockMovement `gorm:"foreignKey:MovementID"`
+	Lot      StockLot      `gorm:"foreignKey:LotID"`
+}
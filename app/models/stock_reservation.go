@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/goravel/framework/database/orm"
+)
+
+type StockReservation struct {
+	orm.Model
+	OrderFabricationID uint    `gorm:"not null;index"`
+	RequirementID      uint    `gorm:"not null;index"`
+	ProductID          uint    `gorm:"not null;index"`
+	VariantID          *uint   `gorm:"index"`
+	LocationID         uint    `gorm:"not null;index"`
+	Quantity           float64 `gorm:"type:decimal(10,3);not null"`
+	ConsumedQuantity   float64 `gorm:"type:decimal(10,3);not null;default:0"`
+	Unit               string  `gorm:"size:50"`
+	Status             string  `gorm:"size:20;not null;default:'active';index"` // active, released, consumed
+	CreatedBy          uint    `gorm:"not null;index"`
+	ReleasedAt         *time.Time
+
+	// Relationships
+	OrderFabrication OrderFabrication              `gorm:"foreignKey:OrderFabricationID"`
+	Requirement      ProductionMaterialRequirement `gorm:"foreignKey:RequirementID"`
+	Product          Product                       `gorm:"foreignKey:ProductID"`
+	Variant          *ProductVariant               `gorm:"foreignKey:VariantID"`
+	Location         StorageLocation               `gorm:"foreignKey:LocationID"`
+	Creator          User                          `gorm:"foreignKey:CreatedBy"`
+}
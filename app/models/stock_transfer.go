@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/goravel/framework/database/orm"
+)
+
+type StockTransfer struct {
+	orm.Model
+	TransferNumber string `gorm:"size:100;unique;not null"`
+	FromLocationID uint   `gorm:"not null;index"`
+	ToLocationID   uint   `gorm:"not null;index"`
+	Status         string `gorm:"size:20;not null;default:'draft';index"` // draft, in_transit, received, cancelled
+	Notes          string `gorm:"type:text"`
+	CreatedBy      uint   `gorm:"not null;index"`
+	DispatchedBy   *uint  `gorm:"index"`
+	DispatchedAt   *time.Time
+	ReceivedBy     *uint `gorm:"index"`
+	ReceivedAt     *time.Time
+
+	// Relationships
+	FromLocation       StorageLocation     `gorm:"foreignKey:FromLocationID"`
+	ToLocation         StorageLocation     `gorm:"foreignKey:ToLocationID"`
+	Creator            User                `gorm:"foreignKey:CreatedBy"`
+	DispatchedByUser   *User               `gorm:"foreignKey:DispatchedBy"`
+	ReceivedByUser     *User               `gorm:"foreignKey:ReceivedBy"`
+	StockTransferItems []StockTransferItem `gorm:"foreignKey:StockTransferID"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"github.com/goravel/framework/database/orm"
+)
+
+type StockTransferItem struct {
+	orm.Model
+	StockTransferID uint    `gorm:"not null;index"`
+	ProductID       uint    `gorm:"not null;index"`
+	VariantID       *uint   `gorm:"index"`
+	Quantity        float64 `gorm:"type:decimal(10,3);not null"`
+	Unit            string  `gorm:"size:50"`
+	OutMovementID   *uint   // movement that dispatched the line
+
+	// Relationships
+	StockTransfer StockTransfer   `gorm:"foreignKey:StockTransferID"`
+	Product       Product         `gorm:"foreignKey:ProductID"`
+	Variant       *ProductVariant `gorm:"foreignKey:VariantID"`
+}
@@ -4,13 +4,31 @@ import (
 	"github.com/goravel/framework/database/orm"
 )
 
+// Storage location types, from the largest to the smallest
+const (
+	StorageLocationTypeWarehouse = "warehouse"
+	StorageLocationTypeZone      = "zone"
+	StorageLocationTypeAisle     = "aisle"
+	StorageLocationTypeRack      = "rack"
+	StorageLocationTypeBin       = "bin"
+)
+
 type StorageLocation struct {
 	orm.Model
 	Name        string `gorm:"size:255;not null;index"`
 	Description string `gorm:"type:text"`
+	ParentID    *uint  `gorm:"index"`
+	Type        string `gorm:"size:20;not null;default:'bin';index"` // warehouse, zone, aisle, rack, bin
+
+	// Subtree stock totals, filled by the tree endpoint: the number of products and variants
+	// held, quantities being in different units, and their value
+	StockProducts int     `gorm:"-"`
+	StockValue    float64 `gorm:"-"`
 
 	// Relationships
-	Products       []Product       `gorm:"foreignKey:LocationID"`
-	StockLevels    []StockLevel    `gorm:"foreignKey:LocationID"`
-	StockMovements []StockMovement `gorm:"foreignKey:LocationID"`
+	Parent         *StorageLocation  `gorm:"foreignKey:ParentID"`
+	Children       []StorageLocation `gorm:"foreignKey:ParentID"`
+	Products       []Product         `gorm:"foreignKey:LocationID"`
+	StockLevels    []StockLevel      `gorm:"foreignKey:LocationID"`
+	StockMovements []StockMovement   `gorm:"foreignKey:LocationID"`
 }
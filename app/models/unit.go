@@ -0,0 +1,13 @@
+package models
+
+import (
+	"github.com/goravel/framework/database/orm"
+)
+
+type Unit struct {
+	orm.Model
+	Code     string  `gorm:"size:50;not null;uniqueIndex"`
+	Name     string  `gorm:"size:100;not null"`
+	Category string  `gorm:"size:20;not null;index"`                // mass, length, area, volume, count
+	Factor   float64 `gorm:"type:decimal(18,6);not null;default:1"` // size in the base unit of the category
+}
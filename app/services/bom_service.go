@@ -0,0 +1,352 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// RecipeCycleError is returned when recipes lead back to a variant they are made from. The
+// path lists the variant IDs from the variant back to itself.
+type RecipeCycleError struct {
+	Path []uint
+}
+
+func (e *RecipeCycleError) Error() string {
+	steps := make([]string, 0, len(e.Path))
+	for _, id := range e.Path {
+		steps = append(steps, fmt.Sprint(id))
+	}
+	return "recipe cycle through variants " + strings.Join(steps, " -> ")
+}
+
+// BomLine is one row of an indented bill of materials
+type BomLine struct {
+	Level         int     `json:"level"`
+	VariantID     uint    `json:"variant_id"`
+	ProductID     uint    `json:"product_id"`
+	Title         string  `json:"title"`
+	SKU           string  `json:"sku"`
+	Quantity      float64 `json:"quantity"`
+	Unit          string  `json:"unit"`
+	ScrapPercent  float64 `json:"scrap_percent"`
+	IsRawMaterial bool    `json:"is_raw_material"`
+	HasRecipe     bool    `json:"has_recipe"`
+	Expanded      bool    `json:"expanded"`
+}
+
+// BomRequirement is the total need of a leaf material over the whole explosion
+type BomRequirement struct {
+	VariantID     uint    `json:"variant_id"`
+	ProductID     uint    `json:"product_id"`
+	Title         string  `json:"title"`
+	SKU           string  `json:"sku"`
+	Quantity      float64 `json:"quantity"`
+	Unit          string  `json:"unit"`
+	IsRawMaterial bool    `json:"is_raw_material"`
+}
+
+// BomExplosion is the multi-level explosion of a variant for a quantity
+type BomExplosion struct {
+	VariantID    uint             `json:"variant_id"`
+	Quantity     float64          `json:"quantity"`
+	Levels       int              `json:"levels"` // 0 when all levels are expanded
+	Lines        []BomLine        `json:"lines"`
+	Requirements []BomRequirement `json:"requirements"`
+}
+
+// WhereUsedLine is a recipe using a material or a sub-assembly made from it
+type WhereUsedLine struct {
+	Level              int     `json:"level"`
+	RecipeID           uint    `json:"recipe_id"`
+	RecipeVersion      int     `json:"recipe_version"`
+	VariantID          uint    `json:"variant_id"`
+	ProductID          uint    `json:"product_id"`
+	Title              string  `json:"title"`
+	SKU                string  `json:"sku"`
+	ComponentVariantID uint    `json:"component_variant_id"` // the material or sub-assembly used
+	Quantity           float64 `json:"quantity"`
+	Unit               string  `json:"unit"`
+}
+
+// WhereUsedOrder is an open manufacturing order depending on a material, with its
+// requirement rows for the material and the sub-assemblies made from it
+type WhereUsedOrder struct {
+	Order        models.OrderFabrication                `json:"order_fabrication"`
+	Requirements []models.ProductionMaterialRequirement `json:"requirements"`
+}
+
+// WhereUsed lists everything depending on a material variant
+type WhereUsed struct {
+	MaterialVariantID uint             `json:"material_variant_id"`
+	Uses              []WhereUsedLine  `json:"uses"`
+	Orders            []WhereUsedOrder `json:"orders"`
+}
+
+type BomService struct {
+	unitService *UnitService
+}
+
+func NewBomService() *BomService {
+	return &BomService{
+		unitService: NewUnitService(),
+	}
+}
+
+// Explode expands the recipe of a variant level by level. Sub-assemblies are expanded while
+// they have a recipe and the level limit, zero for none, is not reached; the leaves left are
+// summed into the requirements. Quantities include the scrap of the items and the parts lost
+// to the recipe yields.
+func (s *BomService) Explode(variantID uint, quantity float64, levels int) (*BomExplosion, error) {
+	explosion := &BomExplosion{
+		VariantID:    variantID,
+		Quantity:     quantity,
+		Levels:       levels,
+		Lines:        []BomLine{},
+		Requirements: []BomRequirement{},
+	}
+
+	recipes := map[uint]*models.RecipeVariant{}
+	index := map[uint]int{}
+	query := facades.Orm().Query()
+
+	var expand func(variantID uint, quantity float64, level int, path []uint) error
+	expand = func(variantID uint, quantity float64, level int, path []uint) error {
+		recipe, err := s.recipe(query, recipes, variantID)
+		if err != nil {
+			return err
+		}
+		if recipe.OutputQuantity <= 0 {
+			return ErrInvalidRecipeOutput
+		}
+
+		factor := quantity / recipe.OutputQuantity
+		for _, item := range recipe.RecipeVariantItems {
+			material := item.MaterialVariant
+			unit := material.Unit
+			if unit == "" {
+				unit = material.Product.Unit
+			}
+			needed, err := s.unitService.Convert(query, material.ProductID, &material.ID, item.Quantity, item.Unit, unit)
+			if err != nil {
+				return err
+			}
+			needed = roundQuantity(grossQuantity(*recipe, item, needed) * factor)
+
+			for i, id := range path {
+				if id == material.ID {
+					return &RecipeCycleError{Path: append(append([]uint{}, path[i:]...), material.ID)}
+				}
+			}
+
+			sub, err := s.recipe(query, recipes, material.ID)
+			if err != nil {
+				return err
+			}
+			line := BomLine{
+				Level:         level,
+				VariantID:     material.ID,
+				ProductID:     material.ProductID,
+				Title:         material.Title,
+				SKU:           material.SKU,
+				Quantity:      needed,
+				Unit:          unit,
+				ScrapPercent:  item.ScrapPercent,
+				IsRawMaterial: material.Product.IsRawMaterial,
+				HasRecipe:     sub != nil,
+				Expanded:      sub != nil && (levels == 0 || level < levels),
+			}
+			explosion.Lines = append(explosion.Lines, line)
+
+			if line.Expanded {
+				if err := expand(material.ID, needed, level+1, append(path, material.ID)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if i, ok := index[material.ID]; ok {
+				explosion.Requirements[i].Quantity = roundQuantity(explosion.Requirements[i].Quantity + needed)
+				continue
+			}
+			index[material.ID] = len(explosion.Requirements)
+			explosion.Requirements = append(explosion.Requirements, BomRequirement{
+				VariantID:     material.ID,
+				ProductID:     material.ProductID,
+				Title:         material.Title,
+				SKU:           material.SKU,
+				Quantity:      needed,
+				Unit:          unit,
+				IsRawMaterial: material.Product.IsRawMaterial,
+			})
+		}
+
+		return nil
+	}
+
+	recipe, err := s.recipe(query, recipes, variantID)
+	if err != nil {
+		return nil, err
+	}
+	if recipe == nil {
+		return nil, ErrMissingRecipe
+	}
+	if err := expand(variantID, quantity, 1, []uint{variantID}); err != nil {
+		return nil, err
+	}
+
+	return explosion, nil
+}
+
+// CheckCycle makes sure that giving a variant a recipe made of the given materials does not
+// let the active recipes lead back to the variant. The versions of the variant itself are
+// ignored since the new items replace them.
+func (s *BomService) CheckCycle(tx orm.Query, variantID uint, materialIDs []uint) error {
+	var recipes []models.RecipeVariant
+	if err := tx.Select("id", "variant_id").Where("variant_id <> ?", variantID).
+		Where("status", models.RecipeStatusActive).Find(&recipes); err != nil {
+		return err
+	}
+	variantOf := map[uint]uint{}
+	for _, recipe := range recipes {
+		variantOf[recipe.ID] = recipe.VariantID
+	}
+
+	var items []models.RecipeVariantItem
+	if err := tx.Select("recipe_variant_id", "material_variant_id").Find(&items); err != nil {
+		return err
+	}
+	children := map[uint][]uint{}
+	for _, item := range items {
+		if parent, ok := variantOf[item.RecipeVariantID]; ok {
+			children[parent] = append(children[parent], item.MaterialVariantID)
+		}
+	}
+	children[variantID] = materialIDs
+
+	// Depth-first search keeping the current path, from the variant back to itself
+	visited := map[uint]bool{}
+	var walk func(id uint, path []uint) []uint
+	walk = func(id uint, path []uint) []uint {
+		for _, child := range children[id] {
+			if child == variantID {
+				return append(append([]uint{}, path...), child)
+			}
+			if visited[child] {
+				continue
+			}
+			visited[child] = true
+			if cycle := walk(child, append(path, child)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+
+	if cycle := walk(variantID, []uint{variantID}); cycle != nil {
+		return &RecipeCycleError{Path: cycle}
+	}
+
+	return nil
+}
+
+// recipe returns the recipe version of a variant in effect today with its items, or nil when
+// it has none
+func (s *BomService) recipe(query orm.Query, recipes map[uint]*models.RecipeVariant, variantID uint) (*models.RecipeVariant, error) {
+	if recipe, ok := recipes[variantID]; ok {
+		return recipe, nil
+	}
+
+	var recipe models.RecipeVariant
+	if err := effectiveRecipe(query.With("RecipeVariantItems.MaterialVariant.Product"), variantID, time.Now(), &recipe); err != nil {
+		return nil, err
+	}
+	if recipe.ID == 0 {
+		recipes[variantID] = nil
+		return nil, nil
+	}
+
+	recipes[variantID] = &recipe
+	return &recipe, nil
+}
+
+// WhereUsed walks the active recipe versions upward from a material variant, level by level,
+// to every recipe and sub-assembly depending on it, and lists the open manufacturing orders
+// making one of them or requiring one of them
+func (s *BomService) WhereUsed(materialVariantID uint) (*WhereUsed, error) {
+	result := &WhereUsed{
+		MaterialVariantID: materialVariantID,
+		Uses:              []WhereUsedLine{},
+		Orders:            []WhereUsedOrder{},
+	}
+
+	query := facades.Orm().Query()
+	visited := map[uint]bool{materialVariantID: true}
+	components := []uint{materialVariantID}
+	parents := []uint{}
+	frontier := []uint{materialVariantID}
+	for level := 1; len(frontier) > 0; level++ {
+		var items []models.RecipeVariantItem
+		if err := query.With("RecipeVariant.Variant").Where("material_variant_id IN ?", frontier).
+			Where("recipe_variant_id IN (SELECT id FROM recipe_variants WHERE status = ?)", models.RecipeStatusActive).
+			OrderBy("id").Find(&items); err != nil {
+			return nil, err
+		}
+
+		frontier = []uint{}
+		for _, item := range items {
+			recipe := item.RecipeVariant
+			result.Uses = append(result.Uses, WhereUsedLine{
+				Level:              level,
+				RecipeID:           recipe.ID,
+				RecipeVersion:      recipe.Version,
+				VariantID:          recipe.VariantID,
+				ProductID:          recipe.ProductID,
+				Title:              recipe.Variant.Title,
+				SKU:                recipe.Variant.SKU,
+				ComponentVariantID: item.MaterialVariantID,
+				Quantity:           item.Quantity,
+				Unit:               item.Unit,
+			})
+			if !visited[recipe.VariantID] {
+				visited[recipe.VariantID] = true
+				frontier = append(frontier, recipe.VariantID)
+				parents = append(parents, recipe.VariantID)
+				components = append(components, recipe.VariantID)
+			}
+		}
+	}
+
+	openStatuses := []string{
+		models.OrderFabricationStatusPending,
+		models.OrderFabricationStatusReleased,
+		models.OrderFabricationStatusInProgress,
+		models.OrderFabricationStatusOnHold,
+	}
+	orderQuery := query.With("Product").With("Variant").With("Client").Where("status IN ?", openStatuses)
+	if len(parents) > 0 {
+		orderQuery = orderQuery.Where("variant_id IN ? OR id IN (SELECT order_fabrication_id FROM production_material_requirements WHERE material_variant_id IN ?)", parents, components)
+	} else {
+		orderQuery = orderQuery.Where("id IN (SELECT order_fabrication_id FROM production_material_requirements WHERE material_variant_id IN ?)", components)
+	}
+	var orders []models.OrderFabrication
+	if err := orderQuery.OrderBy("id").Find(&orders); err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		var requirements []models.ProductionMaterialRequirement
+		if err := query.With("MaterialVariant").Where("order_fabrication_id", order.ID).
+			Where("material_variant_id IN ?", components).Find(&requirements); err != nil {
+			return nil, err
+		}
+		result.Orders = append(result.Orders, WhereUsedOrder{Order: order, Requirements: requirements})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// Material cost sources. Purchase costs materials at their PrixAchat; valuation at the
+// average cost of the stock on hand, falling back to PrixAchat for materials out of stock.
+const (
+	CostSourcePurchase  = "purchase"
+	CostSourceValuation = "valuation"
+)
+
+// CostSources lists the valid material cost sources
+var CostSources = []string{CostSourcePurchase, CostSourceValuation}
+
+// costBasisRecipe marks the lines costed by rolling up the recipe of a sub-assembly
+const costBasisRecipe = "recipe"
+
+var ErrInvalidCostSource = errors.New("invalid material cost source")
+
+// CostLine is the cost of a recipe item for one unit of the variant made with it
+type CostLine struct {
+	VariantID       uint       `json:"variant_id"`
+	ProductID       uint       `json:"product_id"`
+	Title           string     `json:"title"`
+	SKU             string     `json:"sku"`
+	Quantity        float64    `json:"quantity"` // per unit of the parent, in the material unit
+	Unit            string     `json:"unit"`
+	UnitCost        float64    `json:"unit_cost"`
+	Cost            float64    `json:"cost"`
+	Basis           string     `json:"basis"` // purchase, valuation or recipe
+	RecipeVariantID *uint      `json:"recipe_variant_id"`
+	Lines           []CostLine `json:"lines"` // the roll-up of a sub-assembly
+}
+
+// CostRollUp is the cost of one unit of a variant rolled up through its recipe
+type CostRollUp struct {
+	VariantID       uint       `json:"variant_id"`
+	RecipeVariantID uint       `json:"recipe_variant_id"`
+	Source          string     `json:"source"`
+	MaterialCost    float64    `json:"material_cost"`
+	LabourCost      float64    `json:"labour_cost"`
+	TotalCost       float64    `json:"total_cost"`
+	Lines           []CostLine `json:"lines"`
+}
+
+// CostRollUpFailure is a variant the bulk roll-up could not cost
+type CostRollUpFailure struct {
+	VariantID uint   `json:"variant_id"`
+	SKU       string `json:"sku"`
+	Error     string `json:"error"`
+}
+
+// MarginLine compares the standard cost of a variant with its selling price
+type MarginLine struct {
+	StandardCost models.StandardCost `json:"standard_cost"`
+	PrixVente    float64             `json:"prix_vente"`
+	Margin       float64             `json:"margin"`
+	MarginRate   *float64            `json:"margin_rate"` // percentage of the selling price, nil without price
+}
+
+type CostService struct {
+	unitService    *UnitService
+	routingService *RoutingService
+}
+
+func NewCostService() *CostService {
+	return &CostService{
+		unitService:    NewUnitService(),
+		routingService: NewRoutingService(),
+	}
+}
+
+// DefaultSource returns the configured material cost source
+func (s *CostService) DefaultSource() string {
+	return facades.Config().GetString("app.costing.material_cost_source", CostSourcePurchase)
+}
+
+// RollUp costs one unit of a variant from the recipe version in effect today, recursively
+// through its sub-assemblies. An empty source uses the configured one.
+func (s *CostService) RollUp(variantID uint, source string) (*CostRollUp, error) {
+	source, err := s.source(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.rollUp(facades.Orm().Query(), variantID, source, []uint{variantID}, map[uint]*CostRollUp{})
+}
+
+// Save rolls up the cost of a variant and stores it as its standard cost
+func (s *CostService) Save(variantID uint, source string) (*models.StandardCost, *CostRollUp, error) {
+	source, err := s.source(source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var cost *models.StandardCost
+	var rollUp *CostRollUp
+	err = facades.Orm().Transaction(func(tx orm.Query) error {
+		var err error
+		rollUp, err = s.rollUp(tx, variantID, source, []uint{variantID}, map[uint]*CostRollUp{})
+		if err != nil {
+			return err
+		}
+		cost, err = s.store(tx, rollUp)
+		return err
+	})
+
+	return cost, rollUp, err
+}
+
+// RollUpAll stores the standard cost of every variant with a recipe in effect, of one
+// product or of all. Variants that cannot be costed are reported and skipped; sub-assemblies
+// are rolled up once for the whole run.
+func (s *CostService) RollUpAll(productID *uint, source string) ([]models.StandardCost, []CostRollUpFailure, error) {
+	source, err := s.source(source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := facades.Orm().Query()
+	variantQuery := query.Where("id IN (SELECT variant_id FROM recipe_variants WHERE status = ?)", models.RecipeStatusActive)
+	if productID != nil {
+		variantQuery = variantQuery.Where("product_id", *productID)
+	}
+	var variants []models.ProductVariant
+	if err := variantQuery.OrderBy("id").Find(&variants); err != nil {
+		return nil, nil, err
+	}
+
+	costs := []models.StandardCost{}
+	failures := []CostRollUpFailure{}
+	memo := map[uint]*CostRollUp{}
+	for _, variant := range variants {
+		rollUp, err := s.rollUp(query, variant.ID, source, []uint{variant.ID}, memo)
+		if err != nil {
+			var cycleErr *RecipeCycleError
+			var unitErr *UnitConversionError
+			if errors.Is(err, ErrMissingRecipe) || errors.Is(err, ErrInvalidRecipeOutput) ||
+				errors.As(err, &cycleErr) || errors.As(err, &unitErr) {
+				failures = append(failures, CostRollUpFailure{VariantID: variant.ID, SKU: variant.SKU, Error: err.Error()})
+				continue
+			}
+			return costs, failures, err
+		}
+
+		cost, err := s.store(query, rollUp)
+		if err != nil {
+			return costs, failures, err
+		}
+		costs = append(costs, *cost)
+	}
+
+	return costs, failures, nil
+}
+
+// Margin compares a standard cost with the selling price of its variant, or of its product
+// when the variant has none. The cost must be loaded with its variant and product.
+func (s *CostService) Margin(cost models.StandardCost) MarginLine {
+	price := cost.Variant.PrixVente
+	if price == 0 {
+		price = cost.Product.PrixVente
+	}
+
+	line := MarginLine{
+		StandardCost: cost,
+		PrixVente:    price,
+		Margin:       roundCost(price - cost.TotalCost),
+	}
+	if price > 0 {
+		rate := roundCost(line.Margin / price * 100)
+		line.MarginRate = &rate
+	}
+
+	return line
+}
+
+// rollUp costs a variant from its recipe in effect. The path holds the variants being
+// rolled up, from the top one, to stop on recipe cycles.
+func (s *CostService) rollUp(query orm.Query, variantID uint, source string, path []uint, memo map[uint]*CostRollUp) (*CostRollUp, error) {
+	if rollUp, ok := memo[variantID]; ok {
+		return rollUp, nil
+	}
+
+	var recipe models.RecipeVariant
+	if err := effectiveRecipe(query.With("RecipeVariantItems.MaterialVariant.Product"), variantID, time.Now(), &recipe); err != nil {
+		return nil, err
+	}
+	if recipe.ID == 0 || len(recipe.RecipeVariantItems) == 0 {
+		return nil, ErrMissingRecipe
+	}
+	if recipe.OutputQuantity <= 0 {
+		return nil, ErrInvalidRecipeOutput
+	}
+
+	rollUp := &CostRollUp{
+		VariantID:       variantID,
+		RecipeVariantID: recipe.ID,
+		Source:          source,
+		Lines:           []CostLine{},
+	}
+	for _, item := range recipe.RecipeVariantItems {
+		material := item.MaterialVariant
+		if i := slices.Index(path, material.ID); i >= 0 {
+			return nil, &RecipeCycleError{Path: append(append([]uint{}, path[i:]...), material.ID)}
+		}
+
+		unit := material.Unit
+		if unit == "" {
+			unit = material.Product.Unit
+		}
+		quantity, err := s.unitService.Convert(query, material.ProductID, &material.ID, item.Quantity, item.Unit, unit)
+		if err != nil {
+			return nil, err
+		}
+		quantity = grossQuantity(recipe, item, quantity)
+
+		line := CostLine{
+			VariantID: material.ID,
+			ProductID: material.ProductID,
+			Title:     material.Title,
+			SKU:       material.SKU,
+			Quantity:  roundQuantity(quantity / recipe.OutputQuantity),
+			Unit:      unit,
+			Lines:     []CostLine{},
+		}
+
+		// Sub-assemblies are costed from their own recipe, purchased materials at their price
+		var sub *CostRollUp
+		if !material.Product.IsRawMaterial {
+			sub, err = s.rollUp(query, material.ID, source, append(path, material.ID), memo)
+			if err != nil && !errors.Is(err, ErrMissingRecipe) {
+				return nil, err
+			}
+		}
+		if sub != nil {
+			line.UnitCost = sub.TotalCost
+			line.Basis = costBasisRecipe
+			line.RecipeVariantID = &sub.RecipeVariantID
+			line.Lines = sub.Lines
+		} else {
+			line.UnitCost, line.Basis, err = s.materialCost(query, material, source)
+			if err != nil {
+				return nil, err
+			}
+		}
+		line.Cost = roundCost(quantity / recipe.OutputQuantity * line.UnitCost)
+
+		rollUp.MaterialCost += line.Cost
+		rollUp.Lines = append(rollUp.Lines, line)
+	}
+	rollUp.MaterialCost = roundCost(rollUp.MaterialCost)
+
+	// Labour comes from the routing, its setup spread over one recipe batch
+	labourCost, err := s.routingService.LabourCost(query, recipe.ProductID, variantID, recipe.OutputQuantity)
+	if err != nil {
+		return nil, err
+	}
+	rollUp.LabourCost = labourCost
+	rollUp.TotalCost = roundCost(rollUp.MaterialCost + rollUp.LabourCost)
+
+	memo[variantID] = rollUp
+	return rollUp, nil
+}
+
+// materialCost returns the cost of one unit of a purchased material and the basis used
+func (s *CostService) materialCost(query orm.Query, material models.ProductVariant, source string) (float64, string, error) {
+	if source == CostSourceValuation {
+		var stock struct {
+			Quantity float64
+			Value    float64
+		}
+		if err := query.Model(&models.StockLevel{}).Select("COALESCE(SUM(quantity), 0) AS quantity, COALESCE(SUM(total_value), 0) AS value").
+			Where("variant_id", material.ID).Where("quantity > 0").Scan(&stock); err != nil {
+			return 0, "", err
+		}
+		if stock.Quantity > 0 {
+			return roundCost(stock.Value / stock.Quantity), CostSourceValuation, nil
+		}
+	}
+
+	price := material.PrixAchat
+	if price == 0 {
+		price = material.Product.PrixAchat
+	}
+
+	return price, CostSourcePurchase, nil
+}
+
+// store creates or replaces the standard cost of a rolled-up variant
+func (s *CostService) store(query orm.Query, rollUp *CostRollUp) (*models.StandardCost, error) {
+	var variant models.ProductVariant
+	if err := query.Where("id", rollUp.VariantID).FirstOrFail(&variant); err != nil {
+		return nil, err
+	}
+
+	var cost models.StandardCost
+	if err := query.Where("variant_id", variant.ID).First(&cost); err != nil {
+		return nil, err
+	}
+	cost.ProductID = variant.ProductID
+	cost.VariantID = variant.ID
+	cost.RecipeVariantID = &rollUp.RecipeVariantID
+	cost.CostSource = rollUp.Source
+	cost.MaterialCost = rollUp.MaterialCost
+	cost.LabourCost = rollUp.LabourCost
+	cost.TotalCost = rollUp.TotalCost
+	cost.ComputedAt = time.Now()
+
+	if err := query.Save(&cost); err != nil {
+		return nil, err
+	}
+
+	return &cost, nil
+}
+
+// source validates a material cost source, defaulting to the configured one
+func (s *CostService) source(source string) (string, error) {
+	if source == "" {
+		source = s.DefaultSource()
+	}
+	if !slices.Contains(CostSources, source) {
+		return "", ErrInvalidCostSource
+	}
+
+	return source, nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+var ErrChangeOrderPending = errors.New("the recipe version already has a pending change order")
+
+// InvalidChangeOrderStatusError is returned when a change order or its recipe version is not
+// in the status an action requires
+type InvalidChangeOrderStatusError struct {
+	Document string
+	Status   string
+	Expected []string
+}
+
+func (e *InvalidChangeOrderStatusError) Error() string {
+	return fmt.Sprintf("%s is %s, expected one of %v", e.Document, e.Status, e.Expected)
+}
+
+type EngineeringChangeOrderService struct {
+	sequenceService *SequenceService
+	recipeService   *RecipeService
+	bomService      *BomService
+}
+
+func NewEngineeringChangeOrderService() *EngineeringChangeOrderService {
+	return &EngineeringChangeOrderService{
+		sequenceService: NewSequenceService(),
+		recipeService:   NewRecipeService(),
+		bomService:      NewBomService(),
+	}
+}
+
+// Create numbers and stores a pending change order putting a draft recipe version into
+// effect. The version in effect at the time is recorded as the one it replaces.
+func (s *EngineeringChangeOrderService) Create(order *models.EngineeringChangeOrder, user models.User) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		var recipe models.RecipeVariant
+		if err := tx.LockForUpdate().Where("id", order.RecipeVariantID).FirstOrFail(&recipe); err != nil {
+			return err
+		}
+		if recipe.Status != models.RecipeStatusDraft {
+			return &InvalidChangeOrderStatusError{Document: "recipe version", Status: recipe.Status, Expected: []string{models.RecipeStatusDraft}}
+		}
+
+		pending, err := tx.Model(&models.EngineeringChangeOrder{}).Where("recipe_variant_id", recipe.ID).
+			Where("status", models.EngineeringChangeOrderStatusPending).Exists()
+		if err != nil {
+			return err
+		}
+		if pending {
+			return ErrChangeOrderPending
+		}
+
+		previous, err := s.recipeService.EffectiveTx(tx, recipe.VariantID, time.Now())
+		if err != nil {
+			return err
+		}
+
+		ecoNumber, err := s.sequenceService.NextTx(tx, SequenceEngineeringChangeOrder)
+		if err != nil {
+			return err
+		}
+		order.EcoNumber = ecoNumber
+		order.VariantID = recipe.VariantID
+		order.Status = models.EngineeringChangeOrderStatusPending
+		order.RequestedBy = user.ID
+		order.PreviousRecipeID = nil
+		if previous != nil {
+			order.PreviousRecipeID = &previous.ID
+		}
+
+		return tx.Create(order)
+	})
+}
+
+// Approve puts the recipe version of a pending change order into effect from the change
+// order's date, or at once. Orders released from then on use it; orders already released
+// keep the version they were pinned to.
+func (s *EngineeringChangeOrderService) Approve(order *models.EngineeringChangeOrder, user models.User) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := s.lockWithStatus(tx, order, models.EngineeringChangeOrderStatusPending); err != nil {
+			return err
+		}
+
+		var recipe models.RecipeVariant
+		if err := tx.LockForUpdate().With("RecipeVariantItems").Where("id", order.RecipeVariantID).FirstOrFail(&recipe); err != nil {
+			return err
+		}
+		if recipe.Status != models.RecipeStatusDraft {
+			return &InvalidChangeOrderStatusError{Document: "recipe version", Status: recipe.Status, Expected: []string{models.RecipeStatusDraft}}
+		}
+
+		// Other recipes may have changed since the draft was saved
+		materialIDs := make([]uint, 0, len(recipe.RecipeVariantItems))
+		for _, item := range recipe.RecipeVariantItems {
+			materialIDs = append(materialIDs, item.MaterialVariantID)
+		}
+		if err := s.bomService.CheckCycle(tx, recipe.VariantID, materialIDs); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		effectiveFrom := now
+		if order.EffectiveFrom != nil {
+			effectiveFrom = *order.EffectiveFrom
+		}
+		if _, err := tx.Model(&models.RecipeVariant{}).Where("id", recipe.ID).Update(map[string]any{
+			"status":         models.RecipeStatusActive,
+			"effective_from": effectiveFrom,
+		}); err != nil {
+			return err
+		}
+		if err := s.recipeService.ObsoleteSupersededTx(tx, recipe.VariantID, now); err != nil {
+			return err
+		}
+
+		order.Status = models.EngineeringChangeOrderStatusApproved
+		order.ApprovedBy = &user.ID
+		order.ApprovedAt = &now
+
+		return tx.Save(order)
+	})
+}
+
+// Reject closes a pending change order; its recipe version stays a draft that can be
+// changed and submitted again
+func (s *EngineeringChangeOrderService) Reject(order *models.EngineeringChangeOrder, reason string, user models.User) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := s.lockWithStatus(tx, order, models.EngineeringChangeOrderStatusPending); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		order.Status = models.EngineeringChangeOrderStatusRejected
+		order.RejectionReason = reason
+		order.ApprovedBy = &user.ID
+		order.ApprovedAt = &now
+
+		return tx.Save(order)
+	})
+}
+
+// lockWithStatus reloads a change order under lock and checks its status
+func (s *EngineeringChangeOrderService) lockWithStatus(tx orm.Query, order *models.EngineeringChangeOrder, expected ...string) error {
+	id := order.ID
+	*order = models.EngineeringChangeOrder{}
+	if err := tx.LockForUpdate().Where("id", id).FirstOrFail(order); err != nil {
+		return err
+	}
+	if slices.Contains(expected, order.Status) {
+		return nil
+	}
+
+	return &InvalidChangeOrderStatusError{Document: "change order", Status: order.Status, Expected: expected}
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// Inventory count statuses
+const (
+	InventoryCountStatusOpen      = "open"
+	InventoryCountStatusValidated = "validated"
+	InventoryCountStatusCancelled = "cancelled"
+)
+
+// ReferenceTypeInventoryCount tags the adjustments posted when a count is validated
+const ReferenceTypeInventoryCount = "inventory_count"
+
+var (
+	ErrCountNotOpen           = errors.New("inventory count is not open")
+	ErrCountAlreadyOpen       = errors.New("an inventory count is already open on this location")
+	ErrProductOutOfCountScope = errors.New("product is outside the categories of the inventory count")
+)
+
+// CountEntry is a quantity counted for a product or variant. Entries with Add set are
+// summed with what other devices already counted instead of replacing it.
+type CountEntry struct {
+	ProductID uint
+	VariantID *uint
+	Quantity  float64
+	Add       bool
+}
+
+type InventoryCountService struct {
+	sequenceService *SequenceService
+	stockService    *StockService
+	locationService *LocationService
+}
+
+func NewInventoryCountService() *InventoryCountService {
+	return &InventoryCountService{
+		sequenceService: NewSequenceService(),
+		stockService:    NewStockService(),
+		locationService: NewLocationService(),
+	}
+}
+
+// Open starts a count session on a location, optionally restricted to categories, and
+// snapshots the expected quantities of the stock levels in scope. Stock only sits on the
+// locations able to hold it, so counts are opened on those only.
+func (s *InventoryCountService) Open(count *models.InventoryCount, categoryIDs []uint) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		// Serialise openings on the location so two sessions cannot overlap
+		var location models.StorageLocation
+		if err := tx.LockForUpdate().Where("id", count.LocationID).FirstOrFail(&location); err != nil {
+			return err
+		}
+		if err := s.locationService.CheckStockable(tx, location.ID); err != nil {
+			return err
+		}
+		open, err := tx.Model(&models.InventoryCount{}).Where("location_id", count.LocationID).Where("status", InventoryCountStatusOpen).Exists()
+		if err != nil {
+			return err
+		}
+		if open {
+			return ErrCountAlreadyOpen
+		}
+
+		countNumber, err := s.sequenceService.NextTx(tx, SequenceInventoryCount)
+		if err != nil {
+			return err
+		}
+		if categoryIDs == nil {
+			categoryIDs = []uint{}
+		}
+		categories, err := json.Marshal(categoryIDs)
+		if err != nil {
+			return err
+		}
+
+		count.CountNumber = countNumber
+		count.CategoryIDs = string(categories)
+		count.Status = InventoryCountStatusOpen
+		count.OpenedAt = time.Now()
+		if err := tx.Create(count); err != nil {
+			return err
+		}
+
+		// Lock the levels so no movement slips in between the snapshot and the session start
+		query := tx.LockForUpdate().Where("location_id", count.LocationID)
+		if len(categoryIDs) > 0 {
+			query = query.Where("product_id IN (SELECT id FROM products WHERE category_id IN ?)", categoryIDs)
+		}
+		var levels []models.StockLevel
+		if err := query.Find(&levels); err != nil {
+			return err
+		}
+
+		for _, level := range levels {
+			line := models.InventoryCountLine{
+				InventoryCountID: count.ID,
+				ProductID:        level.ProductID,
+				VariantID:        level.VariantID,
+				ExpectedQuantity: level.Quantity,
+				Unit:             level.Unit,
+			}
+			if err := tx.Create(&line); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Submit records counted quantities. Products found on the shelf but missing from the
+// snapshot get a new line with an expected quantity of zero.
+func (s *InventoryCountService) Submit(count *models.InventoryCount, entries []CountEntry, user models.User) ([]models.InventoryCountLine, error) {
+	lines := make([]models.InventoryCountLine, 0, len(entries))
+	err := facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := s.lockOpen(tx, count); err != nil {
+			return err
+		}
+		categoryIDs := InventoryCountCategories(*count)
+
+		for _, entry := range entries {
+			var line models.InventoryCountLine
+			query := tx.LockForUpdate().Where("inventory_count_id", count.ID).Where("product_id", entry.ProductID)
+			if entry.VariantID != nil {
+				query = query.Where("variant_id", *entry.VariantID)
+			} else {
+				query = query.WhereNull("variant_id")
+			}
+			if err := query.First(&line); err != nil {
+				return err
+			}
+
+			if line.ID == 0 {
+				var product models.Product
+				if err := tx.Where("id", entry.ProductID).FirstOrFail(&product); err != nil {
+					return err
+				}
+				if len(categoryIDs) > 0 && (product.CategoryID == nil || !slices.Contains(categoryIDs, *product.CategoryID)) {
+					return ErrProductOutOfCountScope
+				}
+				line = models.InventoryCountLine{
+					InventoryCountID: count.ID,
+					ProductID:        entry.ProductID,
+					VariantID:        entry.VariantID,
+					Unit:             product.Unit,
+				}
+			}
+
+			quantity := entry.Quantity
+			if entry.Add && line.CountedQuantity != nil {
+				quantity += *line.CountedQuantity
+			}
+			quantity = roundQuantity(quantity)
+			now := time.Now()
+			line.CountedQuantity = &quantity
+			line.CountedBy = &user.ID
+			line.CountedAt = &now
+
+			if err := tx.Save(&line); err != nil {
+				return err
+			}
+			withVariance(&line)
+			lines = append(lines, line)
+		}
+
+		return nil
+	})
+
+	return lines, err
+}
+
+// Validate closes the session and posts one adjustment per counted line: the counted
+// quantity minus the snapshot taken at opening. Movements posted during a non-blocking
+// count are kept on top of the adjustment instead of being overwritten. Lines never
+// counted are left untouched.
+func (s *InventoryCountService) Validate(count *models.InventoryCount, user models.User) ([]models.StockMovement, error) {
+	adjustments := []models.StockMovement{}
+	err := facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := s.lockOpen(tx, count); err != nil {
+			return err
+		}
+
+		// Close the session first so the adjustments are not blocked by it
+		now := time.Now()
+		count.Status = InventoryCountStatusValidated
+		count.ValidatedBy = &user.ID
+		count.ValidatedAt = &now
+		if err := tx.Save(count); err != nil {
+			return err
+		}
+
+		var lines []models.InventoryCountLine
+		if err := tx.Where("inventory_count_id", count.ID).WhereNotNull("counted_quantity").Find(&lines); err != nil {
+			return err
+		}
+
+		countID := count.ID
+		for _, line := range lines {
+			delta := roundQuantity(*line.CountedQuantity - line.ExpectedQuantity)
+			if delta == 0 {
+				continue
+			}
+
+			movement := models.StockMovement{
+				ProductID:     line.ProductID,
+				VariantID:     line.VariantID,
+				LocationID:    count.LocationID,
+				MovementType:  MovementTypeAdjustment,
+				Quantity:      delta,
+				Unit:          line.Unit,
+				ReferenceType: ReferenceTypeInventoryCount,
+				ReferenceID:   &countID,
+				Notes:         "Inventory count " + count.CountNumber,
+				CreatedBy:     user.ID,
+			}
+			if _, err := s.stockService.PostMovementTx(tx, &movement); err != nil {
+				return err
+			}
+			adjustments = append(adjustments, movement)
+		}
+
+		return nil
+	})
+
+	return adjustments, err
+}
+
+// Cancel abandons an open session without touching stock
+func (s *InventoryCountService) Cancel(count *models.InventoryCount) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := s.lockOpen(tx, count); err != nil {
+			return err
+		}
+		count.Status = InventoryCountStatusCancelled
+
+		return tx.Save(count)
+	})
+}
+
+// Lines returns the lines of a count with their variance
+func (s *InventoryCountService) Lines(count models.InventoryCount) ([]models.InventoryCountLine, error) {
+	var lines []models.InventoryCountLine
+	if err := facades.Orm().Query().With("Product").With("Variant").With("CountedByUser").
+		Where("inventory_count_id", count.ID).OrderBy("product_id").OrderBy("variant_id").Find(&lines); err != nil {
+		return nil, err
+	}
+	for i := range lines {
+		withVariance(&lines[i])
+	}
+
+	return lines, nil
+}
+
+// MovementsDuringCount returns the movements posted on the counted location while the
+// session was open, which make the snapshot stale when movements were not blocked
+func (s *InventoryCountService) MovementsDuringCount(count models.InventoryCount) ([]models.StockMovement, error) {
+	query := facades.Orm().Query().With("Product").With("Variant").
+		Where("location_id", count.LocationID).
+		Where("created_at >= ?", count.OpenedAt).
+		Where("(reference_type IS NULL OR reference_type <> ?)", ReferenceTypeInventoryCount)
+	if count.ValidatedAt != nil {
+		query = query.Where("created_at <= ?", *count.ValidatedAt)
+	}
+	if categoryIDs := InventoryCountCategories(count); len(categoryIDs) > 0 {
+		query = query.Where("product_id IN (SELECT id FROM products WHERE category_id IN ?)", categoryIDs)
+	}
+
+	var movements []models.StockMovement
+	if err := query.OrderBy("created_at").Find(&movements); err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+// lockOpen reloads a count under lock, without its relationships, and checks it is open
+func (s *InventoryCountService) lockOpen(tx orm.Query, count *models.InventoryCount) error {
+	id := count.ID
+	*count = models.InventoryCount{}
+	if err := tx.LockForUpdate().Where("id", id).FirstOrFail(count); err != nil {
+		return err
+	}
+	if count.Status != InventoryCountStatusOpen {
+		return ErrCountNotOpen
+	}
+
+	return nil
+}
+
+// InventoryCountCategories decodes the categories a count is restricted to
+func InventoryCountCategories(count models.InventoryCount) []uint {
+	var categoryIDs []uint
+	if count.CategoryIDs != "" {
+		_ = json.Unmarshal([]byte(count.CategoryIDs), &categoryIDs)
+	}
+
+	return categoryIDs
+}
+
+// withVariance fills the computed variance of a counted line
+func withVariance(line *models.InventoryCountLine) {
+	if line.CountedQuantity == nil {
+		line.Variance = nil
+		return
+	}
+	variance := roundQuantity(*line.CountedQuantity - line.ExpectedQuantity)
+	line.Variance = &variance
+}
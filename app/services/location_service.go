@@ -0,0 +1,294 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// LocationTypes lists the storage location types from the largest to the smallest. A child
+// location is always of a smaller type than its parent.
+var LocationTypes = []string{
+	models.StorageLocationTypeWarehouse,
+	models.StorageLocationTypeZone,
+	models.StorageLocationTypeAisle,
+	models.StorageLocationTypeRack,
+	models.StorageLocationTypeBin,
+}
+
+var (
+	ErrInvalidLocationType   = errors.New("location type must be warehouse, zone, aisle, rack or bin")
+	ErrLocationTypeOrder     = errors.New("a location must be of a smaller type than its parent")
+	ErrLocationCycle         = errors.New("a location cannot be moved under itself or one of its children")
+	ErrParentHoldsStock      = errors.New("a location holding stock cannot get children")
+	ErrLocationParentMissing = errors.New("parent location does not exist")
+	ErrLocationChildType     = errors.New("a location must be of a larger type than its children")
+	ErrLocationHoldsStock    = errors.New("a location holding stock must keep a type that may hold stock")
+)
+
+// LocationNotStockableError is returned when stock is posted on a location that may not hold it
+type LocationNotStockableError struct {
+	Name string
+	Type string
+}
+
+func (e *LocationNotStockableError) Error() string {
+	return fmt.Sprintf("location %s (%s) cannot hold stock", e.Name, e.Type)
+}
+
+// SubtreeStockLine is the stock of a product or variant summed over a location subtree
+type SubtreeStockLine struct {
+	ProductID uint    `json:"product_id"`
+	VariantID *uint   `json:"variant_id"`
+	Unit      string  `json:"unit"`
+	Quantity  float64 `json:"quantity"`
+	Value     float64 `json:"value"`
+}
+
+type LocationService struct {
+}
+
+func NewLocationService() *LocationService {
+	return &LocationService{}
+}
+
+// StockTypes returns the location types allowed to hold stock, set by app.stock.location_types
+func (s *LocationService) StockTypes() []string {
+	types := []string{}
+	for _, value := range strings.Split(facades.Config().GetString("app.stock.location_types", models.StorageLocationTypeBin), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			types = append(types, value)
+		}
+	}
+
+	return types
+}
+
+// CheckStockable refuses stock on a location whose type may not hold it or which has children
+func (s *LocationService) CheckStockable(tx orm.Query, locationID uint) error {
+	var location models.StorageLocation
+	if err := tx.Where("id", locationID).FirstOrFail(&location); err != nil {
+		return err
+	}
+
+	hasChildren, err := tx.Model(&models.StorageLocation{}).Where("parent_id", locationID).Exists()
+	if err != nil {
+		return err
+	}
+	if hasChildren || !slices.Contains(s.StockTypes(), location.Type) {
+		return &LocationNotStockableError{Name: location.Name, Type: location.Type}
+	}
+
+	return nil
+}
+
+// ValidatePlacement checks the type of a location and its place in the tree. The location
+// ID is zero for a new location.
+func (s *LocationService) ValidatePlacement(locationID uint, locationType string, parentID *uint) error {
+	rank := slices.Index(LocationTypes, locationType)
+	if rank < 0 {
+		return ErrInvalidLocationType
+	}
+	if locationID != 0 {
+		if err := s.validateTypeChange(locationID, locationType, rank); err != nil {
+			return err
+		}
+	}
+	if parentID == nil {
+		return nil
+	}
+
+	var parent models.StorageLocation
+	if err := facades.Orm().Query().Where("id", *parentID).First(&parent); err != nil {
+		return err
+	}
+	if parent.ID == 0 {
+		return ErrLocationParentMissing
+	}
+	if rank <= slices.Index(LocationTypes, parent.Type) {
+		return ErrLocationTypeOrder
+	}
+
+	if locationID != 0 {
+		descendants, err := s.SubtreeIDs(locationID)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(descendants, parent.ID) {
+			return ErrLocationCycle
+		}
+	}
+
+	// Stock only sits on leaves, so a parent must be empty
+	holdsStock, err := facades.Orm().Query().Model(&models.StockLevel{}).Where("location_id", parent.ID).Where("quantity <> ?", 0).Exists()
+	if err != nil {
+		return err
+	}
+	if holdsStock {
+		return ErrParentHoldsStock
+	}
+
+	return nil
+}
+
+// validateTypeChange checks a new type against what sits below an existing location: its
+// children must stay of a smaller type and its stock on a type that may hold stock
+func (s *LocationService) validateTypeChange(locationID uint, locationType string, rank int) error {
+	var location models.StorageLocation
+	if err := facades.Orm().Query().Where("id", locationID).FirstOrFail(&location); err != nil {
+		return err
+	}
+	if location.Type == locationType {
+		return nil
+	}
+
+	var children []models.StorageLocation
+	if err := facades.Orm().Query().Where("parent_id", locationID).Find(&children); err != nil {
+		return err
+	}
+	for _, child := range children {
+		if slices.Index(LocationTypes, child.Type) <= rank {
+			return ErrLocationChildType
+		}
+	}
+
+	if slices.Contains(s.StockTypes(), locationType) {
+		return nil
+	}
+	holdsStock, err := facades.Orm().Query().Model(&models.StockLevel{}).Where("location_id", locationID).Where("quantity <> ?", 0).Exists()
+	if err != nil {
+		return err
+	}
+	if holdsStock {
+		return ErrLocationHoldsStock
+	}
+
+	return nil
+}
+
+// SubtreeIDs returns a location and all the locations below it
+func (s *LocationService) SubtreeIDs(locationID uint) ([]uint, error) {
+	var locations []models.StorageLocation
+	if err := facades.Orm().Query().Select("id", "parent_id").Find(&locations); err != nil {
+		return nil, err
+	}
+
+	children := map[uint][]uint{}
+	for _, location := range locations {
+		if location.ParentID != nil {
+			children[*location.ParentID] = append(children[*location.ParentID], location.ID)
+		}
+	}
+
+	ids := []uint{locationID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+
+	return ids, nil
+}
+
+// Tree returns the root locations with their children nested down to the bins, each
+// node carrying the number of products and variants held in its subtree and their value.
+// Quantities are not summed, the products being kept in different units; the stock
+// endpoint of a location lists them per product.
+func (s *LocationService) Tree() ([]models.StorageLocation, error) {
+	var locations []models.StorageLocation
+	if err := facades.Orm().Query().OrderBy("name", "asc").Find(&locations); err != nil {
+		return nil, err
+	}
+
+	var levels []models.StockLevel
+	if err := facades.Orm().Query().Select("location_id, product_id, variant_id, total_value").
+		Where("quantity <> ?", 0).Find(&levels); err != nil {
+		return nil, err
+	}
+
+	return locationTree(locations, levels), nil
+}
+
+// locationTree nests the locations under their parents and totals the stock levels of
+// every subtree. A product held in several locations of a subtree is counted once.
+func locationTree(locations []models.StorageLocation, levels []models.StockLevel) []models.StorageLocation {
+	byID := map[uint]*models.StorageLocation{}
+	for i := range locations {
+		byID[locations[i].ID] = &locations[i]
+	}
+	held := map[uint]map[stockKey]bool{}
+	for _, level := range levels {
+		location, ok := byID[level.LocationID]
+		if !ok {
+			continue
+		}
+		if held[level.LocationID] == nil {
+			held[level.LocationID] = map[stockKey]bool{}
+		}
+		held[level.LocationID][newStockKey(level.ProductID, level.VariantID, 0)] = true
+		location.StockValue += level.TotalValue
+	}
+
+	children := map[uint][]uint{}
+	roots := []uint{}
+	for _, location := range locations {
+		if location.ParentID != nil && byID[*location.ParentID] != nil {
+			children[*location.ParentID] = append(children[*location.ParentID], location.ID)
+		} else {
+			roots = append(roots, location.ID)
+		}
+	}
+
+	var build func(id uint) (models.StorageLocation, map[stockKey]bool)
+	build = func(id uint) (models.StorageLocation, map[stockKey]bool) {
+		node := *byID[id]
+		node.Children = []models.StorageLocation{}
+		products := map[stockKey]bool{}
+		for key := range held[id] {
+			products[key] = true
+		}
+		for _, childID := range children[id] {
+			child, childProducts := build(childID)
+			for key := range childProducts {
+				products[key] = true
+			}
+			node.StockValue += child.StockValue
+			node.Children = append(node.Children, child)
+		}
+		node.StockProducts = len(products)
+		node.StockValue = roundCost(node.StockValue)
+		return node, products
+	}
+
+	tree := make([]models.StorageLocation, 0, len(roots))
+	for _, id := range roots {
+		node, _ := build(id)
+		tree = append(tree, node)
+	}
+
+	return tree
+}
+
+// SubtreeStock sums the stock levels of a location and everything below it per product
+// and variant
+func (s *LocationService) SubtreeStock(locationID uint) ([]SubtreeStockLine, error) {
+	ids, err := s.SubtreeIDs(locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []SubtreeStockLine{}
+	if err := facades.Orm().Query().Model(&models.StockLevel{}).
+		Select("product_id, variant_id, MAX(unit) AS unit, SUM(quantity) AS quantity, SUM(total_value) AS value").
+		Where("location_id IN ?", ids).Where("quantity <> ?", 0).
+		GroupBy("product_id", "variant_id").OrderBy("product_id").
+		Scan(&lines); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/goravel/framework/database/orm"
+	"github.com/stretchr/testify/assert"
+
+	"pms/app/models"
+)
+
+func TestLocationTreeCountsProductsOnce(t *testing.T) {
+	warehouse := uint(1)
+	rack := uint(2)
+	locations := []models.StorageLocation{
+		{Model: orm.Model{ID: 1}, Name: "Main", Type: models.StorageLocationTypeWarehouse},
+		{Model: orm.Model{ID: 2}, Name: "Rack A", Type: models.StorageLocationTypeRack, ParentID: &warehouse},
+		{Model: orm.Model{ID: 3}, Name: "Bin 1", Type: models.StorageLocationTypeBin, ParentID: &rack},
+		{Model: orm.Model{ID: 4}, Name: "Bin 2", Type: models.StorageLocationTypeBin, ParentID: &rack},
+		{Model: orm.Model{ID: 5}, Name: "Yard", Type: models.StorageLocationTypeWarehouse},
+	}
+	sheet := uint(10)
+	levels := []models.StockLevel{
+		// The same sheet, in kg, in both bins, and bars in metres in one of them
+		{LocationID: 3, ProductID: 1, VariantID: &sheet, Quantity: 120, TotalValue: 240},
+		{LocationID: 4, ProductID: 1, VariantID: &sheet, Quantity: 30, TotalValue: 60},
+		{LocationID: 4, ProductID: 2, Quantity: 12, TotalValue: 36.5},
+	}
+
+	tree := locationTree(locations, levels)
+	assert.Len(t, tree, 2)
+
+	main := tree[0]
+	assert.Equal(t, "Main", main.Name)
+	assert.Equal(t, 2, main.StockProducts)
+	assert.Equal(t, 336.5, main.StockValue)
+
+	rackNode := main.Children[0]
+	assert.Equal(t, 2, rackNode.StockProducts)
+	assert.Len(t, rackNode.Children, 2)
+	assert.Equal(t, 1, rackNode.Children[0].StockProducts)
+	assert.Equal(t, 240.0, rackNode.Children[0].StockValue)
+	assert.Equal(t, 2, rackNode.Children[1].StockProducts)
+
+	assert.Equal(t, 0, tree[1].StockProducts)
+	assert.Empty(t, tree[1].Children)
+}
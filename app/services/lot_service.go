@@ -0,0 +1,150 @@
+package services
+
+import (
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// LotUsage is the quantity of a lot consumed by a manufacturing order
+type LotUsage struct {
+	Lot      models.StockLot `json:"lot"`
+	Quantity float64         `json:"quantity"`
+}
+
+// OrderUsage is the quantity of a lot consumed by a manufacturing order, with its client
+type OrderUsage struct {
+	Order    models.OrderFabrication `json:"order_fabrication"`
+	Quantity float64                 `json:"quantity"`
+}
+
+// LotGenealogy traces a lot from its receipt to the orders and clients it went to
+type LotGenealogy struct {
+	Lot       models.StockLot           `json:"lot"`
+	Movements []models.StockMovementLot `json:"movements"`
+	Orders    []OrderUsage              `json:"order_fabrications"`
+	Clients   []models.Client           `json:"clients"`
+}
+
+type LotService struct {
+	stockService    *StockService
+	sequenceService *SequenceService
+}
+
+func NewLotService() *LotService {
+	return &LotService{
+		stockService:    NewStockService(),
+		sequenceService: NewSequenceService(),
+	}
+}
+
+// Receive creates a lot from its mill certificate details and posts the incoming movement
+// filling it, in one transaction. The lot number is generated when left empty.
+func (s *LotService) Receive(lot *models.StockLot, movement *models.StockMovement) (*models.StockLevel, error) {
+	var level *models.StockLevel
+	err := facades.Orm().Transaction(func(tx orm.Query) error {
+		if lot.LotNumber == "" {
+			number, err := s.sequenceService.NextTx(tx, SequenceStockLot)
+			if err != nil {
+				return err
+			}
+			lot.LotNumber = number
+		}
+		if lot.ReceivedAt == nil {
+			now := time.Now()
+			lot.ReceivedAt = &now
+		}
+		lot.ProductID = movement.ProductID
+		lot.VariantID = *movement.VariantID
+		lot.CreatedBy = movement.CreatedBy
+		if err := tx.Create(lot); err != nil {
+			return err
+		}
+
+		movement.Lots = []models.StockMovementLot{{LotID: lot.ID, Quantity: movement.Quantity}}
+		var err error
+		level, err = s.stockService.PostMovementTx(tx, movement)
+		return err
+	})
+
+	return level, err
+}
+
+// Genealogy returns the movements of a lot and the manufacturing orders and clients that
+// consumed it
+func (s *LotService) Genealogy(lot models.StockLot) (*LotGenealogy, error) {
+	genealogy := &LotGenealogy{Lot: lot, Orders: []OrderUsage{}, Clients: []models.Client{}}
+
+	if err := facades.Orm().Query().With("Movement.Location").With("Movement.Creator").Where("lot_id", lot.ID).
+		OrderBy("id").Find(&genealogy.Movements); err != nil {
+		return nil, err
+	}
+
+	// Material issued to an order, net of what went back to stock
+	consumed := map[uint]float64{}
+	var orderIDs []uint
+	for _, allocation := range genealogy.Movements {
+		movement := allocation.Movement
+		if movement.ReferenceType != ReferenceTypeOrderFabrication || movement.ReferenceID == nil {
+			continue
+		}
+		orderID := *movement.ReferenceID
+		if _, ok := consumed[orderID]; !ok {
+			orderIDs = append(orderIDs, orderID)
+		}
+		if movement.MovementType == MovementTypeOut {
+			consumed[orderID] += allocation.Quantity
+		} else {
+			consumed[orderID] -= allocation.Quantity
+		}
+	}
+	if len(orderIDs) == 0 {
+		return genealogy, nil
+	}
+
+	var orders []models.OrderFabrication
+	if err := facades.Orm().Query().With("Product").With("Variant").With("Client").With("ClientSite").
+		Where("id IN ?", orderIDs).OrderBy("id").Find(&orders); err != nil {
+		return nil, err
+	}
+	clients := map[uint]bool{}
+	for _, order := range orders {
+		genealogy.Orders = append(genealogy.Orders, OrderUsage{Order: order, Quantity: roundQuantity(consumed[order.ID])})
+		if !clients[order.ClientID] {
+			clients[order.ClientID] = true
+			genealogy.Clients = append(genealogy.Clients, order.Client)
+		}
+	}
+
+	return genealogy, nil
+}
+
+// OrderLots returns the lots consumed by a manufacturing order, net of returns
+func (s *LotService) OrderLots(order models.OrderFabrication) ([]LotUsage, error) {
+	var allocations []models.StockMovementLot
+	if err := facades.Orm().Query().With("Movement").With("Lot.Variant").
+		Where("movement_id IN (SELECT id FROM stock_movements WHERE reference_type = ? AND reference_id = ?)", ReferenceTypeOrderFabrication, order.ID).
+		OrderBy("lot_id").Find(&allocations); err != nil {
+		return nil, err
+	}
+
+	usages := []LotUsage{}
+	index := map[uint]int{}
+	for _, allocation := range allocations {
+		quantity := allocation.Quantity
+		if allocation.Movement.MovementType != MovementTypeOut {
+			quantity = -quantity
+		}
+		if i, ok := index[allocation.LotID]; ok {
+			usages[i].Quantity = roundQuantity(usages[i].Quantity + quantity)
+			continue
+		}
+		index[allocation.LotID] = len(usages)
+		usages = append(usages, LotUsage{Lot: allocation.Lot, Quantity: roundQuantity(quantity)})
+	}
+
+	return usages, nil
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+
+	"pms/app/models"
+)
+
+// Material requirement statuses
+const (
+	RequirementStatusPending   = "pending"
+	RequirementStatusRequested = "requested"
+	RequirementStatusAvailable = "available"
+	RequirementStatusConsumed  = "consumed"
+)
+
+var (
+	ErrOrderWithoutVariant = errors.New("manufacturing order has no variant")
+	ErrMissingRecipe       = errors.New("no recipe defined for the variant")
+	ErrInvalidRecipeOutput = errors.New("recipe output quantity must be greater than zero")
+)
+
+type MaterialRequirementService struct {
+	reservationService *ReservationService
+	unitService        *UnitService
+}
+
+func NewMaterialRequirementService() *MaterialRequirementService {
+	return &MaterialRequirementService{
+		reservationService: NewReservationService(),
+		unitService:        NewUnitService(),
+	}
+}
+
+// ExplodeTx computes the material needs of an order from its variant recipe and
+// synchronises the order's requirement rows, keyed by material variant. The order is pinned
+// to the recipe version in effect the first time it is exploded, so that later versions do
+// not change the needs of orders already released.
+func (s *MaterialRequirementService) ExplodeTx(tx orm.Query, order *models.OrderFabrication) ([]models.ProductionMaterialRequirement, error) {
+	if order.VariantID == nil {
+		return nil, ErrOrderWithoutVariant
+	}
+
+	var recipe models.RecipeVariant
+	query := tx.With("RecipeVariantItems.MaterialVariant.Product")
+	if order.RecipeVariantID != nil {
+		if err := query.Where("id", *order.RecipeVariantID).First(&recipe); err != nil {
+			return nil, err
+		}
+	} else if err := effectiveRecipe(query, *order.VariantID, time.Now(), &recipe); err != nil {
+		return nil, err
+	}
+	if recipe.ID == 0 || len(recipe.RecipeVariantItems) == 0 {
+		return nil, ErrMissingRecipe
+	}
+	if order.RecipeVariantID == nil {
+		if _, err := tx.Model(&models.OrderFabrication{}).Where("id", order.ID).Update("recipe_variant_id", recipe.ID); err != nil {
+			return nil, err
+		}
+		order.RecipeVariantID = &recipe.ID
+	}
+	if recipe.OutputQuantity <= 0 {
+		return nil, ErrInvalidRecipeOutput
+	}
+
+	// Aggregate the scaled item quantities per material variant, in the unit it is stocked in,
+	// with the scrap of each item and the parts lost to the recipe yield
+	factor := order.Quantity / recipe.OutputQuantity
+	needs := map[uint]float64{}
+	units := map[uint]string{}
+	materialOrder := []uint{}
+	for _, item := range recipe.RecipeVariantItems {
+		if _, ok := needs[item.MaterialVariantID]; !ok {
+			materialOrder = append(materialOrder, item.MaterialVariantID)
+		}
+		unit := item.MaterialVariant.Unit
+		if unit == "" {
+			unit = item.MaterialVariant.Product.Unit
+		}
+		quantity, err := s.unitService.Convert(tx, item.MaterialVariant.ProductID, &item.MaterialVariantID, item.Quantity, item.Unit, unit)
+		if err != nil {
+			return nil, err
+		}
+		needs[item.MaterialVariantID] += grossQuantity(recipe, item, quantity) * factor
+		units[item.MaterialVariantID] = unit
+	}
+
+	var existing []models.ProductionMaterialRequirement
+	if err := tx.Where("order_fabrication_id", order.ID).Find(&existing); err != nil {
+		return nil, err
+	}
+	existingByMaterial := map[uint]models.ProductionMaterialRequirement{}
+	for _, requirement := range existing {
+		existingByMaterial[requirement.MaterialVariantID] = requirement
+	}
+
+	// Drop requirements for materials no longer in the recipe
+	for _, requirement := range existing {
+		if _, ok := needs[requirement.MaterialVariantID]; ok || requirement.Status == RequirementStatusConsumed {
+			continue
+		}
+		if err := s.dropTx(tx, requirement); err != nil {
+			return nil, err
+		}
+	}
+
+	requirements := make([]models.ProductionMaterialRequirement, 0, len(materialOrder))
+	for _, materialVariantID := range materialOrder {
+		required := roundQuantity(needs[materialVariantID])
+
+		available, err := s.availableQuantity(tx, materialVariantID, order.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		requirement, ok := existingByMaterial[materialVariantID]
+		if !ok {
+			requirement = models.ProductionMaterialRequirement{
+				OrderFabricationID: order.ID,
+				MaterialVariantID:  materialVariantID,
+			}
+		}
+		// A material dropped earlier and back in the recipe is needed again
+		if requirement.Status == RequirementStatusConsumed && roundQuantity(required-requirement.ConsumedQuantity) <= 0 {
+			requirements = append(requirements, requirement)
+			continue
+		}
+		if requirement.Status == RequirementStatusConsumed {
+			requirement.Status = RequirementStatusPending
+		}
+
+		// Only what is still to be consumed has to come from stock
+		outstanding := roundQuantity(math.Max(required-requirement.ConsumedQuantity, 0))
+		requirement.RequiredQuantity = required
+		requirement.StockQuantity = roundQuantity(math.Min(outstanding, math.Max(available, 0)))
+		requirement.RequestQuantity = roundQuantity(outstanding - requirement.StockQuantity)
+		requirement.Unit = units[materialVariantID]
+		if requirement.Status != RequirementStatusRequested {
+			if requirement.RequestQuantity > 0 {
+				requirement.Status = RequirementStatusPending
+			} else {
+				requirement.Status = RequirementStatusAvailable
+			}
+		}
+
+		if err := tx.Save(&requirement); err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, requirement)
+	}
+
+	return requirements, nil
+}
+
+// dropTx removes the requirement of a material no longer in the recipe. Its active
+// reservations are released and its pending stock requests withdrawn first. A requirement
+// other reservations or requests still refer to is kept as consumed, for what was consumed.
+func (s *MaterialRequirementService) dropTx(tx orm.Query, requirement models.ProductionMaterialRequirement) error {
+	if err := s.reservationService.closeActive(tx, "requirement_id", requirement.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Where("requirement_id", requirement.ID).Where("status", StockRequestStatusPending).Delete(&models.StockRequest{}); err != nil {
+		return err
+	}
+
+	reserved, err := tx.Model(&models.StockReservation{}).Where("requirement_id", requirement.ID).Exists()
+	if err != nil {
+		return err
+	}
+	requested, err := tx.Model(&models.StockRequest{}).Where("requirement_id", requirement.ID).Exists()
+	if err != nil {
+		return err
+	}
+	if !reserved && !requested {
+		_, err := tx.Delete(&requirement)
+		return err
+	}
+
+	_, err = tx.Model(&models.ProductionMaterialRequirement{}).Where("id", requirement.ID).Update(map[string]any{
+		"required_quantity": requirement.ConsumedQuantity,
+		"stock_quantity":    0,
+		"request_quantity":  0,
+		"status":            RequirementStatusConsumed,
+	})
+	return err
+}
+
+// availableQuantity returns the quantity of a variant across all locations that is on hand
+// and not reserved by other orders
+func (s *MaterialRequirementService) availableQuantity(tx orm.Query, variantID uint, orderID uint) (float64, error) {
+	var onHand struct {
+		Total float64
+	}
+	if err := tx.Model(&models.StockLevel{}).Select("COALESCE(SUM(quantity), 0) AS total").Where("variant_id", variantID).Scan(&onHand); err != nil {
+		return 0, err
+	}
+
+	var reserved struct {
+		Total float64
+	}
+	if err := tx.Model(&models.StockReservation{}).Select("COALESCE(SUM(quantity - consumed_quantity), 0) AS total").
+		Where("variant_id", variantID).Where("status", ReservationStatusActive).Where("order_fabrication_id <> ?", orderID).Scan(&reserved); err != nil {
+		return 0, err
+	}
+
+	return onHand.Total - reserved.Total, nil
+}
+
+// roundQuantity rounds a quantity to the three decimals stored by the database
+func roundQuantity(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+// roundCost rounds an amount to the four decimals stored by the database
+func roundCost(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
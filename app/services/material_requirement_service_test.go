@@ -0,0 +1,82 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/goravel/framework/database/orm"
+	mocksorm "github.com/goravel/framework/mocks/database/orm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"pms/app/models"
+)
+
+// expectDropCleanup expects the reservations of a requirement to be released and its
+// pending stock requests withdrawn
+func expectDropCleanup(t *testing.T, tx *mocksorm.Query, requirementID uint, reservations []models.StockReservation) {
+	locked := mocksorm.NewQuery(t)
+	tx.EXPECT().LockForUpdate().Return(locked).Once()
+	locked.EXPECT().Where("requirement_id", requirementID).Return(locked).Once()
+	locked.EXPECT().Where("status", ReservationStatusActive).Return(locked).Once()
+	locked.EXPECT().Find(mock.Anything).Run(func(dest any, conds ...any) {
+		*dest.(*[]models.StockReservation) = reservations
+	}).Return(nil).Once()
+
+	requests := mocksorm.NewQuery(t)
+	tx.EXPECT().Where("requirement_id", requirementID).Return(requests).Once()
+	requests.EXPECT().Where("status", StockRequestStatusPending).Return(requests).Once()
+	requests.EXPECT().Delete(mock.AnythingOfType("*models.StockRequest")).Return(nil, nil).Once()
+}
+
+func TestDropRequirementWithoutReferences(t *testing.T) {
+	tx := mocksorm.NewQuery(t)
+	service := NewMaterialRequirementService()
+	requirement := models.ProductionMaterialRequirement{Model: orm.Model{ID: 8}}
+
+	expectDropCleanup(t, tx, 8, nil)
+	for _, model := range []any{&models.StockReservation{}, &models.StockRequest{}} {
+		exists := mocksorm.NewQuery(t)
+		tx.EXPECT().Model(model).Return(exists).Once()
+		exists.EXPECT().Where("requirement_id", uint(8)).Return(exists).Once()
+		exists.EXPECT().Exists().Return(false, nil).Once()
+	}
+	tx.EXPECT().Delete(mock.MatchedBy(func(dropped *models.ProductionMaterialRequirement) bool {
+		return dropped.ID == 8
+	})).Return(nil, nil).Once()
+
+	assert.NoError(t, service.dropTx(tx, requirement))
+}
+
+func TestDropRequirementKeepsConsumedHistory(t *testing.T) {
+	tx := mocksorm.NewQuery(t)
+	service := NewMaterialRequirementService()
+	requirement := models.ProductionMaterialRequirement{Model: orm.Model{ID: 9}, RequiredQuantity: 10, ConsumedQuantity: 4}
+
+	// The partly consumed reservation is shrunk to what was consumed
+	expectDropCleanup(t, tx, 9, []models.StockReservation{{Model: orm.Model{ID: 3}, Quantity: 10, ConsumedQuantity: 4}})
+	closed := mocksorm.NewQuery(t)
+	tx.EXPECT().Model(&models.StockReservation{}).Return(closed).Once()
+	closed.EXPECT().Where("id", uint(3)).Return(closed).Once()
+	closed.EXPECT().Update(map[string]any{"status": ReservationStatusConsumed, "quantity": 4.0}).Return(nil, nil).Once()
+
+	reserved := mocksorm.NewQuery(t)
+	tx.EXPECT().Model(&models.StockReservation{}).Return(reserved).Once()
+	reserved.EXPECT().Where("requirement_id", uint(9)).Return(reserved).Once()
+	reserved.EXPECT().Exists().Return(true, nil).Once()
+	requested := mocksorm.NewQuery(t)
+	tx.EXPECT().Model(&models.StockRequest{}).Return(requested).Once()
+	requested.EXPECT().Where("requirement_id", uint(9)).Return(requested).Once()
+	requested.EXPECT().Exists().Return(false, nil).Once()
+
+	kept := mocksorm.NewQuery(t)
+	tx.EXPECT().Model(&models.ProductionMaterialRequirement{}).Return(kept).Once()
+	kept.EXPECT().Where("id", uint(9)).Return(kept).Once()
+	kept.EXPECT().Update(map[string]any{
+		"required_quantity": 4.0,
+		"stock_quantity":    0,
+		"request_quantity":  0,
+		"status":            RequirementStatusConsumed,
+	}).Return(nil, nil).Once()
+
+	assert.NoError(t, service.dropTx(tx, requirement))
+}
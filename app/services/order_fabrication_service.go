@@ -0,0 +1,216 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// orderFabricationOperatorRoles are the shop floor roles allowed to drive production
+var orderFabricationOperatorRoles = []string{
+	"operateur_decoupe",
+	"operateur_pliage",
+	"operateur_assemblage",
+	"operateur_finition",
+}
+
+// orderFabricationTransitions declares, for each status, the next statuses and the roles
+// allowed to perform the move. Admin may perform every declared transition.
+var orderFabricationTransitions = map[string]map[string][]string{
+	models.OrderFabricationStatusPending: {
+		models.OrderFabricationStatusReleased:  {"ingenieur_methodes"},
+		models.OrderFabricationStatusOnHold:    {"commercial", "ingenieur_methodes"},
+		models.OrderFabricationStatusCancelled: {"commercial"},
+	},
+	models.OrderFabricationStatusReleased: {
+		models.OrderFabricationStatusInProgress: append([]string{"ingenieur_methodes"}, orderFabricationOperatorRoles...),
+		models.OrderFabricationStatusOnHold:     {"commercial", "ingenieur_methodes"},
+		models.OrderFabricationStatusCancelled:  {"commercial"},
+	},
+	models.OrderFabricationStatusInProgress: {
+		models.OrderFabricationStatusOnHold:    append([]string{"ingenieur_methodes"}, orderFabricationOperatorRoles...),
+		models.OrderFabricationStatusCompleted: append([]string{"ingenieur_methodes"}, orderFabricationOperatorRoles...),
+	},
+	models.OrderFabricationStatusOnHold: {
+		models.OrderFabricationStatusPending:    {"commercial", "ingenieur_methodes"},
+		models.OrderFabricationStatusReleased:   {"ingenieur_methodes"},
+		models.OrderFabricationStatusInProgress: append([]string{"ingenieur_methodes"}, orderFabricationOperatorRoles...),
+		models.OrderFabricationStatusCancelled:  {"commercial"},
+	},
+}
+
+// InvalidTransitionError is returned when a status change is not declared for the user's role
+type InvalidTransitionError struct {
+	From    string
+	To      string
+	Allowed []string
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("transition from %s to %s is not allowed", e.From, e.To)
+}
+
+type OrderFabricationService struct {
+	materialRequirementService *MaterialRequirementService
+	stockRequestService        *StockRequestService
+	reservationService         *ReservationService
+	productionService          *ProductionService
+	routingService             *RoutingService
+}
+
+func NewOrderFabricationService() *OrderFabricationService {
+	return &OrderFabricationService{
+		materialRequirementService: NewMaterialRequirementService(),
+		stockRequestService:        NewStockRequestService(),
+		reservationService:         NewReservationService(),
+		productionService:          NewProductionService(),
+		routingService:             NewRoutingService(),
+	}
+}
+
+// AllowedTransitions returns the statuses a user with the given role may move an order to
+func (s *OrderFabricationService) AllowedTransitions(from, roleKey string) []string {
+	allowed := []string{}
+	for to, roles := range orderFabricationTransitions[from] {
+		if roleKey == "admin" || slices.Contains(roles, roleKey) {
+			allowed = append(allowed, to)
+		}
+	}
+	sort.Strings(allowed)
+
+	return allowed
+}
+
+// AllowedTransitionsTx returns the statuses a user with the given role may move an order
+// to. An order put on hold before it was released has no materials nor routing yet: it
+// must go through released again instead of resuming production.
+func (s *OrderFabricationService) AllowedTransitionsTx(tx orm.Query, order *models.OrderFabrication, roleKey string) ([]string, error) {
+	allowed := s.AllowedTransitions(order.Status, roleKey)
+	if order.Status != models.OrderFabricationStatusOnHold || !slices.Contains(allowed, models.OrderFabricationStatusInProgress) {
+		return allowed, nil
+	}
+
+	var hold models.ProductionOfHistory
+	if err := tx.Where("order_fabrication_id", order.ID).Where("status", models.OrderFabricationStatusOnHold).
+		OrderBy("status_at", "desc").OrderBy("id", "desc").First(&hold); err != nil {
+		return nil, err
+	}
+	if hold.PreviousStatus == models.OrderFabricationStatusReleased || hold.PreviousStatus == models.OrderFabricationStatusInProgress {
+		return allowed, nil
+	}
+
+	return slices.DeleteFunc(allowed, func(status string) bool {
+		return status == models.OrderFabricationStatusInProgress
+	}), nil
+}
+
+// Transition moves an order to a new status and records the change in its history.
+// The user must have its Role relationship loaded.
+func (s *OrderFabricationService) Transition(order *models.OrderFabrication, to string, user models.User, reason string) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		return s.TransitionTx(tx, order, to, user, reason)
+	})
+}
+
+// TransitionTx is Transition running inside an existing transaction
+func (s *OrderFabricationService) TransitionTx(tx orm.Query, order *models.OrderFabrication, to string, user models.User, reason string) error {
+	// Reload the order under lock so concurrent transitions are serialised
+	if err := tx.LockForUpdate().Where("id", order.ID).FirstOrFail(order); err != nil {
+		return err
+	}
+
+	allowed, err := s.AllowedTransitionsTx(tx, order, user.Role.Key)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(allowed, to) {
+		return &InvalidTransitionError{From: order.Status, To: to, Allowed: allowed}
+	}
+
+	previousStatus := order.Status
+	if _, err := tx.Model(&models.OrderFabrication{}).Where("id", order.ID).Update("status", to); err != nil {
+		return err
+	}
+	order.Status = to
+
+	// Releasing an order explodes its recipe into material requirements and copies its
+	// routing, sending it back to pending or cancelling it frees the stock it holds and
+	// withdraws its open requests, and completing it receives the finished goods and
+	// by-products
+	switch to {
+	case models.OrderFabricationStatusReleased:
+		if err := s.RefreshMaterialsTx(tx, order, user); err != nil {
+			return err
+		}
+		if _, err := s.routingService.CopyToOrderTx(tx, order); err != nil {
+			return err
+		}
+	case models.OrderFabricationStatusPending, models.OrderFabricationStatusCancelled:
+		if err := s.reservationService.ReleaseForOrderTx(tx, order); err != nil {
+			return err
+		}
+		if err := s.stockRequestService.WithdrawForOrderTx(tx, order); err != nil {
+			return err
+		}
+	case models.OrderFabricationStatusCompleted:
+		if err := s.reservationService.ReleaseForOrderTx(tx, order); err != nil {
+			return err
+		}
+		if _, _, err := s.productionService.ReceiveTx(tx, order, user); err != nil {
+			return err
+		}
+		if _, err := s.productionService.ReceiveByProductsTx(tx, order, user); err != nil {
+			return err
+		}
+	}
+
+	history := models.ProductionOfHistory{
+		OrderFabricationID: order.ID,
+		UserID:             user.ID,
+		PreviousStatus:     previousStatus,
+		Status:             to,
+		Notes:              reason,
+		StatusAt:           time.Now(),
+	}
+
+	return tx.Create(&history)
+}
+
+// HasMaterials reports whether an order's material requirements have been computed
+func (s *OrderFabricationService) HasMaterials(order *models.OrderFabrication) bool {
+	switch order.Status {
+	case models.OrderFabricationStatusReleased, models.OrderFabricationStatusInProgress, models.OrderFabricationStatusOnHold:
+		return true
+	}
+
+	return false
+}
+
+// RefreshMaterials recomputes the material requirements of an order, reserves the stock
+// covering them and raises stock requests for the shortages
+func (s *OrderFabricationService) RefreshMaterials(order *models.OrderFabrication, user models.User) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		return s.RefreshMaterialsTx(tx, order, user)
+	})
+}
+
+// RefreshMaterialsTx is RefreshMaterials running inside an existing transaction
+func (s *OrderFabricationService) RefreshMaterialsTx(tx orm.Query, order *models.OrderFabrication, user models.User) error {
+	if _, err := s.materialRequirementService.ExplodeTx(tx, order); err != nil {
+		return err
+	}
+
+	if _, err := s.reservationService.ReserveForOrderTx(tx, order, user); err != nil {
+		return err
+	}
+
+	_, err := s.stockRequestService.GenerateForOrderTx(tx, order, user)
+
+	return err
+}
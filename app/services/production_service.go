@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+	"math"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+var (
+	ErrNoFinishedGoodsLocation  = errors.New("no storage location to receive the finished goods of a serialised product")
+	ErrFractionalSerialQuantity = errors.New("serialised products must be produced in whole units")
+)
+
+type ProductionService struct {
+	stockService  *StockService
+	serialService *SerialService
+}
+
+func NewProductionService() *ProductionService {
+	return &ProductionService{
+		stockService:  NewStockService(),
+		serialService: NewSerialService(),
+	}
+}
+
+// ReceiveTx receives the finished goods of a completed order into stock and numbers them
+// when the product is serialised. Without a receipt location, non-serialised goods are not
+// received.
+func (s *ProductionService) ReceiveTx(tx orm.Query, order *models.OrderFabrication, user models.User) (*models.StockMovement, []models.SerialNumber, error) {
+	var product models.Product
+	if err := tx.Where("id", order.ProductID).FirstOrFail(&product); err != nil {
+		return nil, nil, err
+	}
+
+	locationID := s.receiptLocation(product)
+	if locationID == 0 {
+		if product.IsSerialized {
+			return nil, nil, ErrNoFinishedGoodsLocation
+		}
+		return nil, nil, nil
+	}
+	if product.IsSerialized && order.Quantity != math.Trunc(order.Quantity) {
+		return nil, nil, ErrFractionalSerialQuantity
+	}
+
+	orderID := order.ID
+	movement := models.StockMovement{
+		ProductID:     order.ProductID,
+		VariantID:     order.VariantID,
+		LocationID:    locationID,
+		MovementType:  MovementTypeIn,
+		Quantity:      order.Quantity,
+		Unit:          product.Unit,
+		ReferenceType: ReferenceTypeOrderFabrication,
+		ReferenceID:   &orderID,
+		Notes:         "Produced by " + order.OrderNumber,
+		CreatedBy:     user.ID,
+	}
+	if _, err := s.stockService.PostMovementTx(tx, &movement); err != nil {
+		return nil, nil, err
+	}
+	if !product.IsSerialized {
+		return &movement, nil, nil
+	}
+
+	serials, err := s.serialService.NumberTx(tx, order, product, &movement)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &movement, serials, nil
+}
+
+// ReceiveByProductsTx receives the by-products of the recipe version a completed order was
+// made with, in proportion to the order quantity. By-products without a receipt location
+// are not received.
+func (s *ProductionService) ReceiveByProductsTx(tx orm.Query, order *models.OrderFabrication, user models.User) ([]models.StockMovement, error) {
+	movements := []models.StockMovement{}
+	if order.RecipeVariantID == nil {
+		return movements, nil
+	}
+
+	var recipe models.RecipeVariant
+	if err := tx.With("ByProducts.Variant.Product").Where("id", *order.RecipeVariantID).FirstOrFail(&recipe); err != nil {
+		return nil, err
+	}
+	if recipe.OutputQuantity <= 0 {
+		return nil, ErrInvalidRecipeOutput
+	}
+
+	orderID := order.ID
+	for _, byProduct := range recipe.ByProducts {
+		product := byProduct.Variant.Product
+		locationID := s.receiptLocation(product)
+		if locationID == 0 {
+			continue
+		}
+
+		variantID := byProduct.VariantID
+		movement := models.StockMovement{
+			ProductID:     product.ID,
+			VariantID:     &variantID,
+			LocationID:    locationID,
+			MovementType:  MovementTypeIn,
+			Quantity:      roundQuantity(byProduct.Quantity * order.Quantity / recipe.OutputQuantity),
+			Unit:          byProduct.Unit,
+			ReferenceType: ReferenceTypeOrderFabrication,
+			ReferenceID:   &orderID,
+			Notes:         "By-product of " + order.OrderNumber,
+			CreatedBy:     user.ID,
+		}
+		if movement.Quantity <= 0 {
+			continue
+		}
+		if _, err := s.stockService.PostMovementTx(tx, &movement); err != nil {
+			return nil, err
+		}
+		movements = append(movements, movement)
+	}
+
+	return movements, nil
+}
+
+// receiptLocation returns where the output of an order is received: the product's location,
+// or the configured finished goods location. Zero means neither is set.
+func (s *ProductionService) receiptLocation(product models.Product) uint {
+	if product.LocationID != nil {
+		return *product.LocationID
+	}
+
+	return uint(facades.Config().GetInt("app.stock.finished_goods_location", 0))
+}
@@ -0,0 +1,477 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+var (
+	ErrRecipeExists          = errors.New("the variant already has a recipe")
+	ErrEmptyRecipe           = errors.New("a recipe needs at least one item")
+	ErrRecipeSelfReference   = errors.New("a recipe cannot use its own variant as material")
+	ErrRecipeNotDraft        = errors.New("only draft recipe versions can be changed")
+	ErrRecipeDraftExists     = errors.New("the variant already has a draft recipe version")
+	ErrRecipeHasChangeOrders = errors.New("the recipe version is referenced by change orders")
+	ErrInvalidRecipeYield    = errors.New("recipe yield must be greater than zero and at most 100 percent")
+)
+
+// InvalidRecipeItemError is returned when a recipe item cannot be used
+type InvalidRecipeItemError struct {
+	Index             int
+	MaterialVariantID uint
+	Reason            string
+}
+
+func (e *InvalidRecipeItemError) Error() string {
+	return fmt.Sprintf("recipe item %d (material variant %d): %s", e.Index+1, e.MaterialVariantID, e.Reason)
+}
+
+// InvalidRecipeByProductError is returned when a recipe by-product cannot be used
+type InvalidRecipeByProductError struct {
+	Index     int
+	VariantID uint
+	Reason    string
+}
+
+func (e *InvalidRecipeByProductError) Error() string {
+	return fmt.Sprintf("recipe by-product %d (variant %d): %s", e.Index+1, e.VariantID, e.Reason)
+}
+
+type RecipeService struct {
+	unitService *UnitService
+	bomService  *BomService
+}
+
+func NewRecipeService() *RecipeService {
+	return &RecipeService{
+		unitService: NewUnitService(),
+		bomService:  NewBomService(),
+	}
+}
+
+// EffectiveTx returns the recipe version of a variant in effect at a time, with its items,
+// or nil when the variant has none
+func (s *RecipeService) EffectiveTx(tx orm.Query, variantID uint, at time.Time) (*models.RecipeVariant, error) {
+	var recipe models.RecipeVariant
+	if err := effectiveRecipe(tx.With("RecipeVariantItems.MaterialVariant.Product"), variantID, at, &recipe); err != nil {
+		return nil, err
+	}
+	if recipe.ID == 0 {
+		return nil, nil
+	}
+
+	return &recipe, nil
+}
+
+// ObsoleteSupersededTx marks obsolete the active versions of a variant replaced by the one
+// in effect at a time. Versions with a later effective date stay active.
+func (s *RecipeService) ObsoleteSupersededTx(tx orm.Query, variantID uint, at time.Time) error {
+	var effective models.RecipeVariant
+	if err := effectiveRecipe(tx, variantID, at, &effective); err != nil || effective.ID == 0 {
+		return err
+	}
+
+	query := tx.Model(&models.RecipeVariant{}).Where("variant_id", variantID).
+		Where("status", models.RecipeStatusActive).Where("id <> ?", effective.ID)
+	if effective.EffectiveFrom != nil {
+		query = query.Where("effective_from IS NULL OR effective_from <= ?", *effective.EffectiveFrom)
+	} else {
+		query = query.WhereNull("effective_from")
+	}
+	_, err := query.Update("status", models.RecipeStatusObsolete)
+	return err
+}
+
+// Save creates the first recipe of a variant, active at once, or updates a draft version
+// and replaces its items and by-products. Later versions are drafts made with NewVersion and
+// put into effect by an engineering change order, and cannot be changed while one is pending.
+func (s *RecipeService) Save(recipe *models.RecipeVariant, items []models.RecipeVariantItem, byProducts []models.RecipeVariantByProduct) error {
+	if recipe.OutputQuantity <= 0 {
+		return ErrInvalidRecipeOutput
+	}
+	if recipe.YieldPercent == 0 {
+		recipe.YieldPercent = 100
+	}
+	if recipe.YieldPercent < 0 || recipe.YieldPercent > 100 {
+		return ErrInvalidRecipeYield
+	}
+
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := s.validateItems(tx, recipe.VariantID, items); err != nil {
+			return err
+		}
+		if err := s.validateByProducts(tx, recipe.VariantID, byProducts); err != nil {
+			return err
+		}
+
+		materialIDs := make([]uint, 0, len(items))
+		for _, item := range items {
+			materialIDs = append(materialIDs, item.MaterialVariantID)
+		}
+		if err := s.bomService.CheckCycle(tx, recipe.VariantID, materialIDs); err != nil {
+			return err
+		}
+
+		if recipe.ID == 0 {
+			exists, err := tx.Model(&models.RecipeVariant{}).Where("variant_id", recipe.VariantID).Exists()
+			if err != nil {
+				return err
+			}
+			if exists {
+				return ErrRecipeExists
+			}
+			recipe.Version = 1
+			recipe.Status = models.RecipeStatusActive
+			if err := tx.Create(recipe); err != nil {
+				return err
+			}
+		} else {
+			var current models.RecipeVariant
+			if err := tx.LockForUpdate().Where("id", recipe.ID).FirstOrFail(&current); err != nil {
+				return err
+			}
+			if current.Status != models.RecipeStatusDraft {
+				return ErrRecipeNotDraft
+			}
+			// The draft under review must stay as it was submitted until the change order is decided
+			pending, err := tx.Model(&models.EngineeringChangeOrder{}).Where("recipe_variant_id", recipe.ID).
+				Where("status", models.EngineeringChangeOrderStatusPending).Exists()
+			if err != nil {
+				return err
+			}
+			if pending {
+				return ErrChangeOrderPending
+			}
+			if _, err := tx.Model(&models.RecipeVariant{}).Where("id", recipe.ID).Update(map[string]any{
+				"output_quantity": recipe.OutputQuantity,
+				"yield_percent":   recipe.YieldPercent,
+				"notes":           recipe.Notes,
+			}); err != nil {
+				return err
+			}
+			if _, err := tx.Where("recipe_variant_id", recipe.ID).Delete(&models.RecipeVariantItem{}); err != nil {
+				return err
+			}
+			if _, err := tx.Where("recipe_variant_id", recipe.ID).Delete(&models.RecipeVariantByProduct{}); err != nil {
+				return err
+			}
+		}
+
+		if err := s.createItems(tx, recipe, items); err != nil {
+			return err
+		}
+		return s.createByProducts(tx, recipe, byProducts)
+	})
+}
+
+// NewVersion starts a draft version of a variant recipe from one of its versions
+func (s *RecipeService) NewVersion(source models.RecipeVariant) (*models.RecipeVariant, error) {
+	var recipe *models.RecipeVariant
+	err := facades.Orm().Transaction(func(tx orm.Query) error {
+		var err error
+		recipe, err = s.createDraftTx(tx, source.ProductID, source.VariantID, source)
+		return err
+	})
+
+	return recipe, err
+}
+
+// Copy duplicates a recipe onto another variant. A variant without recipe gets it as its
+// first, active version; otherwise it becomes a draft version, when overwrite is set, to be
+// put into effect by an engineering change order.
+func (s *RecipeService) Copy(source models.RecipeVariant, target models.ProductVariant, overwrite bool) (*models.RecipeVariant, error) {
+	exists, err := facades.Orm().Query().Model(&models.RecipeVariant{}).Where("variant_id", target.ID).Exists()
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		recipe := &models.RecipeVariant{
+			ProductID:      target.ProductID,
+			VariantID:      target.ID,
+			OutputQuantity: source.OutputQuantity,
+			YieldPercent:   source.YieldPercent,
+			Notes:          source.Notes,
+		}
+		if err := s.Save(recipe, source.RecipeVariantItems, source.ByProducts); err != nil {
+			return nil, err
+		}
+		return recipe, nil
+	}
+	if !overwrite {
+		return nil, ErrRecipeExists
+	}
+
+	var recipe *models.RecipeVariant
+	err = facades.Orm().Transaction(func(tx orm.Query) error {
+		var err error
+		recipe, err = s.createDraftTx(tx, target.ProductID, target.ID, source)
+		return err
+	})
+
+	return recipe, err
+}
+
+// Delete removes a draft recipe version and its items. Versions that were in effect are kept
+// as the history of the orders made with them.
+func (s *RecipeService) Delete(recipe models.RecipeVariant) error {
+	if recipe.Status != models.RecipeStatusDraft {
+		return ErrRecipeNotDraft
+	}
+
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		referenced, err := tx.Model(&models.EngineeringChangeOrder{}).Where("recipe_variant_id", recipe.ID).Exists()
+		if err != nil {
+			return err
+		}
+		if referenced {
+			return ErrRecipeHasChangeOrders
+		}
+
+		if _, err := tx.Where("recipe_variant_id", recipe.ID).Delete(&models.RecipeVariantItem{}); err != nil {
+			return err
+		}
+		if _, err := tx.Where("recipe_variant_id", recipe.ID).Delete(&models.RecipeVariantByProduct{}); err != nil {
+			return err
+		}
+		_, err = tx.Delete(&recipe)
+		return err
+	})
+}
+
+// createDraftTx adds the next version of a variant recipe as a draft copied from a source
+// recipe, loaded with its items and by-products. A variant has at most one draft at a time.
+func (s *RecipeService) createDraftTx(tx orm.Query, productID uint, variantID uint, source models.RecipeVariant) (*models.RecipeVariant, error) {
+	var versions []models.RecipeVariant
+	if err := tx.LockForUpdate().Where("variant_id", variantID).Find(&versions); err != nil {
+		return nil, err
+	}
+	latest := 0
+	for _, version := range versions {
+		if version.Status == models.RecipeStatusDraft {
+			return nil, ErrRecipeDraftExists
+		}
+		latest = max(latest, version.Version)
+	}
+
+	if err := s.validateItems(tx, variantID, source.RecipeVariantItems); err != nil {
+		return nil, err
+	}
+	if err := s.validateByProducts(tx, variantID, source.ByProducts); err != nil {
+		return nil, err
+	}
+
+	recipe := &models.RecipeVariant{
+		ProductID:      productID,
+		VariantID:      variantID,
+		Version:        latest + 1,
+		Status:         models.RecipeStatusDraft,
+		OutputQuantity: source.OutputQuantity,
+		YieldPercent:   source.YieldPercent,
+		Notes:          source.Notes,
+	}
+	if err := tx.Create(recipe); err != nil {
+		return nil, err
+	}
+	if err := s.createItems(tx, recipe, source.RecipeVariantItems); err != nil {
+		return nil, err
+	}
+	if err := s.createByProducts(tx, recipe, source.ByProducts); err != nil {
+		return nil, err
+	}
+
+	return recipe, nil
+}
+
+// createItems stores the items of a recipe version
+func (s *RecipeService) createItems(tx orm.Query, recipe *models.RecipeVariant, items []models.RecipeVariantItem) error {
+	recipe.RecipeVariantItems = make([]models.RecipeVariantItem, 0, len(items))
+	for _, item := range items {
+		row := models.RecipeVariantItem{
+			RecipeVariantID:   recipe.ID,
+			MaterialVariantID: item.MaterialVariantID,
+			Quantity:          item.Quantity,
+			Unit:              item.Unit,
+			ScrapPercent:      item.ScrapPercent,
+			Notes:             item.Notes,
+		}
+		if err := tx.Create(&row); err != nil {
+			return err
+		}
+		recipe.RecipeVariantItems = append(recipe.RecipeVariantItems, row)
+	}
+
+	return nil
+}
+
+// createByProducts stores the by-products of a recipe version
+func (s *RecipeService) createByProducts(tx orm.Query, recipe *models.RecipeVariant, byProducts []models.RecipeVariantByProduct) error {
+	recipe.ByProducts = make([]models.RecipeVariantByProduct, 0, len(byProducts))
+	for _, byProduct := range byProducts {
+		row := models.RecipeVariantByProduct{
+			RecipeVariantID: recipe.ID,
+			VariantID:       byProduct.VariantID,
+			Quantity:        byProduct.Quantity,
+			Unit:            byProduct.Unit,
+			Notes:           byProduct.Notes,
+		}
+		if err := tx.Create(&row); err != nil {
+			return err
+		}
+		recipe.ByProducts = append(recipe.ByProducts, row)
+	}
+
+	return nil
+}
+
+// validateItems checks that every item uses a positive quantity of a raw material or of a
+// sub-assembly, a variant with its own recipe, other than the recipe's variant, in a unit
+// convertible to the unit the material is stocked in
+func (s *RecipeService) validateItems(tx orm.Query, variantID uint, items []models.RecipeVariantItem) error {
+	if len(items) == 0 {
+		return ErrEmptyRecipe
+	}
+
+	for i, item := range items {
+		if item.MaterialVariantID == variantID {
+			return ErrRecipeSelfReference
+		}
+		if item.Quantity <= 0 {
+			return &InvalidRecipeItemError{Index: i, MaterialVariantID: item.MaterialVariantID, Reason: "quantity must be greater than zero"}
+		}
+		if item.ScrapPercent < 0 || item.ScrapPercent >= 100 {
+			return &InvalidRecipeItemError{Index: i, MaterialVariantID: item.MaterialVariantID, Reason: "scrap percentage must be between 0 and 100"}
+		}
+
+		var material models.ProductVariant
+		if err := tx.With("Product").Where("id", item.MaterialVariantID).First(&material); err != nil {
+			return err
+		}
+		if material.ID == 0 {
+			return &InvalidRecipeItemError{Index: i, MaterialVariantID: item.MaterialVariantID, Reason: "material variant does not exist"}
+		}
+
+		if !material.Product.IsRawMaterial {
+			subAssembly, err := tx.Model(&models.RecipeVariant{}).Where("variant_id", material.ID).
+				Where("status", models.RecipeStatusActive).Exists()
+			if err != nil {
+				return err
+			}
+			if !subAssembly {
+				return &InvalidRecipeItemError{Index: i, MaterialVariantID: item.MaterialVariantID, Reason: "material must be a raw material or a sub-assembly with its own recipe"}
+			}
+		}
+
+		unit := material.Unit
+		if unit == "" {
+			unit = material.Product.Unit
+		}
+		if _, err := s.unitService.Convert(tx, material.ProductID, &material.ID, 1, item.Unit, unit); err != nil {
+			var unitErr *UnitConversionError
+			if errors.As(err, &unitErr) {
+				return &InvalidRecipeItemError{Index: i, MaterialVariantID: item.MaterialVariantID, Reason: unitErr.Error()}
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateByProducts checks that every by-product is a positive quantity of an existing
+// variant, other than the recipe's variant, in a unit convertible to its stock unit
+func (s *RecipeService) validateByProducts(tx orm.Query, variantID uint, byProducts []models.RecipeVariantByProduct) error {
+	for i, byProduct := range byProducts {
+		if byProduct.VariantID == variantID {
+			return &InvalidRecipeByProductError{Index: i, VariantID: byProduct.VariantID, Reason: "the recipe's own variant is its main output"}
+		}
+		if byProduct.Quantity <= 0 {
+			return &InvalidRecipeByProductError{Index: i, VariantID: byProduct.VariantID, Reason: "quantity must be greater than zero"}
+		}
+
+		var variant models.ProductVariant
+		if err := tx.With("Product").Where("id", byProduct.VariantID).First(&variant); err != nil {
+			return err
+		}
+		if variant.ID == 0 {
+			return &InvalidRecipeByProductError{Index: i, VariantID: byProduct.VariantID, Reason: "variant does not exist"}
+		}
+
+		unit := variant.Unit
+		if unit == "" {
+			unit = variant.Product.Unit
+		}
+		if _, err := s.unitService.Convert(tx, variant.ProductID, &variant.ID, 1, byProduct.Unit, unit); err != nil {
+			var unitErr *UnitConversionError
+			if errors.As(err, &unitErr) {
+				return &InvalidRecipeByProductError{Index: i, VariantID: byProduct.VariantID, Reason: unitErr.Error()}
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// grossQuantity returns the material to issue for the net quantity of a recipe item, adding
+// the item's scrap and the parts lost to the recipe's yield
+func grossQuantity(recipe models.RecipeVariant, item models.RecipeVariantItem, quantity float64) float64 {
+	quantity *= 1 + item.ScrapPercent/100
+	if recipe.YieldPercent > 0 {
+		quantity /= recipe.YieldPercent / 100
+	}
+
+	return quantity
+}
+
+// effectiveRecipe loads the recipe version of a variant in effect at a time: the active
+// version with the latest effective date not after it. The recipe keeps a zero ID when the
+// variant has none. The versions are compared in Go, databases disagreeing on where they sort
+// a missing effective date.
+func effectiveRecipe(query orm.Query, variantID uint, at time.Time, recipe *models.RecipeVariant) error {
+	var versions []models.RecipeVariant
+	if err := query.Where("variant_id", variantID).Where("status", models.RecipeStatusActive).
+		Where("effective_from IS NULL OR effective_from <= ?", at).Find(&versions); err != nil {
+		return err
+	}
+
+	if i := latestRecipeVersion(versions); i >= 0 {
+		*recipe = versions[i]
+	}
+
+	return nil
+}
+
+// latestRecipeVersion returns the index of the version taking effect last, or -1 when there
+// is none. A version without effective date took effect first; versions effective the same
+// day are ranked by version number.
+func latestRecipeVersion(versions []models.RecipeVariant) int {
+	latest := -1
+	for i, version := range versions {
+		if latest < 0 {
+			latest = i
+			continue
+		}
+
+		current := versions[latest]
+		switch {
+		case version.EffectiveFrom == nil && current.EffectiveFrom != nil:
+			// an undated version never supersedes a dated one
+		case version.EffectiveFrom != nil && current.EffectiveFrom == nil:
+			latest = i
+		case version.EffectiveFrom != nil && !version.EffectiveFrom.Equal(*current.EffectiveFrom):
+			if version.EffectiveFrom.After(*current.EffectiveFrom) {
+				latest = i
+			}
+		case version.Version > current.Version:
+			latest = i
+		}
+	}
+
+	return latest
+}
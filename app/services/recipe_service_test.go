@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"pms/app/models"
+)
+
+func TestLatestRecipeVersion(t *testing.T) {
+	past := time.Now().AddDate(0, 0, -7)
+	earlier := past.AddDate(0, -1, 0)
+
+	tests := []struct {
+		name     string
+		versions []models.RecipeVariant
+		expected int
+	}{
+		{
+			name:     "no version",
+			versions: nil,
+			expected: -1,
+		},
+		{
+			name: "undated first version is superseded by a dated one",
+			versions: []models.RecipeVariant{
+				{Version: 1},
+				{Version: 2, EffectiveFrom: &past},
+			},
+			expected: 1,
+		},
+		{
+			name: "dated version listed before the undated one",
+			versions: []models.RecipeVariant{
+				{Version: 2, EffectiveFrom: &past},
+				{Version: 1},
+			},
+			expected: 0,
+		},
+		{
+			name: "latest effective date wins over the version number",
+			versions: []models.RecipeVariant{
+				{Version: 3, EffectiveFrom: &earlier},
+				{Version: 2, EffectiveFrom: &past},
+			},
+			expected: 1,
+		},
+		{
+			name: "same effective date falls back to the version number",
+			versions: []models.RecipeVariant{
+				{Version: 3, EffectiveFrom: &past},
+				{Version: 4, EffectiveFrom: &past},
+			},
+			expected: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, latestRecipeVersion(test.versions))
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// Actions of a recipe generation on a variant
+const (
+	RecipeGenerationCreate      = "create"       // first recipe of the variant, active at once
+	RecipeGenerationUpdateDraft = "update_draft" // the variant's draft version is replaced
+	RecipeGenerationNewVersion  = "new_version"  // a draft version to put into effect by change order
+	RecipeGenerationUnchanged   = "unchanged"
+	RecipeGenerationError       = "error"
+)
+
+// Changes of a material line between the current and the generated recipe
+const (
+	RecipeLineAdded     = "added"
+	RecipeLineRemoved   = "removed"
+	RecipeLineChanged   = "changed"
+	RecipeLineUnchanged = "unchanged"
+)
+
+var ErrEmptyRecipeTemplate = errors.New("the product has no recipe template lines")
+
+// InvalidTemplateLineError is returned when a recipe template line cannot be used
+type InvalidTemplateLineError struct {
+	Index  int
+	Reason string
+}
+
+func (e *InvalidTemplateLineError) Error() string {
+	return fmt.Sprintf("template line %d: %s", e.Index+1, e.Reason)
+}
+
+// RecipeGenerationLine compares a material of the current recipe of a variant with the
+// generated one. Quantities are per output quantity of the recipe, in the line unit.
+type RecipeGenerationLine struct {
+	MaterialVariantID uint     `json:"material_variant_id"`
+	SKU               string   `json:"sku"`
+	Change            string   `json:"change"`
+	CurrentQuantity   *float64 `json:"current_quantity"`
+	Quantity          *float64 `json:"quantity"`
+	Unit              string   `json:"unit"`
+}
+
+// RecipeGenerationResult is what the generation does, or would do, to one variant
+type RecipeGenerationResult struct {
+	VariantID uint                   `json:"variant_id"`
+	SKU       string                 `json:"sku"`
+	Action    string                 `json:"action"`
+	RecipeID  *uint                  `json:"recipe_id"` // the recipe compared with, or written
+	Error     string                 `json:"error,omitempty"`
+	Lines     []RecipeGenerationLine `json:"lines"`
+}
+
+// RecipeGeneration is the outcome of generating the recipes of all variants of a product
+type RecipeGeneration struct {
+	ProductID uint                     `json:"product_id"`
+	DryRun    bool                     `json:"dry_run"`
+	Results   []RecipeGenerationResult `json:"results"`
+}
+
+var leadingNumberPattern = regexp.MustCompile(`^\s*(-?\d+(?:[.,]\d+)?)`)
+
+type RecipeTemplateService struct {
+	recipeService *RecipeService
+}
+
+func NewRecipeTemplateService() *RecipeTemplateService {
+	return &RecipeTemplateService{
+		recipeService: NewRecipeService(),
+	}
+}
+
+// Save replaces the recipe template lines of a product
+func (s *RecipeTemplateService) Save(product models.Product, lines []models.RecipeProduct) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		for i := range lines {
+			line := &lines[i]
+			if line.MaterialProductID == product.ID {
+				return &InvalidTemplateLineError{Index: i, Reason: "a product cannot use itself as material"}
+			}
+			if line.Quantity <= 0 {
+				return &InvalidTemplateLineError{Index: i, Reason: "quantity must be greater than zero"}
+			}
+			if line.ScrapPercent < 0 || line.ScrapPercent >= 100 {
+				return &InvalidTemplateLineError{Index: i, Reason: "scrap percentage must be between 0 and 100"}
+			}
+			if line.ScaleFactor == 0 {
+				line.ScaleFactor = 1
+			}
+
+			exists, err := tx.Model(&models.Product{}).Where("id", line.MaterialProductID).Exists()
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return &InvalidTemplateLineError{Index: i, Reason: "material product does not exist"}
+			}
+			if line.MaterialVariantID != nil {
+				if line.MatchAttribute != "" {
+					return &InvalidTemplateLineError{Index: i, Reason: "a line either fixes its material variant or matches it on an attribute"}
+				}
+				exists, err := tx.Model(&models.ProductVariant{}).Where("id", *line.MaterialVariantID).
+					Where("product_id", line.MaterialProductID).Exists()
+				if err != nil {
+					return err
+				}
+				if !exists {
+					return &InvalidTemplateLineError{Index: i, Reason: "material variant does not belong to the material product"}
+				}
+			}
+		}
+
+		if _, err := tx.Where("product_id", product.ID).Delete(&models.RecipeProduct{}); err != nil {
+			return err
+		}
+		for i := range lines {
+			lines[i].ID = 0
+			lines[i].ProductID = product.ID
+			if err := tx.Create(&lines[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Generate builds the recipe of every variant of a product from its template lines and
+// compares it with the variant's current recipe. Unless it is a dry run, variants without
+// recipe get it as their first version, a draft version is replaced and otherwise the changes
+// become a new draft version, to be put into effect by an engineering change order. Variants
+// whose recipe cannot be generated are reported and skipped.
+func (s *RecipeTemplateService) Generate(productID uint, dryRun bool) (*RecipeGeneration, error) {
+	query := facades.Orm().Query()
+
+	var lines []models.RecipeProduct
+	if err := query.With("MaterialVariant").Where("product_id", productID).OrderBy("id").Find(&lines); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, ErrEmptyRecipeTemplate
+	}
+
+	var variants []models.ProductVariant
+	if err := query.Where("product_id", productID).OrderBy("id").Find(&variants); err != nil {
+		return nil, err
+	}
+
+	generation := &RecipeGeneration{ProductID: productID, DryRun: dryRun, Results: []RecipeGenerationResult{}}
+	materials := map[uint][]models.ProductVariant{}
+	for _, variant := range variants {
+		result := RecipeGenerationResult{VariantID: variant.ID, SKU: variant.SKU, Lines: []RecipeGenerationLine{}}
+
+		items, err := s.items(query, lines, variant, materials)
+		if err != nil {
+			var lineErr *InvalidTemplateLineError
+			if !errors.As(err, &lineErr) {
+				return nil, err
+			}
+			result.Action = RecipeGenerationError
+			result.Error = err.Error()
+			generation.Results = append(generation.Results, result)
+			continue
+		}
+
+		if err := s.compare(query, variant, items, &result); err != nil {
+			return nil, err
+		}
+		if !dryRun && result.Action != RecipeGenerationUnchanged {
+			if err := s.apply(query, variant, items, &result); err != nil {
+				return nil, err
+			}
+		}
+
+		generation.Results = append(generation.Results, result)
+	}
+
+	return generation, nil
+}
+
+// items builds the recipe items of one unit of a variant from the template lines
+func (s *RecipeTemplateService) items(query orm.Query, lines []models.RecipeProduct, variant models.ProductVariant, materials map[uint][]models.ProductVariant) ([]models.RecipeVariantItem, error) {
+	attributes := variantAttributes(variant)
+
+	items := make([]models.RecipeVariantItem, 0, len(lines))
+	for i, line := range lines {
+		var material *models.ProductVariant
+		switch {
+		case line.MaterialVariant != nil:
+			material = line.MaterialVariant
+		default:
+			candidates, ok := materials[line.MaterialProductID]
+			if !ok {
+				if err := query.Where("product_id", line.MaterialProductID).OrderBy("id").Find(&candidates); err != nil {
+					return nil, err
+				}
+				materials[line.MaterialProductID] = candidates
+			}
+
+			if line.MatchAttribute != "" {
+				value, ok := attributes[strings.ToLower(line.MatchAttribute)]
+				if !ok {
+					return nil, &InvalidTemplateLineError{Index: i, Reason: "the variant has no " + line.MatchAttribute + " attribute"}
+				}
+				key := line.MaterialAttribute
+				if key == "" {
+					key = line.MatchAttribute
+				}
+				matches := []models.ProductVariant{}
+				for _, candidate := range candidates {
+					if strings.EqualFold(strings.TrimSpace(variantAttributes(candidate)[strings.ToLower(key)]), strings.TrimSpace(value)) {
+						matches = append(matches, candidate)
+					}
+				}
+				candidates = matches
+			}
+
+			if len(candidates) != 1 {
+				return nil, &InvalidTemplateLineError{Index: i, Reason: fmt.Sprintf("%d material variants match, expected one", len(candidates))}
+			}
+			material = &candidates[0]
+		}
+
+		quantity := line.Quantity
+		if line.ScaleAttribute != "" {
+			value, ok := attributes[strings.ToLower(line.ScaleAttribute)]
+			if !ok {
+				return nil, &InvalidTemplateLineError{Index: i, Reason: "the variant has no " + line.ScaleAttribute + " attribute"}
+			}
+			match := leadingNumberPattern.FindStringSubmatch(value)
+			if match == nil {
+				return nil, &InvalidTemplateLineError{Index: i, Reason: "the " + line.ScaleAttribute + " attribute is not a number"}
+			}
+			scale, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+			if err != nil {
+				return nil, &InvalidTemplateLineError{Index: i, Reason: "the " + line.ScaleAttribute + " attribute is not a number"}
+			}
+			quantity *= scale * line.ScaleFactor
+		}
+
+		items = append(items, models.RecipeVariantItem{
+			MaterialVariantID: material.ID,
+			MaterialVariant:   *material,
+			Quantity:          roundQuantity(quantity),
+			Unit:              line.Unit,
+			ScrapPercent:      line.ScrapPercent,
+			Notes:             line.Notes,
+		})
+	}
+
+	return items, nil
+}
+
+// compare decides what the generation does to a variant and lists the line changes against
+// its draft version, or else its recipe in effect. The generated items are scaled to the
+// output quantity of that recipe.
+func (s *RecipeTemplateService) compare(query orm.Query, variant models.ProductVariant, items []models.RecipeVariantItem, result *RecipeGenerationResult) error {
+	var current models.RecipeVariant
+	if err := query.With("RecipeVariantItems.MaterialVariant").Where("variant_id", variant.ID).
+		Where("status", models.RecipeStatusDraft).First(&current); err != nil {
+		return err
+	}
+	action := RecipeGenerationUpdateDraft
+	if current.ID == 0 {
+		effective, err := s.recipeService.EffectiveTx(query, variant.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if effective != nil {
+			current = *effective
+			action = RecipeGenerationNewVersion
+		} else {
+			exists, err := query.Model(&models.RecipeVariant{}).Where("variant_id", variant.ID).Exists()
+			if err != nil {
+				return err
+			}
+			if exists {
+				result.Action = RecipeGenerationError
+				result.Error = "the variant has no recipe in effect nor draft to compare with"
+				return nil
+			}
+			action = RecipeGenerationCreate
+		}
+	}
+
+	output := 1.0
+	if current.ID != 0 {
+		result.RecipeID = &current.ID
+		output = current.OutputQuantity
+	}
+	for i := range items {
+		items[i].Quantity = roundQuantity(items[i].Quantity * output)
+	}
+
+	// Quantities are compared per material, in the unit of the lines
+	type side struct {
+		sku      string
+		unit     string
+		scrap    float64
+		quantity *float64
+	}
+	order := []uint{}
+	currentLines := map[uint]*side{}
+	generatedLines := map[uint]*side{}
+	add := func(lines map[uint]*side, item models.RecipeVariantItem) {
+		if _, ok := currentLines[item.MaterialVariantID]; !ok {
+			if _, ok := generatedLines[item.MaterialVariantID]; !ok {
+				order = append(order, item.MaterialVariantID)
+			}
+		}
+		if lines[item.MaterialVariantID] == nil {
+			quantity := 0.0
+			lines[item.MaterialVariantID] = &side{sku: item.MaterialVariant.SKU, unit: item.Unit, scrap: item.ScrapPercent, quantity: &quantity}
+		}
+		*lines[item.MaterialVariantID].quantity = roundQuantity(*lines[item.MaterialVariantID].quantity + item.Quantity)
+	}
+	for _, item := range current.RecipeVariantItems {
+		add(currentLines, item)
+	}
+	for _, item := range items {
+		add(generatedLines, item)
+	}
+
+	changed := false
+	for _, id := range order {
+		before, after := currentLines[id], generatedLines[id]
+		line := RecipeGenerationLine{MaterialVariantID: id}
+		switch {
+		case before == nil:
+			line.Change, line.SKU, line.Unit, line.Quantity = RecipeLineAdded, after.sku, after.unit, after.quantity
+		case after == nil:
+			line.Change, line.SKU, line.Unit, line.CurrentQuantity = RecipeLineRemoved, before.sku, before.unit, before.quantity
+		default:
+			line.Change, line.SKU, line.Unit = RecipeLineUnchanged, after.sku, after.unit
+			line.CurrentQuantity, line.Quantity = before.quantity, after.quantity
+			if *before.quantity != *after.quantity || !strings.EqualFold(before.unit, after.unit) || before.scrap != after.scrap {
+				line.Change = RecipeLineChanged
+			}
+		}
+		if line.Change != RecipeLineUnchanged {
+			changed = true
+		}
+		result.Lines = append(result.Lines, line)
+	}
+
+	result.Action = action
+	if !changed && action != RecipeGenerationCreate {
+		result.Action = RecipeGenerationUnchanged
+	}
+
+	return nil
+}
+
+// apply writes the generated recipe of a variant as decided by compare. Recipes rejected by
+// the recipe validation are reported on the result.
+func (s *RecipeTemplateService) apply(query orm.Query, variant models.ProductVariant, items []models.RecipeVariantItem, result *RecipeGenerationResult) error {
+	var err error
+	var recipe *models.RecipeVariant
+	switch result.Action {
+	case RecipeGenerationCreate:
+		recipe = &models.RecipeVariant{
+			ProductID:      variant.ProductID,
+			VariantID:      variant.ID,
+			OutputQuantity: 1,
+			Notes:          "Generated from the product recipe template",
+		}
+		err = s.recipeService.Save(recipe, items, nil)
+	case RecipeGenerationUpdateDraft:
+		recipe = &models.RecipeVariant{}
+		if err := query.With("ByProducts").Where("id", *result.RecipeID).FirstOrFail(recipe); err != nil {
+			return err
+		}
+		err = s.recipeService.Save(recipe, items, recipe.ByProducts)
+	case RecipeGenerationNewVersion:
+		var current models.RecipeVariant
+		if err := query.With("ByProducts").Where("id", *result.RecipeID).FirstOrFail(&current); err != nil {
+			return err
+		}
+		current.RecipeVariantItems = items
+		recipe, err = s.recipeService.NewVersion(current)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		var itemErr *InvalidRecipeItemError
+		var cycleErr *RecipeCycleError
+		if errors.As(err, &itemErr) || errors.As(err, &cycleErr) || errors.Is(err, ErrRecipeSelfReference) ||
+			errors.Is(err, ErrRecipeDraftExists) {
+			result.Action = RecipeGenerationError
+			result.Error = err.Error()
+			return nil
+		}
+		return err
+	}
+
+	result.RecipeID = &recipe.ID
+	return nil
+}
+
+// variantAttributes decodes the attributes of a variant, keyed in lower case
+func variantAttributes(variant models.ProductVariant) map[string]string {
+	attributes := map[string]string{}
+	if variant.Attributes == "" {
+		return attributes
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(variant.Attributes), &raw); err != nil {
+		return attributes
+	}
+	for key, value := range raw {
+		attributes[strings.ToLower(key)] = fmt.Sprint(value)
+	}
+
+	return attributes
+}
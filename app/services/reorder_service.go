@@ -0,0 +1,223 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/contracts/mail"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/mails"
+	"pms/app/models"
+)
+
+// Replenishment suggestion statuses
+const (
+	SuggestionStatusDraft     = "draft"
+	SuggestionStatusOrdered   = "ordered"
+	SuggestionStatusDismissed = "dismissed"
+	SuggestionStatusResolved  = "resolved"
+)
+
+// LowStockReport is the outcome of a reorder point check
+type LowStockReport struct {
+	CheckedAt   time.Time
+	Rules       int
+	Suggestions []models.ReplenishmentSuggestion
+	Resolved    int
+}
+
+type ReorderService struct {
+	mailer mail.Mail // the mail facade when nil
+}
+
+func NewReorderService() *ReorderService {
+	return &ReorderService{}
+}
+
+// Evaluate computes the stock position of every active rule on a raw material variant and
+// returns the ones at or below their reorder point, without saving anything
+func (s *ReorderService) Evaluate(query orm.Query) ([]models.ReplenishmentSuggestion, []models.ReorderRule, error) {
+	var rules []models.ReorderRule
+	if err := query.With("Variant").Where("is_active", true).
+		Where("variant_id IN (SELECT pv.id FROM product_variants pv JOIN products p ON p.id = pv.product_id WHERE p.is_raw_material = ?)", true).
+		OrderBy("id").Find(&rules); err != nil {
+		return nil, nil, err
+	}
+
+	low := []models.ReplenishmentSuggestion{}
+	for _, rule := range rules {
+		onHand, reserved, err := s.position(query, rule)
+		if err != nil {
+			return nil, nil, err
+		}
+		available := roundQuantity(onHand - reserved)
+		if available > rule.ReorderPoint {
+			continue
+		}
+
+		low = append(low, models.ReplenishmentSuggestion{
+			ReorderRuleID:     rule.ID,
+			ProductID:         rule.Variant.ProductID,
+			VariantID:         rule.VariantID,
+			LocationID:        rule.LocationID,
+			OnHand:            roundQuantity(onHand),
+			Reserved:          roundQuantity(reserved),
+			Available:         available,
+			SuggestedQuantity: roundQuantity(math.Max(rule.MaxQuantity-available, 0)),
+			Unit:              rule.Variant.Unit,
+			Status:            SuggestionStatusDraft,
+			IsCritical:        available < rule.MinQuantity,
+		})
+	}
+
+	return low, rules, nil
+}
+
+// Check evaluates the rules and keeps one draft suggestion per low rule up to date. Drafts
+// whose stock recovered are marked resolved.
+func (s *ReorderService) Check() (*LowStockReport, error) {
+	report := &LowStockReport{CheckedAt: time.Now()}
+	err := facades.Orm().Transaction(func(tx orm.Query) error {
+		low, rules, err := s.Evaluate(tx)
+		if err != nil {
+			return err
+		}
+		report.Rules = len(rules)
+
+		lowRules := map[uint]bool{}
+		for _, suggestion := range low {
+			lowRules[suggestion.ReorderRuleID] = true
+
+			var draft models.ReplenishmentSuggestion
+			if err := tx.LockForUpdate().Where("reorder_rule_id", suggestion.ReorderRuleID).Where("status", SuggestionStatusDraft).First(&draft); err != nil {
+				return err
+			}
+			if draft.ID != 0 {
+				suggestion.ID = draft.ID
+				suggestion.CreatedAt = draft.CreatedAt
+				suggestion.NotifiedAt = draft.NotifiedAt
+			}
+			if err := tx.Save(&suggestion); err != nil {
+				return err
+			}
+			report.Suggestions = append(report.Suggestions, suggestion)
+		}
+
+		// Drafts of rules no longer low are not needed anymore
+		var drafts []models.ReplenishmentSuggestion
+		if err := tx.Where("status", SuggestionStatusDraft).Find(&drafts); err != nil {
+			return err
+		}
+		for _, draft := range drafts {
+			if lowRules[draft.ReorderRuleID] {
+				continue
+			}
+			if _, err := tx.Model(&models.ReplenishmentSuggestion{}).Where("id", draft.ID).Update("status", SuggestionStatusResolved); err != nil {
+				return err
+			}
+			report.Resolved++
+		}
+
+		return nil
+	})
+
+	return report, err
+}
+
+// Notify mails the low-stock report to the active users of the achat role and stamps the
+// notified suggestions. It returns the number of recipients.
+func (s *ReorderService) Notify(report *LowStockReport) (int, error) {
+	if len(report.Suggestions) == 0 {
+		return 0, nil
+	}
+
+	var role models.Role
+	if err := facades.Orm().Query().Where("key", "achat").First(&role); err != nil || role.ID == 0 {
+		return 0, err
+	}
+	var users []models.User
+	if err := facades.Orm().Query().Where("role_id", role.ID).Where("is_active", true).OrderBy("id").Find(&users); err != nil {
+		return 0, err
+	}
+	recipients := lowStockRecipients(users)
+	if len(recipients) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uint, 0, len(report.Suggestions))
+	for _, suggestion := range report.Suggestions {
+		ids = append(ids, suggestion.ID)
+	}
+	var suggestions []models.ReplenishmentSuggestion
+	if err := facades.Orm().Query().With("Product").With("Variant").With("Location").Where("id IN ?", ids).OrderBy("id").Find(&suggestions); err != nil {
+		return 0, err
+	}
+
+	if err := s.sendLowStock(recipients, suggestions); err != nil {
+		return 0, err
+	}
+
+	if _, err := facades.Orm().Query().Model(&models.ReplenishmentSuggestion{}).Where("id IN ?", ids).Update("notified_at", time.Now()); err != nil {
+		return 0, err
+	}
+
+	return len(recipients), nil
+}
+
+// sendLowStock mails the low-stock report of the suggestions to the recipients
+func (s *ReorderService) sendLowStock(recipients []string, suggestions []models.ReplenishmentSuggestion) error {
+	mailer := s.mailer
+	if mailer == nil {
+		mailer = facades.Mail()
+	}
+
+	return mailer.Send(mails.NewLowStockMail(recipients, suggestions))
+}
+
+// lowStockRecipients returns the addresses of the active users, once each and in order,
+// leaving out users without an email
+func lowStockRecipients(users []models.User) []string {
+	recipients := []string{}
+	seen := map[string]bool{}
+	for _, user := range users {
+		email := strings.TrimSpace(user.Email)
+		if !user.IsActive || email == "" || seen[strings.ToLower(email)] {
+			continue
+		}
+		seen[strings.ToLower(email)] = true
+		recipients = append(recipients, email)
+	}
+
+	return recipients
+}
+
+// position returns the on hand and reserved quantities in the scope of a rule
+func (s *ReorderService) position(query orm.Query, rule models.ReorderRule) (float64, float64, error) {
+	var onHand struct {
+		Total float64
+	}
+	levels := query.Model(&models.StockLevel{}).Select("COALESCE(SUM(quantity), 0) AS total").Where("variant_id", rule.VariantID)
+	if rule.LocationID != nil {
+		levels = levels.Where("location_id", *rule.LocationID)
+	}
+	if err := levels.Scan(&onHand); err != nil {
+		return 0, 0, err
+	}
+
+	var reserved struct {
+		Total float64
+	}
+	reservations := query.Model(&models.StockReservation{}).Select("COALESCE(SUM(quantity - consumed_quantity), 0) AS total").
+		Where("variant_id", rule.VariantID).Where("status", ReservationStatusActive)
+	if rule.LocationID != nil {
+		reservations = reservations.Where("location_id", *rule.LocationID)
+	}
+	if err := reservations.Scan(&reserved); err != nil {
+		return 0, 0, err
+	}
+
+	return onHand.Total, reserved.Total, nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/goravel/framework/contracts/mail"
+	mocksmail "github.com/goravel/framework/mocks/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"pms/app/models"
+)
+
+func TestLowStockRecipients(t *testing.T) {
+	users := []models.User{
+		{Email: "buyer@example.com", IsActive: true},
+		{Email: "", IsActive: true},
+		{Email: "  ", IsActive: true},
+		{Email: "left@example.com", IsActive: false},
+		{Email: "Buyer@Example.com", IsActive: true},
+		{Email: " second@example.com ", IsActive: true},
+	}
+
+	assert.Equal(t, []string{"buyer@example.com", "second@example.com"}, lowStockRecipients(users))
+	assert.Empty(t, lowStockRecipients(nil))
+}
+
+func TestReorderServiceSendLowStock(t *testing.T) {
+	mailer := mocksmail.NewMail(t)
+	service := &ReorderService{mailer: mailer}
+	recipients := []string{"buyer@example.com", "second@example.com"}
+	suggestions := []models.ReplenishmentSuggestion{
+		{Product: models.Product{Title: "Steel sheet"}, SuggestedQuantity: 20, Unit: "kg"},
+	}
+
+	mailer.EXPECT().Send(mock.MatchedBy(func(mailable mail.Mailable) bool {
+		return assert.ObjectsAreEqual(recipients, mailable.Envelope().To) &&
+			assert.Contains(t, mailable.Content().Html, "<td>Steel sheet</td>")
+	})).Return(nil).Once()
+	assert.NoError(t, service.sendLowStock(recipients, suggestions))
+
+	failure := errors.New("smtp unavailable")
+	mailer.EXPECT().Send(mock.Anything).Return(failure).Once()
+	assert.ErrorIs(t, service.sendLowStock(recipients, suggestions), failure)
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// Stock reservation statuses
+const (
+	ReservationStatusActive   = "active"
+	ReservationStatusReleased = "released"
+	ReservationStatusConsumed = "consumed"
+)
+
+// ReferenceTypeOrderFabrication tags the movements consuming material for a manufacturing order
+const ReferenceTypeOrderFabrication = "order_fabrication"
+
+var ErrOrderNotConsuming = errors.New("material can only be consumed on released or in progress orders")
+
+// InsufficientReservationError is returned when more material is consumed than reserved
+type InsufficientReservationError struct {
+	Reserved  float64
+	Requested float64
+}
+
+func (e *InsufficientReservationError) Error() string {
+	return fmt.Sprintf("insufficient reservation: %.3f reserved, %.3f requested", e.Reserved, e.Requested)
+}
+
+type ReservationService struct {
+	stockService *StockService
+}
+
+func NewReservationService() *ReservationService {
+	return &ReservationService{
+		stockService: NewStockService(),
+	}
+}
+
+// ReserveForOrderTx soft-reserves the stock covering each requirement of an order, spread
+// over the locations with free stock. Active reservations are rebuilt on every run, while
+// what was already consumed is kept.
+func (s *ReservationService) ReserveForOrderTx(tx orm.Query, order *models.OrderFabrication, user models.User) ([]models.StockReservation, error) {
+	var requirements []models.ProductionMaterialRequirement
+	if err := tx.With("MaterialVariant").Where("order_fabrication_id", order.ID).Find(&requirements); err != nil {
+		return nil, err
+	}
+
+	reservations := []models.StockReservation{}
+	for _, requirement := range requirements {
+		if err := s.closeActive(tx, "requirement_id", requirement.ID); err != nil {
+			return nil, err
+		}
+		if requirement.Status == RequirementStatusConsumed || requirement.StockQuantity <= 0 {
+			continue
+		}
+
+		// Lock the levels of the material so concurrent orders reserve one after the other
+		var levels []models.StockLevel
+		if err := tx.LockForUpdate().Where("variant_id", requirement.MaterialVariantID).Where("quantity > ?", 0).
+			OrderBy("quantity", "desc").Find(&levels); err != nil {
+			return nil, err
+		}
+
+		remaining := requirement.StockQuantity
+		variantID := requirement.MaterialVariantID
+		for _, level := range levels {
+			if remaining <= 0 {
+				break
+			}
+			reserved, err := s.ReservedQuantity(tx, level.ProductID, level.VariantID, level.LocationID)
+			if err != nil {
+				return nil, err
+			}
+			quantity := roundQuantity(math.Min(remaining, level.Quantity-reserved))
+			if quantity <= 0 {
+				continue
+			}
+
+			reservation := models.StockReservation{
+				OrderFabricationID: order.ID,
+				RequirementID:      requirement.ID,
+				ProductID:          requirement.MaterialVariant.ProductID,
+				VariantID:          &variantID,
+				LocationID:         level.LocationID,
+				Quantity:           quantity,
+				Unit:               requirement.Unit,
+				Status:             ReservationStatusActive,
+				CreatedBy:          user.ID,
+			}
+			if err := tx.Create(&reservation); err != nil {
+				return nil, err
+			}
+			reservations = append(reservations, reservation)
+			remaining = roundQuantity(remaining - quantity)
+		}
+	}
+
+	return reservations, nil
+}
+
+// ReleaseForOrderTx frees the active reservations of an order, typically on cancellation
+func (s *ReservationService) ReleaseForOrderTx(tx orm.Query, order *models.OrderFabrication) error {
+	return s.closeActive(tx, "order_fabrication_id", order.ID)
+}
+
+// Consume turns reserved material into real out movements, taking from the requirement's
+// reservations in the order they were made
+func (s *ReservationService) Consume(order *models.OrderFabrication, requirement *models.ProductionMaterialRequirement, quantity float64, user models.User) ([]models.StockMovement, error) {
+	movements := []models.StockMovement{}
+	err := facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := tx.LockForUpdate().Where("id", order.ID).FirstOrFail(order); err != nil {
+			return err
+		}
+		if order.Status != models.OrderFabricationStatusReleased && order.Status != models.OrderFabricationStatusInProgress {
+			return ErrOrderNotConsuming
+		}
+		if err := tx.LockForUpdate().Where("id", requirement.ID).Where("order_fabrication_id", order.ID).FirstOrFail(requirement); err != nil {
+			return err
+		}
+
+		var reservations []models.StockReservation
+		if err := tx.LockForUpdate().Where("requirement_id", requirement.ID).Where("status", ReservationStatusActive).
+			OrderBy("id").Find(&reservations); err != nil {
+			return err
+		}
+
+		reserved := 0.0
+		for _, reservation := range reservations {
+			reserved += reservation.Quantity - reservation.ConsumedQuantity
+		}
+		if roundQuantity(reserved) < quantity {
+			return &InsufficientReservationError{Reserved: roundQuantity(reserved), Requested: quantity}
+		}
+
+		remaining := quantity
+		orderID := order.ID
+		for _, reservation := range reservations {
+			if remaining <= 0 {
+				break
+			}
+			take := roundQuantity(math.Min(remaining, reservation.Quantity-reservation.ConsumedQuantity))
+			if take <= 0 {
+				continue
+			}
+
+			movement := models.StockMovement{
+				ProductID:     reservation.ProductID,
+				VariantID:     reservation.VariantID,
+				LocationID:    reservation.LocationID,
+				MovementType:  MovementTypeOut,
+				Quantity:      take,
+				Unit:          reservation.Unit,
+				ReferenceType: ReferenceTypeOrderFabrication,
+				ReferenceID:   &orderID,
+				Notes:         "Material consumed by " + order.OrderNumber,
+				CreatedBy:     user.ID,
+			}
+			if _, err := s.stockService.PostMovementTx(tx, &movement); err != nil {
+				return err
+			}
+			movements = append(movements, movement)
+
+			consumed := roundQuantity(reservation.ConsumedQuantity + take)
+			status := reservation.Status
+			if consumed >= reservation.Quantity {
+				status = ReservationStatusConsumed
+			}
+			if _, err := tx.Model(&models.StockReservation{}).Where("id", reservation.ID).Update(map[string]any{
+				"consumed_quantity": consumed,
+				"status":            status,
+			}); err != nil {
+				return err
+			}
+			remaining = roundQuantity(remaining - take)
+		}
+
+		consumed := roundQuantity(requirement.ConsumedQuantity + quantity)
+		updates := map[string]any{"consumed_quantity": consumed}
+		if consumed >= requirement.RequiredQuantity {
+			updates["status"] = RequirementStatusConsumed
+		}
+		_, err := tx.Model(&models.ProductionMaterialRequirement{}).Where("id", requirement.ID).Update(updates)
+
+		return err
+	})
+
+	return movements, err
+}
+
+// ReservedQuantity returns the quantity held by active reservations on a stock level
+func (s *ReservationService) ReservedQuantity(tx orm.Query, productID uint, variantID *uint, locationID uint) (float64, error) {
+	var result struct {
+		Total float64
+	}
+	query := tx.Model(&models.StockReservation{}).Select("COALESCE(SUM(quantity - consumed_quantity), 0) AS total").
+		Where("status", ReservationStatusActive)
+	if err := s.stockService.levelQuery(query, productID, variantID, locationID).Scan(&result); err != nil {
+		return 0, err
+	}
+
+	return result.Total, nil
+}
+
+// WithAvailability fills the reserved and available quantities of stock levels
+func (s *ReservationService) WithAvailability(levels []models.StockLevel) error {
+	for i := range levels {
+		reserved, err := s.ReservedQuantity(facades.Orm().Query(), levels[i].ProductID, levels[i].VariantID, levels[i].LocationID)
+		if err != nil {
+			return err
+		}
+		levels[i].Reserved = roundQuantity(reserved)
+		levels[i].Available = roundQuantity(levels[i].Quantity - reserved)
+	}
+
+	return nil
+}
+
+// closeActive ends the active reservations matching a column. Partly consumed ones are
+// shrunk to what was consumed, the others are released.
+func (s *ReservationService) closeActive(tx orm.Query, column string, value uint) error {
+	var reservations []models.StockReservation
+	if err := tx.LockForUpdate().Where(column, value).Where("status", ReservationStatusActive).Find(&reservations); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, reservation := range reservations {
+		updates := map[string]any{"status": ReservationStatusReleased, "released_at": now}
+		if reservation.ConsumedQuantity > 0 {
+			updates = map[string]any{"status": ReservationStatusConsumed, "quantity": reservation.ConsumedQuantity}
+		}
+		if _, err := tx.Model(&models.StockReservation{}).Where("id", reservation.ID).Update(updates); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+var ErrRoutingVariantMismatch = errors.New("variant does not belong to the product")
+
+// InvalidRoutingStepError is returned when a routing step cannot be used
+type InvalidRoutingStepError struct {
+	Index  int
+	Reason string
+}
+
+func (e *InvalidRoutingStepError) Error() string {
+	return fmt.Sprintf("routing step %d: %s", e.Index+1, e.Reason)
+}
+
+type RoutingService struct{}
+
+func NewRoutingService() *RoutingService {
+	return &RoutingService{}
+}
+
+// StepsFor returns the routing of a variant: its own steps when it has some, the steps of
+// its product otherwise. A nil variant returns the product routing.
+func (s *RoutingService) StepsFor(query orm.Query, productID uint, variantID *uint) ([]models.RoutingStep, error) {
+	var steps []models.RoutingStep
+	if variantID != nil {
+		if err := query.With("Operation").Where("product_id", productID).Where("variant_id", *variantID).
+			OrderBy("sequence").Find(&steps); err != nil {
+			return nil, err
+		}
+		if len(steps) > 0 {
+			return steps, nil
+		}
+	}
+
+	if err := query.With("Operation").Where("product_id", productID).WhereNull("variant_id").
+		OrderBy("sequence").Find(&steps); err != nil {
+		return nil, err
+	}
+
+	return steps, nil
+}
+
+// Save replaces the routing of a product, or the routing specific to one of its variants.
+// Steps without sequence are numbered by 10 in the given order.
+func (s *RoutingService) Save(product models.Product, variantID *uint, steps []models.RoutingStep) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		if variantID != nil {
+			exists, err := tx.Model(&models.ProductVariant{}).Where("id", *variantID).Where("product_id", product.ID).Exists()
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return ErrRoutingVariantMismatch
+			}
+		}
+
+		sequences := map[int]bool{}
+		for i := range steps {
+			step := &steps[i]
+			if step.Sequence == 0 {
+				step.Sequence = (i + 1) * 10
+			}
+			if step.Sequence < 0 {
+				return &InvalidRoutingStepError{Index: i, Reason: "sequence must be positive"}
+			}
+			if sequences[step.Sequence] {
+				return &InvalidRoutingStepError{Index: i, Reason: "sequence is used by another step"}
+			}
+			sequences[step.Sequence] = true
+			if step.SetupMinutes < 0 || step.RunMinutes < 0 {
+				return &InvalidRoutingStepError{Index: i, Reason: "setup and run times cannot be negative"}
+			}
+
+			exists, err := tx.Model(&models.Operation{}).Where("id", step.OperationID).Exists()
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return &InvalidRoutingStepError{Index: i, Reason: "operation does not exist"}
+			}
+			if step.RequiredRole != "" {
+				exists, err := tx.Model(&models.Role{}).Where("key", step.RequiredRole).Exists()
+				if err != nil {
+					return err
+				}
+				if !exists {
+					return &InvalidRoutingStepError{Index: i, Reason: "required role does not exist"}
+				}
+			}
+		}
+
+		deleteQuery := tx.Where("product_id", product.ID)
+		if variantID != nil {
+			deleteQuery = deleteQuery.Where("variant_id", *variantID)
+		} else {
+			deleteQuery = deleteQuery.WhereNull("variant_id")
+		}
+		if _, err := deleteQuery.Delete(&models.RoutingStep{}); err != nil {
+			return err
+		}
+		for i := range steps {
+			steps[i].ID = 0
+			steps[i].ProductID = product.ID
+			steps[i].VariantID = variantID
+			if err := tx.Create(&steps[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// CopyToOrderTx copies the routing of an order's variant onto the order, with the time
+// planned for its quantity. Orders already holding operations, such as orders released
+// again after a hold, keep them.
+func (s *RoutingService) CopyToOrderTx(tx orm.Query, order *models.OrderFabrication) ([]models.OrderFabricationOperation, error) {
+	var operations []models.OrderFabricationOperation
+	if err := tx.Where("order_fabrication_id", order.ID).OrderBy("sequence").Find(&operations); err != nil {
+		return nil, err
+	}
+	if len(operations) > 0 {
+		return operations, nil
+	}
+
+	steps, err := s.StepsFor(tx, order.ProductID, order.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	operations = make([]models.OrderFabricationOperation, 0, len(steps))
+	for _, step := range steps {
+		stepID := step.ID
+		operation := models.OrderFabricationOperation{
+			OrderFabricationID: order.ID,
+			OperationID:        step.OperationID,
+			RoutingStepID:      &stepID,
+			Sequence:           step.Sequence,
+			SetupMinutes:       step.SetupMinutes,
+			RunMinutes:         step.RunMinutes,
+			PlannedMinutes:     math.Round((step.SetupMinutes+step.RunMinutes*order.Quantity)*100) / 100,
+			Workstation:        step.Workstation,
+			RequiredRole:       step.RequiredRole,
+			Notes:              step.Notes,
+		}
+		if err := tx.Create(&operation); err != nil {
+			return nil, err
+		}
+		operations = append(operations, operation)
+	}
+
+	return operations, nil
+}
+
+// LabourCost returns the labour cost of one unit of a variant from its routing, at the
+// hourly rate of each operation. Setup times are spread over the given batch quantity.
+func (s *RoutingService) LabourCost(query orm.Query, productID, variantID uint, batch float64) (float64, error) {
+	steps, err := s.StepsFor(query, productID, &variantID)
+	if err != nil {
+		return 0, err
+	}
+	if batch <= 0 {
+		batch = 1
+	}
+
+	cost := 0.0
+	for _, step := range steps {
+		minutes := step.RunMinutes + step.SetupMinutes/batch
+		cost += minutes / 60 * step.Operation.HourlyRate
+	}
+
+	return roundCost(cost), nil
+}
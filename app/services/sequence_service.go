@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// Document types numbered by the sequence service, matching the keys of app.sequences
+const (
+	SequenceOrderFabrication       = "order_fabrication"
+	SequenceStockRequest           = "stock_request"
+	SequenceStockTransfer          = "stock_transfer"
+	SequenceInventoryCount         = "inventory_count"
+	SequenceStockLot               = "stock_lot"
+	SequenceSerialNumber           = "serial_number"
+	SequenceDelivery               = "delivery"
+	SequenceEngineeringChangeOrder = "engineering_change_order"
+)
+
+var sequenceTokenPattern = regexp.MustCompile(`\{(YYYY|YY|MM|DD|seq(?::(\d+))?)\}`)
+
+var (
+	ErrSequencePatternToken   = errors.New("pattern contains an unknown token")
+	ErrSequencePatternCounter = errors.New("pattern must contain a {seq} counter")
+	ErrSequencePatternYear    = errors.New("pattern must contain a {YYYY} or {YY} token, as counters restart every year")
+)
+
+type SequenceService struct {
+}
+
+func NewSequenceService() *SequenceService {
+	return &SequenceService{}
+}
+
+// Next returns the next number for a document type in its own transaction
+func (s *SequenceService) Next(key string) (string, error) {
+	var number string
+	err := facades.Orm().Transaction(func(tx orm.Query) error {
+		var err error
+		number, err = s.NextTx(tx, key)
+		return err
+	})
+
+	return number, err
+}
+
+// NextTx returns the next number for a document type inside the caller's transaction.
+// The counter row stays locked until the transaction ends, so numbers are gap free and
+// unique under concurrent inserts.
+func (s *SequenceService) NextTx(tx orm.Query, key string) (string, error) {
+	pattern := facades.Config().GetString("app.sequences." + key)
+	if pattern == "" {
+		return "", fmt.Errorf("no sequence pattern configured for %s", key)
+	}
+
+	return s.NextPatternTx(tx, key, pattern)
+}
+
+// NextPatternTx is NextTx with a pattern given by the caller rather than configured, the
+// key naming the counter
+func (s *SequenceService) NextPatternTx(tx orm.Query, key string, pattern string) (string, error) {
+	now := time.Now()
+	period := now.Format("2006")
+
+	if err := s.ensureCounter(key, period); err != nil {
+		return "", err
+	}
+
+	var sequence models.DocumentSequence
+	if err := tx.LockForUpdate().Where("document_type", key).Where("period", period).FirstOrFail(&sequence); err != nil {
+		return "", err
+	}
+
+	sequence.LastValue++
+	if _, err := tx.Model(&models.DocumentSequence{}).Where("id", sequence.ID).Update("last_value", sequence.LastValue); err != nil {
+		return "", err
+	}
+
+	return FormatSequence(pattern, now, sequence.LastValue), nil
+}
+
+// ensureCounter creates the counter row for a period outside the caller's transaction,
+// so a concurrent duplicate insert cannot abort it
+func (s *SequenceService) ensureCounter(key, period string) error {
+	exists, err := facades.Orm().Query().Model(&models.DocumentSequence{}).Where("document_type", key).Where("period", period).Exists()
+	if err != nil || exists {
+		return err
+	}
+
+	sequence := models.DocumentSequence{DocumentType: key, Period: period}
+	if err := facades.Orm().Query().Create(&sequence); err != nil {
+		// Another request may have created the row in the meantime
+		exists, existsErr := facades.Orm().Query().Model(&models.DocumentSequence{}).Where("document_type", key).Where("period", period).Exists()
+		if existsErr != nil || !exists {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateSequencePattern checks that a number pattern only uses the tokens FormatSequence
+// renders, with a counter and a year, so the numbers stay unique once the counter restarts
+func ValidateSequencePattern(pattern string) error {
+	counter, year := false, false
+	for _, match := range sequenceTokenPattern.FindAllStringSubmatch(pattern, -1) {
+		switch match[1] {
+		case "YYYY", "YY":
+			year = true
+		case "MM", "DD":
+		default:
+			counter = true
+		}
+	}
+	if strings.ContainsAny(sequenceTokenPattern.ReplaceAllString(pattern, ""), "{}") {
+		return ErrSequencePatternToken
+	}
+	if !counter {
+		return ErrSequencePatternCounter
+	}
+	if !year {
+		return ErrSequencePatternYear
+	}
+
+	return nil
+}
+
+// FormatSequence renders a number pattern for the given date and counter value
+func FormatSequence(pattern string, at time.Time, value int64) string {
+	return sequenceTokenPattern.ReplaceAllStringFunc(pattern, func(token string) string {
+		match := sequenceTokenPattern.FindStringSubmatch(token)
+		switch match[1] {
+		case "YYYY":
+			return at.Format("2006")
+		case "YY":
+			return at.Format("06")
+		case "MM":
+			return at.Format("01")
+		case "DD":
+			return at.Format("02")
+		}
+
+		width, _ := strconv.Atoi(match[2])
+		return fmt.Sprintf("%0*d", width, value)
+	})
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSequencePattern(t *testing.T) {
+	assert.NoError(t, ValidateSequencePattern("SN-{YYYY}-{seq:6}"))
+	assert.NoError(t, ValidateSequencePattern("{YY}{MM}{DD}{seq}"))
+
+	assert.ErrorIs(t, ValidateSequencePattern("SN-{seq:6}"), ErrSequencePatternYear)
+	assert.ErrorIs(t, ValidateSequencePattern("SN-{YYYY}"), ErrSequencePatternCounter)
+	assert.ErrorIs(t, ValidateSequencePattern("SN-{YYYY}-{seqX}"), ErrSequencePatternToken)
+	assert.ErrorIs(t, ValidateSequencePattern("SN-{YYYY}-{seq:}"), ErrSequencePatternToken)
+	assert.ErrorIs(t, ValidateSequencePattern("SN-{YYYY}-{seq"), ErrSequencePatternToken)
+	assert.ErrorIs(t, ValidateSequencePattern("SN-{yyyy}-{seq}"), ErrSequencePatternToken)
+}
+
+func TestFormatSequence(t *testing.T) {
+	at := time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "SN-2026-000042", FormatSequence("SN-{YYYY}-{seq:6}", at, 42))
+	assert.Equal(t, "260307-7", FormatSequence("{YY}{MM}{DD}-{seq}", at, 7))
+}
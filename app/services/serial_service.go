@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// Serial number statuses
+const (
+	SerialStatusInStock   = "in_stock"
+	SerialStatusDelivered = "delivered"
+)
+
+// ReferenceTypeDelivery tags the movements shipping goods to a client site
+const ReferenceTypeDelivery = "delivery"
+
+var (
+	ErrEmptyDelivery  = errors.New("a delivery needs at least one serial number")
+	ErrSerialNotFound = errors.New("serial number does not exist")
+)
+
+// SerialNotAvailableError is returned when a delivered serial number is not in stock
+type SerialNotAvailableError struct {
+	SerialNumber string
+	Status       string
+}
+
+func (e *SerialNotAvailableError) Error() string {
+	return fmt.Sprintf("serial number %s is %s", e.SerialNumber, e.Status)
+}
+
+// SerialTrace links a serial number to the order that made it, its operators and material lots
+type SerialTrace struct {
+	Serial    models.SerialNumber          `json:"serial_number"`
+	Operators []models.ProductionOfHistory `json:"operators"`
+	Lots      []LotUsage                   `json:"lots"`
+}
+
+type SerialService struct {
+	stockService    *StockService
+	sequenceService *SequenceService
+	lotService      *LotService
+}
+
+func NewSerialService() *SerialService {
+	return &SerialService{
+		stockService:    NewStockService(),
+		sequenceService: NewSequenceService(),
+		lotService:      NewLotService(),
+	}
+}
+
+// NumberTx gives a serial number to every unit of a serialised product received by a
+// completed order
+func (s *SerialService) NumberTx(tx orm.Query, order *models.OrderFabrication, product models.Product, movement *models.StockMovement) ([]models.SerialNumber, error) {
+	// Products with their own pattern keep their own counter
+	key, pattern := SequenceSerialNumber, facades.Config().GetString("app.sequences."+SequenceSerialNumber)
+	if product.SerialPattern != "" {
+		key, pattern = fmt.Sprintf("%s_%d", SequenceSerialNumber, product.ID), product.SerialPattern
+	}
+
+	locationID := movement.LocationID
+	serials := make([]models.SerialNumber, 0, int(order.Quantity))
+	for i := 0; i < int(order.Quantity); i++ {
+		number, err := s.sequenceService.NextPatternTx(tx, key, pattern)
+		if err != nil {
+			return nil, err
+		}
+		serial := models.SerialNumber{
+			SerialNumber:       number,
+			ProductID:          order.ProductID,
+			VariantID:          order.VariantID,
+			OrderFabricationID: order.ID,
+			MovementID:         movement.ID,
+			LocationID:         &locationID,
+			Status:             SerialStatusInStock,
+		}
+		if err := tx.Create(&serial); err != nil {
+			return nil, err
+		}
+		serials = append(serials, serial)
+	}
+
+	return serials, nil
+}
+
+// Deliver ships serial numbers in stock to a client site: one out movement is posted per
+// product, variant and location, and every serial is attached to the delivery
+func (s *SerialService) Deliver(delivery *models.Delivery, serialIDs []uint, user models.User) error {
+	if len(serialIDs) == 0 {
+		return ErrEmptyDelivery
+	}
+
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		number, err := s.sequenceService.NextTx(tx, SequenceDelivery)
+		if err != nil {
+			return err
+		}
+		delivery.DeliveryNumber = number
+		delivery.DeliveredAt = time.Now()
+		delivery.CreatedBy = user.ID
+		if err := tx.Create(delivery); err != nil {
+			return err
+		}
+
+		var serials []models.SerialNumber
+		if err := tx.LockForUpdate().Where("id IN ?", serialIDs).OrderBy("id").Find(&serials); err != nil {
+			return err
+		}
+		if len(serials) != len(serialIDs) {
+			return ErrSerialNotFound
+		}
+
+		// Group the serials sharing a stock level into one movement
+		type group struct {
+			productID  uint
+			variantID  *uint
+			locationID uint
+			serials    []models.SerialNumber
+		}
+		groups := []*group{}
+		index := map[stockKey]*group{}
+		for _, serial := range serials {
+			if serial.Status != SerialStatusInStock || serial.LocationID == nil {
+				return &SerialNotAvailableError{SerialNumber: serial.SerialNumber, Status: serial.Status}
+			}
+			key := newStockKey(serial.ProductID, serial.VariantID, *serial.LocationID)
+			if index[key] == nil {
+				index[key] = &group{productID: serial.ProductID, variantID: serial.VariantID, locationID: *serial.LocationID}
+				groups = append(groups, index[key])
+			}
+			index[key].serials = append(index[key].serials, serial)
+		}
+
+		deliveryID := delivery.ID
+		for _, group := range groups {
+			movement := models.StockMovement{
+				ProductID:     group.productID,
+				VariantID:     group.variantID,
+				LocationID:    group.locationID,
+				MovementType:  MovementTypeOut,
+				Quantity:      float64(len(group.serials)),
+				ReferenceType: ReferenceTypeDelivery,
+				ReferenceID:   &deliveryID,
+				Notes:         "Delivered by " + delivery.DeliveryNumber,
+				CreatedBy:     user.ID,
+			}
+			if _, err := s.stockService.PostMovementTx(tx, &movement); err != nil {
+				return err
+			}
+
+			ids := make([]uint, 0, len(group.serials))
+			for _, serial := range group.serials {
+				ids = append(ids, serial.ID)
+			}
+			if _, err := tx.Model(&models.SerialNumber{}).Where("id IN ?", ids).Update(map[string]any{
+				"status":               SerialStatusDelivered,
+				"delivery_id":          deliveryID,
+				"delivery_movement_id": movement.ID,
+				"location_id":          nil,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Trace returns the operators who moved the order of a serial number through production
+// and the material lots it consumed. The serial must have its OrderFabrication loaded.
+func (s *SerialService) Trace(serial models.SerialNumber) (*SerialTrace, error) {
+	trace := &SerialTrace{Serial: serial}
+
+	if err := facades.Orm().Query().With("User").Where("order_fabrication_id", serial.OrderFabricationID).
+		OrderBy("status_at").Find(&trace.Operators); err != nil {
+		return nil, err
+	}
+
+	lots, err := s.lotService.OrderLots(serial.OrderFabrication)
+	if err != nil {
+		return nil, err
+	}
+	trace.Lots = lots
+
+	return trace, nil
+}
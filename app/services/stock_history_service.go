@@ -0,0 +1,514 @@
+package services
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// StockSnapshotFilter restricts a replay to a product, variant or location
+type StockSnapshotFilter struct {
+	ProductID  *uint
+	VariantID  *uint
+	LocationID *uint
+}
+
+// StockSnapshotLine is the replayed quantity of a product or variant at a location
+type StockSnapshotLine struct {
+	ProductID  uint    `json:"product_id"`
+	VariantID  *uint   `json:"variant_id"`
+	LocationID uint    `json:"location_id"`
+	Quantity   float64 `json:"quantity"`
+	Movements  int64   `json:"movements"`
+}
+
+// StockDrift is a stock level whose quantity differs from the movement ledger, whose cost
+// layers hold more than the ledger quantity, or whose lot balances differ from the lot ledger
+type StockDrift struct {
+	StockLevelID  uint       `json:"stock_level_id"` // zero when the level row is missing
+	ProductID     uint       `json:"product_id"`
+	VariantID     *uint      `json:"variant_id"`
+	LocationID    uint       `json:"location_id"`
+	Recorded      float64    `json:"recorded"`
+	Replayed      float64    `json:"replayed"`
+	Difference    float64    `json:"difference"`
+	LayerQuantity float64    `json:"layer_quantity"` // remaining on the FIFO cost layers
+	Lots          []LotDrift `json:"lots"`
+}
+
+// LotDrift is a lot balance that differs from the lots recorded on the movements
+type LotDrift struct {
+	LotID      uint    `json:"lot_id"`
+	Recorded   float64 `json:"recorded"`
+	Replayed   float64 `json:"replayed"`
+	Difference float64 `json:"difference"`
+}
+
+// lotLine is the replayed quantity of a lot at a location
+type lotLine struct {
+	LotID      uint
+	ProductID  uint
+	VariantID  uint
+	LocationID uint
+	Quantity   float64
+}
+
+// stockKey identifies the stock of a product or variant at a location
+type stockKey struct {
+	productID  uint
+	variantID  uint
+	locationID uint
+}
+
+func newStockKey(productID uint, variantID *uint, locationID uint) stockKey {
+	key := stockKey{productID: productID, locationID: locationID}
+	if variantID != nil {
+		key.variantID = *variantID
+	}
+
+	return key
+}
+
+type StockHistoryService struct {
+}
+
+func NewStockHistoryService() *StockHistoryService {
+	return &StockHistoryService{}
+}
+
+// Snapshot replays the movement ledger up to the given time and returns the quantity of
+// every product or variant per location. Lines back to zero are left out.
+func (s *StockHistoryService) Snapshot(at time.Time, filter StockSnapshotFilter) ([]StockSnapshotLine, error) {
+	lines, err := s.replay(facades.Orm().Query(), &at, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := make([]StockSnapshotLine, 0, len(lines))
+	for _, line := range lines {
+		if line.Quantity != 0 {
+			snapshot = append(snapshot, line)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// CheckConsistency compares the stock levels with a full replay of the movement ledger
+// and returns the levels that drifted, including ledger stock without a level row. Levels
+// whose cost layers hold more than the ledger quantity, or whose lot balances differ from
+// the lots recorded on the movements, are reported as well.
+func (s *StockHistoryService) CheckConsistency() ([]StockDrift, error) {
+	query := facades.Orm().Query()
+
+	lines, err := s.replay(query, nil, StockSnapshotFilter{})
+	if err != nil {
+		return nil, err
+	}
+	replayed := map[stockKey]StockSnapshotLine{}
+	for _, line := range lines {
+		replayed[newStockKey(line.ProductID, line.VariantID, line.LocationID)] = line
+	}
+
+	var layers []struct {
+		StockLevelID uint
+		Quantity     float64
+	}
+	if err := query.Model(&models.StockCostLayer{}).Select("stock_level_id, SUM(remaining_quantity) AS quantity").
+		Where("remaining_quantity > ?", 0).GroupBy("stock_level_id").Scan(&layers); err != nil {
+		return nil, err
+	}
+	layered := map[uint]float64{}
+	for _, layer := range layers {
+		layered[layer.StockLevelID] = roundQuantity(layer.Quantity)
+	}
+
+	lots, err := s.lotDrifts(query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var levels []models.StockLevel
+	if err := query.OrderBy("id").Find(&levels); err != nil {
+		return nil, err
+	}
+
+	drifts := []StockDrift{}
+	seen := map[stockKey]bool{}
+	for _, level := range levels {
+		key := newStockKey(level.ProductID, level.VariantID, level.LocationID)
+		seen[key] = true
+		quantity := replayed[key].Quantity
+		if roundQuantity(level.Quantity-quantity) == 0 && layered[level.ID] <= quantity && len(lots[key]) == 0 {
+			continue
+		}
+		drifts = append(drifts, StockDrift{
+			StockLevelID:  level.ID,
+			ProductID:     level.ProductID,
+			VariantID:     level.VariantID,
+			LocationID:    level.LocationID,
+			Recorded:      level.Quantity,
+			Replayed:      quantity,
+			Difference:    roundQuantity(level.Quantity - quantity),
+			LayerQuantity: layered[level.ID],
+			Lots:          lots[key],
+		})
+	}
+	for _, line := range lines {
+		key := newStockKey(line.ProductID, line.VariantID, line.LocationID)
+		if seen[key] || (line.Quantity == 0 && len(lots[key]) == 0) {
+			continue
+		}
+		seen[key] = true
+		drifts = append(drifts, StockDrift{
+			ProductID:  line.ProductID,
+			VariantID:  line.VariantID,
+			LocationID: line.LocationID,
+			Replayed:   line.Quantity,
+			Difference: -line.Quantity,
+			Lots:       lots[key],
+		})
+	}
+	// Lot balances left on a location the variant has no ledger stock at
+	var orphans []stockKey
+	for key := range lots {
+		if !seen[key] {
+			orphans = append(orphans, key)
+		}
+	}
+	slices.SortFunc(orphans, func(a, b stockKey) int {
+		return cmp.Or(cmp.Compare(a.locationID, b.locationID), cmp.Compare(a.productID, b.productID), cmp.Compare(a.variantID, b.variantID))
+	})
+	for _, key := range orphans {
+		variantID := key.variantID
+		drifts = append(drifts, StockDrift{
+			ProductID:  key.productID,
+			VariantID:  &variantID,
+			LocationID: key.locationID,
+			Lots:       lots[key],
+		})
+	}
+
+	return drifts, nil
+}
+
+// Repair realigns the drifted stock levels on the movement ledger, which stays the source
+// of truth, and returns the drifts corrected. Each level is replayed again under lock so
+// movements posted since the check are taken into account. The FIFO cost layers are trimmed
+// to the corrected quantity, oldest first, and the lot balances rebuilt from the lots
+// recorded on the movements, in the same transaction.
+func (s *StockHistoryService) Repair() ([]StockDrift, error) {
+	drifts, err := s.CheckConsistency()
+	if err != nil {
+		return nil, err
+	}
+
+	repaired := []StockDrift{}
+	for _, drift := range drifts {
+		err := facades.Orm().Transaction(func(tx orm.Query) error {
+			var level models.StockLevel
+			levels := tx.LockForUpdate().Where("product_id", drift.ProductID).Where("location_id", drift.LocationID)
+			if drift.VariantID != nil {
+				levels = levels.Where("variant_id", *drift.VariantID)
+			} else {
+				levels = levels.WhereNull("variant_id")
+			}
+			if err := levels.First(&level); err != nil {
+				return err
+			}
+
+			lines, err := s.replay(tx, nil, StockSnapshotFilter{
+				ProductID:  &drift.ProductID,
+				VariantID:  drift.VariantID,
+				LocationID: &drift.LocationID,
+			})
+			if err != nil {
+				return err
+			}
+			quantity := 0.0
+			for _, line := range lines {
+				if (line.VariantID == nil) == (drift.VariantID == nil) {
+					quantity = line.Quantity
+				}
+			}
+
+			drift.StockLevelID = level.ID
+			drift.Recorded = level.Quantity
+			drift.Replayed = quantity
+			drift.Difference = roundQuantity(level.Quantity - quantity)
+
+			if drift.Difference != 0 {
+				// Keep the valuation of the level consistent with the corrected quantity
+				averageCost := level.AverageCost
+				if averageCost == 0 && level.Quantity > 0 {
+					averageCost = roundCost(level.TotalValue / level.Quantity)
+				}
+				totalValue := roundCost(quantity * averageCost)
+				if quantity <= 0 {
+					totalValue = 0
+				}
+
+				if level.ID == 0 {
+					level = models.StockLevel{
+						ProductID:   drift.ProductID,
+						VariantID:   drift.VariantID,
+						LocationID:  drift.LocationID,
+						Quantity:    quantity,
+						AverageCost: averageCost,
+						TotalValue:  totalValue,
+					}
+					if err := tx.Create(&level); err != nil {
+						return err
+					}
+					drift.StockLevelID = level.ID
+				} else if _, err := tx.Model(&models.StockLevel{}).Where("id", level.ID).Update(map[string]any{
+					"quantity":     quantity,
+					"average_cost": averageCost,
+					"total_value":  totalValue,
+				}); err != nil {
+					return err
+				}
+			}
+
+			layerQuantity, trimmed, err := s.trimLayersTx(tx, level.ID, quantity)
+			if err != nil {
+				return err
+			}
+			drift.LayerQuantity = layerQuantity
+
+			drift.Lots = nil
+			if drift.VariantID != nil {
+				drift.Lots, err = s.rebuildLotsTx(tx, *drift.VariantID, drift.LocationID)
+				if err != nil {
+					return err
+				}
+			}
+
+			if drift.Difference == 0 && !trimmed && len(drift.Lots) == 0 {
+				return nil
+			}
+			repaired = append(repaired, drift)
+			return nil
+		})
+		if err != nil {
+			return repaired, err
+		}
+	}
+
+	return repaired, nil
+}
+
+// trimLayersTx lowers the remaining quantity of the cost layers of a level so they do not
+// hold more than the level quantity, and returns what they hold afterwards and whether
+// any layer changed
+func (s *StockHistoryService) trimLayersTx(tx orm.Query, levelID uint, quantity float64) (float64, bool, error) {
+	if levelID == 0 {
+		return 0, false, nil
+	}
+
+	var layers []models.StockCostLayer
+	if err := tx.LockForUpdate().Where("stock_level_id", levelID).Where("remaining_quantity > ?", 0).
+		OrderBy("id").Find(&layers); err != nil {
+		return 0, false, err
+	}
+
+	trimmed := trimLayers(layers, quantity)
+	for _, layer := range trimmed {
+		if _, err := tx.Model(&models.StockCostLayer{}).Where("id", layer.ID).Update("remaining_quantity", layer.RemainingQuantity); err != nil {
+			return 0, false, err
+		}
+	}
+
+	remaining := 0.0
+	for _, layer := range layers {
+		remaining += layer.RemainingQuantity
+	}
+	for _, layer := range trimmed {
+		remaining -= layer.Quantity
+	}
+
+	return roundQuantity(remaining), len(trimmed) > 0, nil
+}
+
+// trimLayers consumes the excess of the layers over the quantity from the oldest ones, as
+// the issues missing from them would have, and returns the layers changed with their new
+// remaining quantity. Quantity holds what each of them lost.
+func trimLayers(layers []models.StockCostLayer, quantity float64) []models.StockCostLayer {
+	excess := -math.Max(quantity, 0)
+	for _, layer := range layers {
+		excess += layer.RemainingQuantity
+	}
+	excess = roundQuantity(excess)
+
+	var trimmed []models.StockCostLayer
+	for _, layer := range layers {
+		if excess <= 0 {
+			break
+		}
+		take := roundQuantity(math.Min(excess, layer.RemainingQuantity))
+		if take <= 0 {
+			continue
+		}
+		trimmed = append(trimmed, models.StockCostLayer{
+			Model:             layer.Model,
+			RemainingQuantity: roundQuantity(layer.RemainingQuantity - take),
+			Quantity:          take,
+		})
+		excess = roundQuantity(excess - take)
+	}
+
+	return trimmed
+}
+
+// rebuildLotsTx sets the lot balances of a variant at a location to the lots recorded on
+// its movements and returns the balances corrected
+func (s *StockHistoryService) rebuildLotsTx(tx orm.Query, variantID uint, locationID uint) ([]LotDrift, error) {
+	var balances []models.StockLotBalance
+	if err := tx.LockForUpdate().Where("location_id", locationID).
+		Where("lot_id IN (SELECT id FROM stock_lots WHERE variant_id = ?)", variantID).
+		Find(&balances); err != nil {
+		return nil, err
+	}
+	rows := map[uint]uint{}
+	for _, balance := range balances {
+		rows[balance.LotID] = balance.ID
+	}
+
+	drifted, err := s.lotDrifts(tx, &variantID, &locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	lots := []LotDrift{}
+	for _, drifts := range drifted {
+		for _, drift := range drifts {
+			if id, ok := rows[drift.LotID]; ok {
+				if _, err := tx.Model(&models.StockLotBalance{}).Where("id", id).Update("quantity", drift.Replayed); err != nil {
+					return nil, err
+				}
+			} else {
+				balance := models.StockLotBalance{LotID: drift.LotID, LocationID: locationID, Quantity: drift.Replayed}
+				if err := tx.Create(&balance); err != nil {
+					return nil, err
+				}
+			}
+			lots = append(lots, drift)
+		}
+	}
+
+	return lots, nil
+}
+
+// lotDrifts replays the lots recorded on the movements, optionally for a variant and a
+// location, and returns the lot balances that differ from them per variant and location
+func (s *StockHistoryService) lotDrifts(query orm.Query, variantID *uint, locationID *uint) (map[stockKey][]LotDrift, error) {
+	allocations := query.Model(&models.StockMovementLot{}).
+		Select("stock_lots.id AS lot_id, stock_lots.product_id, stock_lots.variant_id, stock_movements.location_id, " +
+			"SUM(CASE WHEN stock_movements.movement_type = 'out' OR stock_movements.quantity < 0 " +
+			"THEN -stock_movement_lots.quantity ELSE stock_movement_lots.quantity END) AS quantity").
+		Join("JOIN stock_movements ON stock_movements.id = stock_movement_lots.movement_id").
+		Join("JOIN stock_lots ON stock_lots.id = stock_movement_lots.lot_id")
+	balances := query.With("Lot")
+	if variantID != nil {
+		allocations = allocations.Where("stock_lots.variant_id = ?", *variantID)
+		balances = balances.Where("lot_id IN (SELECT id FROM stock_lots WHERE variant_id = ?)", *variantID)
+	}
+	if locationID != nil {
+		allocations = allocations.Where("stock_movements.location_id = ?", *locationID)
+		balances = balances.Where("location_id", *locationID)
+	}
+
+	var lines []lotLine
+	if err := allocations.GroupBy("stock_lots.id", "stock_lots.product_id", "stock_lots.variant_id", "stock_movements.location_id").
+		OrderBy("stock_lots.id").Scan(&lines); err != nil {
+		return nil, err
+	}
+	var recorded []models.StockLotBalance
+	if err := balances.OrderBy("lot_id").Find(&recorded); err != nil {
+		return nil, err
+	}
+
+	return compareLots(lines, recorded), nil
+}
+
+// compareLots matches the replayed lot quantities with the recorded balances and returns
+// the differences per variant and location. A balance without movements should be empty.
+func compareLots(lines []lotLine, balances []models.StockLotBalance) map[stockKey][]LotDrift {
+	type lotKey struct {
+		lotID      uint
+		locationID uint
+	}
+
+	recorded := map[lotKey]float64{}
+	for _, balance := range balances {
+		recorded[lotKey{balance.LotID, balance.LocationID}] = balance.Quantity
+	}
+
+	drifts := map[stockKey][]LotDrift{}
+	add := func(productID, variantID, locationID, lotID uint, quantity, replayed float64) {
+		if roundQuantity(quantity-replayed) == 0 {
+			return
+		}
+		key := newStockKey(productID, &variantID, locationID)
+		drifts[key] = append(drifts[key], LotDrift{
+			LotID:      lotID,
+			Recorded:   quantity,
+			Replayed:   replayed,
+			Difference: roundQuantity(quantity - replayed),
+		})
+	}
+
+	seen := map[lotKey]bool{}
+	for _, line := range lines {
+		key := lotKey{line.LotID, line.LocationID}
+		seen[key] = true
+		add(line.ProductID, line.VariantID, line.LocationID, line.LotID, recorded[key], roundQuantity(line.Quantity))
+	}
+	for _, balance := range balances {
+		if seen[lotKey{balance.LotID, balance.LocationID}] {
+			continue
+		}
+		add(balance.Lot.ProductID, balance.Lot.VariantID, balance.LocationID, balance.LotID, balance.Quantity, 0)
+	}
+
+	return drifts
+}
+
+// replay sums the signed movement quantities per product, variant and location, up to the
+// given time when set
+func (s *StockHistoryService) replay(query orm.Query, at *time.Time, filter StockSnapshotFilter) ([]StockSnapshotLine, error) {
+	movements := query.Model(&models.StockMovement{}).
+		Select("product_id, variant_id, location_id, " +
+			"SUM(CASE WHEN movement_type = 'out' THEN -quantity ELSE quantity END) AS quantity, " +
+			"COUNT(*) AS movements")
+	if at != nil {
+		movements = movements.Where("created_at <= ?", *at)
+	}
+	if filter.ProductID != nil {
+		movements = movements.Where("product_id", *filter.ProductID)
+	}
+	if filter.VariantID != nil {
+		movements = movements.Where("variant_id", *filter.VariantID)
+	}
+	if filter.LocationID != nil {
+		movements = movements.Where("location_id", *filter.LocationID)
+	}
+
+	var lines []StockSnapshotLine
+	if err := movements.GroupBy("product_id", "variant_id", "location_id").
+		OrderBy("location_id").OrderBy("product_id").OrderBy("variant_id").
+		Scan(&lines); err != nil {
+		return nil, err
+	}
+	for i := range lines {
+		lines[i].Quantity = roundQuantity(lines[i].Quantity)
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/goravel/framework/database/orm"
+	"github.com/stretchr/testify/assert"
+
+	"pms/app/models"
+)
+
+func TestTrimLayersConsumesOldestFirst(t *testing.T) {
+	layers := []models.StockCostLayer{
+		{Model: orm.Model{ID: 1}, RemainingQuantity: 4},
+		{Model: orm.Model{ID: 2}, RemainingQuantity: 5},
+		{Model: orm.Model{ID: 3}, RemainingQuantity: 3},
+	}
+
+	trimmed := trimLayers(layers, 6)
+	assert.Len(t, trimmed, 2)
+	assert.Equal(t, uint(1), trimmed[0].ID)
+	assert.Equal(t, 0.0, trimmed[0].RemainingQuantity)
+	assert.Equal(t, uint(2), trimmed[1].ID)
+	assert.Equal(t, 3.0, trimmed[1].RemainingQuantity)
+	assert.Equal(t, 2.0, trimmed[1].Quantity)
+
+	// Layers holding less than the level are left alone, the rest is costed at average
+	assert.Empty(t, trimLayers(layers, 20))
+
+	// A level replayed below zero empties every layer
+	assert.Len(t, trimLayers(layers, -1), 3)
+}
+
+func TestCompareLots(t *testing.T) {
+	lines := []lotLine{
+		{LotID: 1, ProductID: 10, VariantID: 20, LocationID: 5, Quantity: 8},
+		{LotID: 2, ProductID: 10, VariantID: 20, LocationID: 5, Quantity: 3},
+		{LotID: 3, ProductID: 10, VariantID: 20, LocationID: 5, Quantity: 2},
+	}
+	balances := []models.StockLotBalance{
+		{LotID: 1, LocationID: 5, Quantity: 8},
+		{LotID: 2, LocationID: 5, Quantity: 4.5},
+		{LotID: 4, LocationID: 6, Quantity: 1, Lot: models.StockLot{ProductID: 10, VariantID: 20}},
+	}
+
+	variantID := uint(20)
+	drifts := compareLots(lines, balances)
+	assert.Len(t, drifts, 2)
+	assert.Equal(t, []LotDrift{
+		{LotID: 2, Recorded: 4.5, Replayed: 3, Difference: 1.5},
+		{LotID: 3, Recorded: 0, Replayed: 2, Difference: -2},
+	}, drifts[newStockKey(10, &variantID, 5)])
+	assert.Equal(t, []LotDrift{
+		{LotID: 4, Recorded: 1, Replayed: 0, Difference: 1},
+	}, drifts[newStockKey(10, &variantID, 6)])
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// Stock request statuses
+const (
+	StockRequestStatusPending   = "pending"
+	StockRequestStatusApproved  = "approved"
+	StockRequestStatusRejected  = "rejected"
+	StockRequestStatusFulfilled = "fulfilled"
+)
+
+// Reasons a stock request can be rejected for
+const (
+	RejectionReasonShortage = "shortage"
+	RejectionReasonOther    = "other"
+)
+
+// ReferenceTypeStockRequest tags the stock movements posted when a request is fulfilled
+const ReferenceTypeStockRequest = "stock_request"
+
+// InvalidRequestStatusError is returned when a stock request is not in the status an action requires
+type InvalidRequestStatusError struct {
+	Status   string
+	Expected string
+}
+
+func (e *InvalidRequestStatusError) Error() string {
+	return fmt.Sprintf("stock request is %s, expected %s", e.Status, e.Expected)
+}
+
+// stockRequestPriorities maps manufacturing order priorities onto stock request priorities
+var stockRequestPriorities = map[string]string{
+	"low":    "low",
+	"normal": "medium",
+	"high":   "high",
+	"urgent": "urgent",
+}
+
+type StockRequestService struct {
+	sequenceService *SequenceService
+	stockService    *StockService
+}
+
+func NewStockRequestService() *StockRequestService {
+	return &StockRequestService{
+		sequenceService: NewSequenceService(),
+		stockService:    NewStockService(),
+	}
+}
+
+// Create numbers and stores a manually raised stock request
+func (s *StockRequestService) Create(request *models.StockRequest) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		requestNumber, err := s.sequenceService.NextTx(tx, SequenceStockRequest)
+		if err != nil {
+			return err
+		}
+		request.RequestNumber = requestNumber
+		request.Status = StockRequestStatusPending
+		request.RequestedAt = time.Now()
+
+		return tx.Create(request)
+	})
+}
+
+// Approve marks a pending request as approved by the given user
+func (s *StockRequestService) Approve(request *models.StockRequest, user models.User) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := s.lockWithStatus(tx, request, StockRequestStatusPending); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		request.Status = StockRequestStatusApproved
+		request.ApprovedBy = &user.ID
+		request.ApprovedAt = &now
+
+		return tx.Save(request)
+	})
+}
+
+// Reject marks a pending request as rejected. Requests rejected for shortage are
+// picked up by the achat role for purchasing.
+func (s *StockRequestService) Reject(request *models.StockRequest, user models.User, reason string, notes string) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := s.lockWithStatus(tx, request, StockRequestStatusPending); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		request.Status = StockRequestStatusRejected
+		request.ApprovedBy = &user.ID
+		request.ApprovedAt = &now
+		request.RejectionReason = reason
+		request.RejectionNotes = notes
+
+		return tx.Save(request)
+	})
+}
+
+// Fulfil serves an approved request: it posts an out movement from the request's location,
+// updates the stock level and closes the request in a single transaction. A request raised
+// for a material requirement issues the material to its order: the movement references the
+// order and the quantity is booked as consumed on the requirement.
+func (s *StockRequestService) Fulfil(request *models.StockRequest, user models.User) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := s.lockWithStatus(tx, request, StockRequestStatusApproved); err != nil {
+			return err
+		}
+
+		var requirement models.ProductionMaterialRequirement
+		if request.RequirementID != nil {
+			if err := tx.LockForUpdate().With("OrderFabrication").Where("id", *request.RequirementID).FirstOrFail(&requirement); err != nil {
+				return err
+			}
+		}
+
+		requestID := request.ID
+		movement := models.StockMovement{
+			ProductID:     request.ProductID,
+			VariantID:     request.VariantID,
+			LocationID:    request.LocationID,
+			MovementType:  MovementTypeOut,
+			Quantity:      request.Quantity,
+			Unit:          request.Unit,
+			ReferenceType: ReferenceTypeStockRequest,
+			ReferenceID:   &requestID,
+			Notes:         "Stock request " + request.RequestNumber,
+			CreatedBy:     user.ID,
+		}
+		if requirement.ID != 0 {
+			orderID := requirement.OrderFabricationID
+			movement.ReferenceType = ReferenceTypeOrderFabrication
+			movement.ReferenceID = &orderID
+			movement.Notes = "Stock request " + request.RequestNumber + " for " + requirement.OrderFabrication.OrderNumber
+		}
+		if _, err := s.stockService.PostMovementTx(tx, &movement); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		request.Status = StockRequestStatusFulfilled
+		request.FulfilledAt = &now
+		if err := tx.Save(request); err != nil {
+			return err
+		}
+
+		if requirement.ID == 0 {
+			return nil
+		}
+
+		// The served quantity is issued to the order. The requirement is consumed once fully
+		// issued, and covered once none of its requests is still open.
+		consumed := roundQuantity(requirement.ConsumedQuantity + request.Quantity)
+		updates := map[string]any{"consumed_quantity": consumed}
+		if consumed >= requirement.RequiredQuantity {
+			updates["status"] = RequirementStatusConsumed
+		} else if requirement.Status == RequirementStatusRequested {
+			open, err := tx.Model(&models.StockRequest{}).Where("requirement_id", requirement.ID).
+				Where("status IN ?", []string{StockRequestStatusPending, StockRequestStatusApproved}).Count()
+			if err != nil {
+				return err
+			}
+			if open == 0 {
+				updates["status"] = RequirementStatusAvailable
+			}
+		}
+		_, err := tx.Model(&models.ProductionMaterialRequirement{}).Where("id", requirement.ID).Update(updates)
+
+		return err
+	})
+}
+
+// lockWithStatus reloads a request under lock and checks its status
+func (s *StockRequestService) lockWithStatus(tx orm.Query, request *models.StockRequest, expected string) error {
+	if err := tx.LockForUpdate().Where("id", request.ID).FirstOrFail(request); err != nil {
+		return err
+	}
+	if request.Status != expected {
+		return &InvalidRequestStatusError{Status: request.Status, Expected: expected}
+	}
+
+	return nil
+}
+
+// GenerateForOrderTx creates or adjusts the stock requests covering the shortages of an
+// order's material requirements. Running it again after the requirements changed updates
+// the open requests instead of duplicating them.
+func (s *StockRequestService) GenerateForOrderTx(tx orm.Query, order *models.OrderFabrication, user models.User) ([]models.StockRequest, error) {
+	var requirements []models.ProductionMaterialRequirement
+	if err := tx.With("MaterialVariant").Where("order_fabrication_id", order.ID).Find(&requirements); err != nil {
+		return nil, err
+	}
+
+	priority := stockRequestPriorities[order.Priority]
+	if priority == "" {
+		priority = "medium"
+	}
+
+	generated := []models.StockRequest{}
+	for _, requirement := range requirements {
+		if requirement.Status == RequirementStatusConsumed {
+			continue
+		}
+
+		var requests []models.StockRequest
+		if err := tx.Where("requirement_id", requirement.ID).OrderBy("id").Find(&requests); err != nil {
+			return nil, err
+		}
+
+		covered, open := requestCoverage(requests)
+		outstanding := roundQuantity(requirement.RequestQuantity - covered)
+
+		switch {
+		case outstanding <= 0 && open != nil:
+			if _, err := tx.Delete(open); err != nil {
+				return nil, err
+			}
+			open = nil
+		case outstanding > 0 && open != nil:
+			open.Quantity = outstanding
+			open.Priority = priority
+			if err := tx.Save(open); err != nil {
+				return nil, err
+			}
+			generated = append(generated, *open)
+		case outstanding > 0:
+			locationID, err := s.sourceLocation(tx, requirement.MaterialVariant)
+			if err != nil {
+				return nil, err
+			}
+			if locationID == 0 {
+				// Without any known location the shortage stays visible on the requirement only
+				continue
+			}
+
+			requestNumber, err := s.sequenceService.NextTx(tx, SequenceStockRequest)
+			if err != nil {
+				return nil, err
+			}
+
+			requirementID := requirement.ID
+			variantID := requirement.MaterialVariantID
+			orderID := order.ID
+			request := models.StockRequest{
+				RequestNumber:      requestNumber,
+				OrderFabricationID: &orderID,
+				RequirementID:      &requirementID,
+				ProductID:          requirement.MaterialVariant.ProductID,
+				VariantID:          &variantID,
+				LocationID:         locationID,
+				Quantity:           outstanding,
+				Unit:               requirement.Unit,
+				Status:             StockRequestStatusPending,
+				Priority:           priority,
+				Notes:              "Material shortage for " + order.OrderNumber,
+				RequestedBy:        user.ID,
+				RequestedAt:        time.Now(),
+			}
+			if err := tx.Create(&request); err != nil {
+				return nil, err
+			}
+			open = &request
+			generated = append(generated, request)
+		}
+
+		// Keep the requirement status in line with its requests
+		var status string
+		if open != nil || covered > 0 {
+			status = RequirementStatusRequested
+		} else if requirement.RequestQuantity > 0 {
+			status = RequirementStatusPending
+		} else {
+			status = RequirementStatusAvailable
+		}
+		if status != requirement.Status {
+			if _, err := tx.Model(&models.ProductionMaterialRequirement{}).Where("id", requirement.ID).Update("status", status); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return generated, nil
+}
+
+// WithdrawForOrderTx deletes the pending stock requests of an order that no longer needs
+// its materials, such as an order sent back to pending or cancelled
+func (s *StockRequestService) WithdrawForOrderTx(tx orm.Query, order *models.OrderFabrication) error {
+	_, err := tx.Where("order_fabrication_id", order.ID).Where("status", StockRequestStatusPending).Delete(&models.StockRequest{})
+
+	return err
+}
+
+// requestCoverage returns the quantity of a requirement's requests that is not to be
+// requested again, and its pending request if any. Approved quantities are on their way and
+// quantities rejected for shortage are left to purchasing; served ones are booked as
+// consumed and so already left out of the request quantity.
+func requestCoverage(requests []models.StockRequest) (float64, *models.StockRequest) {
+	covered := 0.0
+	var open *models.StockRequest
+	for i := range requests {
+		switch requests[i].Status {
+		case StockRequestStatusPending:
+			open = &requests[i]
+		case StockRequestStatusApproved:
+			covered += requests[i].Quantity
+		case StockRequestStatusRejected:
+			if requests[i].RejectionReason == RejectionReasonShortage {
+				covered += requests[i].Quantity
+			}
+		}
+	}
+
+	return roundQuantity(covered), open
+}
+
+// sourceLocation picks the location a material is requested from: the product's default
+// location, otherwise the location holding the most stock of the variant
+func (s *StockRequestService) sourceLocation(tx orm.Query, variant models.ProductVariant) (uint, error) {
+	var product models.Product
+	if err := tx.Where("id", variant.ProductID).First(&product); err != nil {
+		return 0, err
+	}
+	if product.LocationID != nil {
+		return *product.LocationID, nil
+	}
+
+	var level models.StockLevel
+	if err := tx.Where("variant_id", variant.ID).OrderBy("quantity", "desc").First(&level); err != nil {
+		return 0, err
+	}
+
+	return level.LocationID, nil
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/goravel/framework/database/orm"
+	"github.com/stretchr/testify/assert"
+
+	"pms/app/models"
+)
+
+func TestRequestCoverage(t *testing.T) {
+	requests := []models.StockRequest{
+		{Model: orm.Model{ID: 1}, Status: StockRequestStatusApproved, Quantity: 2},
+		{Model: orm.Model{ID: 2}, Status: StockRequestStatusRejected, RejectionReason: RejectionReasonShortage, Quantity: 5},
+		{Model: orm.Model{ID: 3}, Status: StockRequestStatusRejected, RejectionReason: RejectionReasonOther, Quantity: 4},
+		{Model: orm.Model{ID: 4}, Status: StockRequestStatusFulfilled, Quantity: 3},
+		{Model: orm.Model{ID: 5}, Status: StockRequestStatusPending, Quantity: 1.5},
+	}
+
+	covered, open := requestCoverage(requests)
+	assert.Equal(t, 7.0, covered)
+	if assert.NotNil(t, open) {
+		assert.Equal(t, uint(5), open.ID)
+	}
+
+	// A shortage rejected for the whole quantity is not raised again
+	covered, open = requestCoverage(requests[1:2])
+	assert.Equal(t, 5.0, covered)
+	assert.Nil(t, open)
+}
@@ -0,0 +1,561 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// Stock movement types
+const (
+	MovementTypeIn         = "in"
+	MovementTypeOut        = "out"
+	MovementTypeAdjustment = "adjustment"
+)
+
+// Valuation methods used to cost stock issues, set by app.stock.valuation_method
+const (
+	ValuationWeightedAverage = "wac"
+	ValuationFIFO            = "fifo"
+)
+
+var (
+	ErrInvalidMovementType = errors.New("movement type must be in, out or adjustment")
+	ErrInvalidQuantity     = errors.New("quantity must be greater than zero")
+	ErrLotNotTracked       = errors.New("lots are only tracked on raw material variants")
+	ErrLotMismatch         = errors.New("lot does not belong to the moved variant")
+	ErrLotOverAllocated    = errors.New("lot quantities exceed the moved quantity")
+)
+
+// InsufficientStockError is returned when a movement would take a stock level below zero
+type InsufficientStockError struct {
+	ProductID  uint
+	VariantID  *uint
+	LocationID uint
+	Available  float64
+	Requested  float64
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock at location %d: %.3f available, %.3f requested", e.LocationID, e.Available, e.Requested)
+}
+
+// CountInProgressError is returned when a movement hits stock frozen by an open inventory count
+type CountInProgressError struct {
+	CountNumber string
+	LocationID  uint
+}
+
+func (e *CountInProgressError) Error() string {
+	return fmt.Sprintf("inventory count %s is in progress on location %d", e.CountNumber, e.LocationID)
+}
+
+// InsufficientLotError is returned when a movement takes more from a lot than it holds at a location
+type InsufficientLotError struct {
+	LotNumber  string
+	LocationID uint
+	Available  float64
+	Requested  float64
+}
+
+func (e *InsufficientLotError) Error() string {
+	return fmt.Sprintf("insufficient stock in lot %s at location %d: %.3f available, %.3f requested", e.LotNumber, e.LocationID, e.Available, e.Requested)
+}
+
+type StockService struct {
+	sequenceService *SequenceService
+	locationService *LocationService
+	unitService     *UnitService
+}
+
+func NewStockService() *StockService {
+	return &StockService{
+		sequenceService: NewSequenceService(),
+		locationService: NewLocationService(),
+		unitService:     NewUnitService(),
+	}
+}
+
+// PostMovement records a movement and applies it to its stock level in one transaction
+func (s *StockService) PostMovement(movement *models.StockMovement) (*models.StockLevel, error) {
+	var level *models.StockLevel
+	err := facades.Orm().Transaction(func(tx orm.Query) error {
+		var err error
+		level, err = s.PostMovementTx(tx, movement)
+		return err
+	})
+
+	return level, err
+}
+
+// PostMovementTx records a movement and applies it to the matching stock level inside the
+// caller's transaction. The level row is locked so concurrent postings cannot lose updates.
+// Adjustments carry a signed quantity, in and out movements a positive one.
+func (s *StockService) PostMovementTx(tx orm.Query, movement *models.StockMovement) (*models.StockLevel, error) {
+	var delta float64
+	switch movement.MovementType {
+	case MovementTypeIn:
+		delta = movement.Quantity
+	case MovementTypeOut:
+		delta = -movement.Quantity
+	case MovementTypeAdjustment:
+		delta = movement.Quantity
+	default:
+		return nil, ErrInvalidMovementType
+	}
+	if movement.MovementType != MovementTypeAdjustment && movement.Quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	if err := s.checkCountLock(tx, movement); err != nil {
+		return nil, err
+	}
+
+	// Stock may only be put on locations allowed to hold it, while any location may be emptied
+	if delta > 0 {
+		if err := s.locationService.CheckStockable(tx, movement.LocationID); err != nil {
+			return nil, err
+		}
+	}
+
+	level, err := s.lockLevel(tx, movement.ProductID, movement.VariantID, movement.LocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Quantities entered in another unit are converted to the unit the stock is kept in
+	ratio, err := s.toStockUnit(tx, level, movement)
+	if err != nil {
+		return nil, err
+	}
+	delta = roundQuantity(delta * ratio)
+
+	quantity := roundQuantity(level.Quantity + delta)
+	if quantity < 0 {
+		return nil, &InsufficientStockError{
+			ProductID:  movement.ProductID,
+			VariantID:  movement.VariantID,
+			LocationID: movement.LocationID,
+			Available:  level.Quantity,
+			Requested:  -delta,
+		}
+	}
+
+	// Value the movement: receipts at their unit cost, issues with the valuation method
+	var unitCost, value float64
+	if delta > 0 {
+		unitCost, err = s.receiptUnitCost(tx, level, movement)
+		if err != nil {
+			return nil, err
+		}
+		value = roundCost(delta * unitCost)
+	} else {
+		cost, err := s.issueCost(tx, level, -delta, quantity)
+		if err != nil {
+			return nil, err
+		}
+		value = -cost
+		unitCost = roundCost(cost / -delta)
+	}
+	movement.UnitCost = &unitCost
+	movement.TotalCost = value
+
+	level.Quantity = quantity
+	level.TotalValue = roundCost(level.TotalValue + value)
+	if quantity > 0 {
+		level.AverageCost = roundCost(level.TotalValue / quantity)
+	} else {
+		level.TotalValue = 0
+	}
+	if level.Unit == "" {
+		level.Unit = movement.Unit
+	}
+	if _, err := tx.Model(&models.StockLevel{}).Where("id", level.ID).Update(map[string]any{
+		"quantity":     level.Quantity,
+		"average_cost": level.AverageCost,
+		"total_value":  level.TotalValue,
+		"unit":         level.Unit,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Create(movement); err != nil {
+		return nil, err
+	}
+
+	if err := s.applyLots(tx, movement, delta); err != nil {
+		return nil, err
+	}
+
+	// Every receipt opens a cost layer consumed first in, first out
+	if delta > 0 {
+		layer := models.StockCostLayer{
+			StockLevelID:      level.ID,
+			MovementID:        movement.ID,
+			Quantity:          delta,
+			RemainingQuantity: delta,
+			UnitCost:          unitCost,
+		}
+		if err := tx.Create(&layer); err != nil {
+			return nil, err
+		}
+	}
+
+	return level, nil
+}
+
+// ValuationMethod returns the configured method used to cost stock issues
+func (s *StockService) ValuationMethod() string {
+	if facades.Config().GetString("app.stock.valuation_method", ValuationWeightedAverage) == ValuationFIFO {
+		return ValuationFIFO
+	}
+
+	return ValuationWeightedAverage
+}
+
+// toStockUnit converts a movement entered in another unit to the unit of its stock level, or
+// of its variant or product for a new level. The entered quantity is kept on the movement and
+// its unit cost and lot quantities follow the conversion. It returns the ratio applied.
+func (s *StockService) toStockUnit(tx orm.Query, level *models.StockLevel, movement *models.StockMovement) (float64, error) {
+	stockUnit := level.Unit
+	if stockUnit == "" && movement.VariantID != nil {
+		var variant models.ProductVariant
+		if err := tx.Where("id", *movement.VariantID).First(&variant); err != nil {
+			return 0, err
+		}
+		stockUnit = variant.Unit
+	}
+	if stockUnit == "" {
+		var product models.Product
+		if err := tx.Where("id", movement.ProductID).First(&product); err != nil {
+			return 0, err
+		}
+		stockUnit = product.Unit
+	}
+
+	if movement.Unit == "" || stockUnit == "" || strings.EqualFold(movement.Unit, stockUnit) {
+		if movement.Unit == "" {
+			movement.Unit = stockUnit
+		}
+		return 1, nil
+	}
+
+	ratio, err := s.unitService.Convert(tx, movement.ProductID, movement.VariantID, 1, movement.Unit, stockUnit)
+	if err != nil {
+		return 0, err
+	}
+
+	entered := movement.Quantity
+	movement.EnteredQuantity = &entered
+	movement.EnteredUnit = movement.Unit
+	movement.Quantity = roundQuantity(movement.Quantity * ratio)
+	movement.Unit = stockUnit
+	if movement.UnitCost != nil {
+		unitCost := roundCost(*movement.UnitCost / ratio)
+		movement.UnitCost = &unitCost
+	}
+	for i := range movement.Lots {
+		movement.Lots[i].Quantity = roundQuantity(movement.Lots[i].Quantity * ratio)
+	}
+
+	return ratio, nil
+}
+
+// receiptUnitCost returns the unit cost of incoming stock: the one given on the movement,
+// otherwise the current average cost, otherwise the purchase price of the variant or product
+func (s *StockService) receiptUnitCost(tx orm.Query, level *models.StockLevel, movement *models.StockMovement) (float64, error) {
+	if movement.UnitCost != nil && *movement.UnitCost >= 0 {
+		return roundCost(*movement.UnitCost), nil
+	}
+	if level.AverageCost > 0 {
+		return level.AverageCost, nil
+	}
+
+	if movement.VariantID != nil {
+		var variant models.ProductVariant
+		if err := tx.Where("id", *movement.VariantID).First(&variant); err != nil {
+			return 0, err
+		}
+		if variant.PrixAchat > 0 {
+			return variant.PrixAchat, nil
+		}
+	}
+
+	var product models.Product
+	if err := tx.Where("id", movement.ProductID).First(&product); err != nil {
+		return 0, err
+	}
+
+	return product.PrixAchat, nil
+}
+
+// issueCost consumes the cost layers of a level first in, first out and returns the cost
+// of the issued quantity under the configured valuation method. Stock received before
+// layers existed is costed at the average cost.
+func (s *StockService) issueCost(tx orm.Query, level *models.StockLevel, issued float64, remainingQuantity float64) (float64, error) {
+	var layers []models.StockCostLayer
+	if err := tx.LockForUpdate().Where("stock_level_id", level.ID).Where("remaining_quantity > ?", 0).
+		OrderBy("id").Find(&layers); err != nil {
+		return 0, err
+	}
+
+	fifoCost := 0.0
+	left := issued
+	for _, layer := range layers {
+		if left <= 0 {
+			break
+		}
+		take := math.Min(left, layer.RemainingQuantity)
+		fifoCost += take * layer.UnitCost
+		left = roundQuantity(left - take)
+		if _, err := tx.Model(&models.StockCostLayer{}).Where("id", layer.ID).Update("remaining_quantity", roundQuantity(layer.RemainingQuantity-take)); err != nil {
+			return 0, err
+		}
+	}
+	fifoCost += left * level.AverageCost
+
+	// Emptying the level releases whatever value is left, absorbing rounding
+	if remainingQuantity == 0 {
+		return level.TotalValue, nil
+	}
+	if s.ValuationMethod() == ValuationFIFO {
+		return roundCost(fifoCost), nil
+	}
+
+	return roundCost(issued * level.AverageCost), nil
+}
+
+// IsLotTracked reports whether a product variant is held per lot, which is the case of
+// raw material variants
+func (s *StockService) IsLotTracked(tx orm.Query, productID uint, variantID *uint) (bool, error) {
+	if variantID == nil {
+		return false, nil
+	}
+
+	var product models.Product
+	if err := tx.Where("id", productID).First(&product); err != nil {
+		return false, err
+	}
+
+	return product.IsRawMaterial, nil
+}
+
+// applyLots records the lots filled or drawn by a movement and updates their balances at
+// the location. The lots given on the movement are used first. The rest of an increase goes
+// to a new lot, the rest of a decrease is taken from the oldest lots; stock held before lot
+// tracking is not covered by any lot and is issued untracked.
+func (s *StockService) applyLots(tx orm.Query, movement *models.StockMovement, delta float64) error {
+	requested := movement.Lots
+	movement.Lots = []models.StockMovementLot{}
+
+	tracked, err := s.IsLotTracked(tx, movement.ProductID, movement.VariantID)
+	if err != nil {
+		return err
+	}
+	if !tracked {
+		if len(requested) > 0 {
+			return ErrLotNotTracked
+		}
+		return nil
+	}
+
+	remaining := math.Abs(delta)
+	for _, allocation := range requested {
+		var lot models.StockLot
+		if err := tx.Where("id", allocation.LotID).FirstOrFail(&lot); err != nil {
+			return err
+		}
+		if lot.VariantID != *movement.VariantID {
+			return ErrLotMismatch
+		}
+		if allocation.Quantity <= 0 {
+			return ErrInvalidQuantity
+		}
+		remaining = roundQuantity(remaining - allocation.Quantity)
+		if remaining < 0 {
+			return ErrLotOverAllocated
+		}
+		if err := s.moveLot(tx, movement, lot, allocation.Quantity, delta > 0); err != nil {
+			return err
+		}
+	}
+	if remaining <= 0 {
+		return nil
+	}
+
+	if delta > 0 {
+		number, err := s.sequenceService.NextTx(tx, SequenceStockLot)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		lot := models.StockLot{
+			LotNumber:  number,
+			ProductID:  movement.ProductID,
+			VariantID:  *movement.VariantID,
+			ReceivedAt: &now,
+			Notes:      movement.Notes,
+			CreatedBy:  movement.CreatedBy,
+		}
+		if err := tx.Create(&lot); err != nil {
+			return err
+		}
+
+		return s.moveLot(tx, movement, lot, remaining, true)
+	}
+
+	var balances []models.StockLotBalance
+	if err := tx.LockForUpdate().With("Lot").Where("location_id", movement.LocationID).Where("quantity > ?", 0).
+		Where("lot_id IN (SELECT id FROM stock_lots WHERE variant_id = ?)", *movement.VariantID).
+		OrderBy("lot_id").Find(&balances); err != nil {
+		return err
+	}
+	for _, balance := range balances {
+		if remaining <= 0 {
+			break
+		}
+		take := roundQuantity(math.Min(remaining, balance.Quantity))
+		if err := s.moveLot(tx, movement, balance.Lot, take, false); err != nil {
+			return err
+		}
+		remaining = roundQuantity(remaining - take)
+	}
+
+	return nil
+}
+
+// moveLot adds or takes a quantity of a lot at the movement location and records it on the movement
+func (s *StockService) moveLot(tx orm.Query, movement *models.StockMovement, lot models.StockLot, quantity float64, increase bool) error {
+	var balance models.StockLotBalance
+	if err := tx.LockForUpdate().Where("lot_id", lot.ID).Where("location_id", movement.LocationID).First(&balance); err != nil {
+		return err
+	}
+
+	// The balance row is created in the transaction, which may have created the lot itself
+	// and not committed it yet
+	if balance.ID == 0 {
+		if !increase {
+			return &InsufficientLotError{
+				LotNumber:  lot.LotNumber,
+				LocationID: movement.LocationID,
+				Requested:  quantity,
+			}
+		}
+		balance = models.StockLotBalance{LotID: lot.ID, LocationID: movement.LocationID}
+		if err := tx.Create(&balance); err != nil {
+			return err
+		}
+	}
+
+	updated := roundQuantity(balance.Quantity + quantity)
+	if !increase {
+		updated = roundQuantity(balance.Quantity - quantity)
+	}
+	if updated < 0 {
+		return &InsufficientLotError{
+			LotNumber:  lot.LotNumber,
+			LocationID: movement.LocationID,
+			Available:  balance.Quantity,
+			Requested:  quantity,
+		}
+	}
+	if _, err := tx.Model(&models.StockLotBalance{}).Where("id", balance.ID).Update("quantity", updated); err != nil {
+		return err
+	}
+
+	allocation := models.StockMovementLot{
+		MovementID: movement.ID,
+		LotID:      lot.ID,
+		Quantity:   quantity,
+	}
+	if err := tx.Create(&allocation); err != nil {
+		return err
+	}
+	allocation.Lot = lot
+	movement.Lots = append(movement.Lots, allocation)
+
+	return nil
+}
+
+// checkCountLock refuses movements on a location being counted with movements blocked,
+// when the product falls within the categories of the count
+func (s *StockService) checkCountLock(tx orm.Query, movement *models.StockMovement) error {
+	var counts []models.InventoryCount
+	if err := tx.Where("location_id", movement.LocationID).Where("status", InventoryCountStatusOpen).Where("block_movements", true).Find(&counts); err != nil {
+		return err
+	}
+
+	for _, count := range counts {
+		categoryIDs := InventoryCountCategories(count)
+		if len(categoryIDs) > 0 {
+			var product models.Product
+			if err := tx.Where("id", movement.ProductID).First(&product); err != nil {
+				return err
+			}
+			if product.CategoryID == nil || !slices.Contains(categoryIDs, *product.CategoryID) {
+				continue
+			}
+		}
+
+		return &CountInProgressError{CountNumber: count.CountNumber, LocationID: movement.LocationID}
+	}
+
+	return nil
+}
+
+// lockLevel returns the stock level for a product, variant and location locked for update,
+// creating an empty one first if needed
+func (s *StockService) lockLevel(tx orm.Query, productID uint, variantID *uint, locationID uint) (*models.StockLevel, error) {
+	if err := s.ensureLevel(productID, variantID, locationID); err != nil {
+		return nil, err
+	}
+
+	var level models.StockLevel
+	if err := s.levelQuery(tx.LockForUpdate(), productID, variantID, locationID).FirstOrFail(&level); err != nil {
+		return nil, err
+	}
+
+	return &level, nil
+}
+
+// ensureLevel creates a missing stock level row outside the caller's transaction, so a
+// concurrent duplicate insert cannot abort it
+func (s *StockService) ensureLevel(productID uint, variantID *uint, locationID uint) error {
+	exists, err := s.levelQuery(facades.Orm().Query().Model(&models.StockLevel{}), productID, variantID, locationID).Exists()
+	if err != nil || exists {
+		return err
+	}
+
+	level := models.StockLevel{
+		ProductID:  productID,
+		VariantID:  variantID,
+		LocationID: locationID,
+	}
+	if err := facades.Orm().Query().Create(&level); err != nil {
+		// Another posting may have created the row in the meantime
+		exists, existsErr := s.levelQuery(facades.Orm().Query().Model(&models.StockLevel{}), productID, variantID, locationID).Exists()
+		if existsErr != nil || !exists {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// levelQuery scopes a query to the stock level of a product, variant and location
+func (s *StockService) levelQuery(query orm.Query, productID uint, variantID *uint, locationID uint) orm.Query {
+	query = query.Where("product_id", productID).Where("location_id", locationID)
+	if variantID != nil {
+		return query.Where("variant_id", *variantID)
+	}
+
+	return query.WhereNull("variant_id")
+}
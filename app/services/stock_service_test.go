@@ -0,0 +1,85 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/goravel/framework/database/orm"
+	mocksorm "github.com/goravel/framework/mocks/database/orm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"pms/app/models"
+)
+
+// TestApplyLotsReceiptCreatesBalanceInTransaction follows a lot receipt: the lot was just
+// created by the transaction, so its balance row must be created through it as well
+func TestApplyLotsReceiptCreatesBalanceInTransaction(t *testing.T) {
+	tx := mocksorm.NewQuery(t)
+	service := NewStockService()
+	variantID := uint(20)
+	movement := &models.StockMovement{
+		Model:        orm.Model{ID: 30},
+		ProductID:    10,
+		VariantID:    &variantID,
+		LocationID:   5,
+		MovementType: MovementTypeIn,
+		Quantity:     12.5,
+		Lots:         []models.StockMovementLot{{LotID: 40, Quantity: 12.5}},
+	}
+
+	products := mocksorm.NewQuery(t)
+	tx.EXPECT().Where("id", uint(10)).Return(products).Once()
+	products.EXPECT().First(mock.Anything).Run(func(dest any) {
+		*dest.(*models.Product) = models.Product{Model: orm.Model{ID: 10}, IsRawMaterial: true}
+	}).Return(nil).Once()
+
+	lots := mocksorm.NewQuery(t)
+	tx.EXPECT().Where("id", uint(40)).Return(lots).Once()
+	lots.EXPECT().FirstOrFail(mock.Anything).Run(func(dest any) {
+		*dest.(*models.StockLot) = models.StockLot{Model: orm.Model{ID: 40}, ProductID: 10, VariantID: 20, LotNumber: "LOT-1"}
+	}).Return(nil).Once()
+
+	// No balance yet at the location
+	balances := mocksorm.NewQuery(t)
+	tx.EXPECT().LockForUpdate().Return(balances).Once()
+	balances.EXPECT().Where("lot_id", uint(40)).Return(balances).Once()
+	balances.EXPECT().Where("location_id", uint(5)).Return(balances).Once()
+	balances.EXPECT().First(mock.Anything).Return(nil).Once()
+
+	tx.EXPECT().Create(mock.MatchedBy(func(balance *models.StockLotBalance) bool {
+		return balance.LotID == 40 && balance.LocationID == 5 && balance.Quantity == 0
+	})).Run(func(value any) {
+		value.(*models.StockLotBalance).ID = 50
+	}).Return(nil).Once()
+
+	updates := mocksorm.NewQuery(t)
+	tx.EXPECT().Model(mock.Anything).Return(updates).Once()
+	updates.EXPECT().Where("id", uint(50)).Return(updates).Once()
+	updates.EXPECT().Update("quantity", 12.5).Return(nil, nil).Once()
+
+	tx.EXPECT().Create(mock.MatchedBy(func(allocation *models.StockMovementLot) bool {
+		return allocation.MovementID == 30 && allocation.LotID == 40 && allocation.Quantity == 12.5
+	})).Return(nil).Once()
+
+	assert.NoError(t, service.applyLots(tx, movement, 12.5))
+	assert.Len(t, movement.Lots, 1)
+	assert.Equal(t, "LOT-1", movement.Lots[0].Lot.LotNumber)
+}
+
+func TestMoveLotIssueWithoutBalance(t *testing.T) {
+	tx := mocksorm.NewQuery(t)
+	service := NewStockService()
+	movement := &models.StockMovement{Model: orm.Model{ID: 31}, LocationID: 6}
+	lot := models.StockLot{Model: orm.Model{ID: 41}, LotNumber: "LOT-2"}
+
+	balances := mocksorm.NewQuery(t)
+	tx.EXPECT().LockForUpdate().Return(balances).Once()
+	balances.EXPECT().Where("lot_id", uint(41)).Return(balances).Once()
+	balances.EXPECT().Where("location_id", uint(6)).Return(balances).Once()
+	balances.EXPECT().First(mock.Anything).Return(nil).Once()
+
+	err := service.moveLot(tx, movement, lot, 2, false)
+	var lotErr *InsufficientLotError
+	assert.ErrorAs(t, err, &lotErr)
+	assert.Equal(t, "LOT-2", lotErr.LotNumber)
+}
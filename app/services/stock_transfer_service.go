@@ -0,0 +1,194 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// Stock transfer statuses
+const (
+	StockTransferStatusDraft     = "draft"
+	StockTransferStatusInTransit = "in_transit"
+	StockTransferStatusReceived  = "received"
+	StockTransferStatusCancelled = "cancelled"
+)
+
+// ReferenceTypeStockTransfer tags the paired movements posted by a transfer
+const ReferenceTypeStockTransfer = "stock_transfer"
+
+// InvalidTransferStatusError is returned when a transfer is not in the status an action requires
+type InvalidTransferStatusError struct {
+	Status   string
+	Expected []string
+}
+
+func (e *InvalidTransferStatusError) Error() string {
+	return fmt.Sprintf("stock transfer is %s, expected one of %v", e.Status, e.Expected)
+}
+
+type StockTransferService struct {
+	sequenceService *SequenceService
+	stockService    *StockService
+}
+
+func NewStockTransferService() *StockTransferService {
+	return &StockTransferService{
+		sequenceService: NewSequenceService(),
+		stockService:    NewStockService(),
+	}
+}
+
+// Create numbers and stores a draft transfer with its lines
+func (s *StockTransferService) Create(transfer *models.StockTransfer, items []models.StockTransferItem) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		transferNumber, err := s.sequenceService.NextTx(tx, SequenceStockTransfer)
+		if err != nil {
+			return err
+		}
+		transfer.TransferNumber = transferNumber
+		transfer.Status = StockTransferStatusDraft
+
+		if err := tx.Create(transfer); err != nil {
+			return err
+		}
+
+		for i := range items {
+			items[i].StockTransferID = transfer.ID
+			if err := tx.Create(&items[i]); err != nil {
+				return err
+			}
+		}
+		transfer.StockTransferItems = items
+
+		return nil
+	})
+}
+
+// Dispatch takes the goods out of the source location. From then on they are in transit
+// and counted in neither location until received.
+func (s *StockTransferService) Dispatch(transfer *models.StockTransfer, user models.User) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := s.lockWithStatus(tx, transfer, StockTransferStatusDraft); err != nil {
+			return err
+		}
+
+		if err := s.postItems(tx, transfer, transfer.FromLocationID, MovementTypeOut, "Dispatch of transfer", user); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		transfer.Status = StockTransferStatusInTransit
+		transfer.DispatchedBy = &user.ID
+		transfer.DispatchedAt = &now
+
+		return tx.Save(transfer)
+	})
+}
+
+// Receive puts the in transit goods into the destination location
+func (s *StockTransferService) Receive(transfer *models.StockTransfer, user models.User) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := s.lockWithStatus(tx, transfer, StockTransferStatusInTransit); err != nil {
+			return err
+		}
+
+		if err := s.postItems(tx, transfer, transfer.ToLocationID, MovementTypeIn, "Receipt of transfer", user); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		transfer.Status = StockTransferStatusReceived
+		transfer.ReceivedBy = &user.ID
+		transfer.ReceivedAt = &now
+
+		return tx.Save(transfer)
+	})
+}
+
+// Cancel abandons a transfer. Goods already dispatched are returned to the source location.
+func (s *StockTransferService) Cancel(transfer *models.StockTransfer, user models.User) error {
+	return facades.Orm().Transaction(func(tx orm.Query) error {
+		if err := s.lockWithStatus(tx, transfer, StockTransferStatusDraft, StockTransferStatusInTransit); err != nil {
+			return err
+		}
+
+		if transfer.Status == StockTransferStatusInTransit {
+			if err := s.postItems(tx, transfer, transfer.FromLocationID, MovementTypeIn, "Return of cancelled transfer", user); err != nil {
+				return err
+			}
+		}
+
+		transfer.Status = StockTransferStatusCancelled
+
+		return tx.Save(transfer)
+	})
+}
+
+// postItems posts one movement per transfer line at the given location, all sharing the
+// transfer as reference
+func (s *StockTransferService) postItems(tx orm.Query, transfer *models.StockTransfer, locationID uint, movementType string, label string, user models.User) error {
+	var items []models.StockTransferItem
+	if err := tx.Where("stock_transfer_id", transfer.ID).OrderBy("id").Find(&items); err != nil {
+		return err
+	}
+
+	transferID := transfer.ID
+	for _, item := range items {
+		movement := models.StockMovement{
+			ProductID:     item.ProductID,
+			VariantID:     item.VariantID,
+			LocationID:    locationID,
+			MovementType:  movementType,
+			Quantity:      item.Quantity,
+			Unit:          item.Unit,
+			ReferenceType: ReferenceTypeStockTransfer,
+			ReferenceID:   &transferID,
+			Notes:         label + " " + transfer.TransferNumber,
+			CreatedBy:     user.ID,
+		}
+		// Goods arriving or returning keep the quantity, cost and lots the line left the
+		// source with, in the unit the source converted them to
+		if movementType == MovementTypeIn && item.OutMovementID != nil {
+			var out models.StockMovement
+			if err := tx.Where("id", *item.OutMovementID).FirstOrFail(&out); err != nil {
+				return err
+			}
+			movement.Quantity = out.Quantity
+			movement.Unit = out.Unit
+			movement.UnitCost = out.UnitCost
+			if err := tx.Where("movement_id", out.ID).OrderBy("id").Find(&movement.Lots); err != nil {
+				return err
+			}
+		}
+		if _, err := s.stockService.PostMovementTx(tx, &movement); err != nil {
+			return err
+		}
+		if movementType == MovementTypeOut {
+			if _, err := tx.Model(&models.StockTransferItem{}).Where("id", item.ID).Update("out_movement_id", movement.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// lockWithStatus reloads a transfer under lock, without its relationships, and checks its status
+func (s *StockTransferService) lockWithStatus(tx orm.Query, transfer *models.StockTransfer, expected ...string) error {
+	id := transfer.ID
+	*transfer = models.StockTransfer{}
+	if err := tx.LockForUpdate().Where("id", id).FirstOrFail(transfer); err != nil {
+		return err
+	}
+	if slices.Contains(expected, transfer.Status) {
+		return nil
+	}
+
+	return &InvalidTransferStatusError{Status: transfer.Status, Expected: expected}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/goravel/framework/contracts/database/orm"
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// Unit categories. Units of one category convert through their factors, except counts:
+// a sheet and a bar are both counted but only a product conversion relates them.
+const (
+	UnitCategoryMass   = "mass"
+	UnitCategoryLength = "length"
+	UnitCategoryArea   = "area"
+	UnitCategoryVolume = "volume"
+	UnitCategoryCount  = "count"
+)
+
+// UnitCategories lists the valid unit categories
+var UnitCategories = []string{UnitCategoryMass, UnitCategoryLength, UnitCategoryArea, UnitCategoryVolume, UnitCategoryCount}
+
+var ErrUnknownUnit = errors.New("unit does not exist")
+
+// UnitConversionError is returned when a quantity cannot be expressed in another unit
+type UnitConversionError struct {
+	ProductID uint
+	From      string
+	To        string
+}
+
+func (e *UnitConversionError) Error() string {
+	return fmt.Sprintf("no conversion from %s to %s for product %d", e.From, e.To, e.ProductID)
+}
+
+type UnitService struct {
+}
+
+func NewUnitService() *UnitService {
+	return &UnitService{}
+}
+
+// Exists checks that a unit code is defined
+func (s *UnitService) Exists(code string) (bool, error) {
+	return facades.Orm().Query().Model(&models.Unit{}).Where("code", code).Exists()
+}
+
+// Convert expresses a quantity of a product given in one unit in another unit. Units of the
+// same category convert through their factors; otherwise a conversion of the variant, or of
+// the product, bridges the two categories in either direction. An empty unit is taken as
+// the other one, as for rows recorded before units were defined.
+func (s *UnitService) Convert(tx orm.Query, productID uint, variantID *uint, quantity float64, from string, to string) (float64, error) {
+	if from == "" || to == "" || strings.EqualFold(from, to) {
+		return quantity, nil
+	}
+
+	var units []models.Unit
+	if err := tx.Find(&units); err != nil {
+		return 0, err
+	}
+	byCode := map[string]models.Unit{}
+	for _, unit := range units {
+		byCode[strings.ToLower(unit.Code)] = unit
+	}
+
+	if ratio, ok := s.ratio(byCode, from, to); ok {
+		return quantity * ratio, nil
+	}
+
+	query := tx.Where("product_id", productID)
+	if variantID != nil {
+		query = query.Where("variant_id IS NULL OR variant_id = ?", *variantID)
+	} else {
+		query = query.WhereNull("variant_id")
+	}
+	var conversions []models.ProductUnitConversion
+	if err := query.Find(&conversions); err != nil {
+		return 0, err
+	}
+
+	if converted, ok := s.bridge(byCode, conversions, quantity, from, to); ok {
+		return converted, nil
+	}
+
+	return 0, &UnitConversionError{ProductID: productID, From: from, To: to}
+}
+
+// bridge converts a quantity through the first product conversion relating the two units,
+// in either direction. Variant conversions are tried first so they override the product
+// ones; they are put first here rather than by the query, databases disagreeing on where
+// they sort a missing variant.
+func (s *UnitService) bridge(units map[string]models.Unit, conversions []models.ProductUnitConversion, quantity float64, from string, to string) (float64, bool) {
+	ordered := slices.Clone(conversions)
+	slices.SortStableFunc(ordered, func(a, b models.ProductUnitConversion) int {
+		switch {
+		case a.VariantID != nil && b.VariantID == nil:
+			return -1
+		case a.VariantID == nil && b.VariantID != nil:
+			return 1
+		}
+		return 0
+	})
+
+	for _, conversion := range ordered {
+		if conversion.Factor <= 0 {
+			continue
+		}
+		before, okBefore := s.ratio(units, from, conversion.FromUnit)
+		after, okAfter := s.ratio(units, conversion.ToUnit, to)
+		if okBefore && okAfter {
+			return quantity * before * conversion.Factor * after, true
+		}
+		before, okBefore = s.ratio(units, from, conversion.ToUnit)
+		after, okAfter = s.ratio(units, conversion.FromUnit, to)
+		if okBefore && okAfter {
+			return quantity * before / conversion.Factor * after, true
+		}
+	}
+
+	return 0, false
+}
+
+// ratio returns how many of the target unit make one of the source unit, when both are the
+// same unit or share a category other than count
+func (s *UnitService) ratio(units map[string]models.Unit, from string, to string) (float64, bool) {
+	if strings.EqualFold(from, to) {
+		return 1, true
+	}
+
+	source, okSource := units[strings.ToLower(from)]
+	target, okTarget := units[strings.ToLower(to)]
+	if !okSource || !okTarget || source.Category != target.Category || source.Category == UnitCategoryCount || target.Factor <= 0 {
+		return 0, false
+	}
+
+	return source.Factor / target.Factor, true
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"pms/app/models"
+)
+
+func TestUnitServiceBridgePrefersVariantConversion(t *testing.T) {
+	service := NewUnitService()
+	variantID := uint(7)
+	units := map[string]models.Unit{
+		"kg":    {Code: "kg", Category: UnitCategoryMass, Factor: 1},
+		"g":     {Code: "g", Category: UnitCategoryMass, Factor: 0.001},
+		"sheet": {Code: "sheet", Category: UnitCategoryCount, Factor: 1},
+	}
+
+	// The product conversion is listed first, as a database sorting NULLs first returns it
+	conversions := []models.ProductUnitConversion{
+		{ProductID: 1, FromUnit: "sheet", ToUnit: "kg", Factor: 31.4},
+		{ProductID: 1, VariantID: &variantID, FromUnit: "sheet", ToUnit: "kg", Factor: 47.1},
+	}
+
+	converted, ok := service.bridge(units, conversions, 2, "sheet", "kg")
+	assert.True(t, ok)
+	assert.InDelta(t, 94.2, converted, 1e-9)
+
+	// Reverse direction, through a unit of the same category
+	converted, ok = service.bridge(units, conversions, 47100, "g", "sheet")
+	assert.True(t, ok)
+	assert.InDelta(t, 1, converted, 1e-9)
+
+	// Without a variant row the product conversion applies
+	converted, ok = service.bridge(units, conversions[:1], 2, "sheet", "kg")
+	assert.True(t, ok)
+	assert.InDelta(t, 62.8, converted, 1e-9)
+}
+
+func TestUnitServiceBridgeWithoutConversion(t *testing.T) {
+	service := NewUnitService()
+	units := map[string]models.Unit{
+		"kg":  {Code: "kg", Category: UnitCategoryMass, Factor: 1},
+		"bar": {Code: "bar", Category: UnitCategoryCount, Factor: 1},
+	}
+
+	_, ok := service.bridge(units, nil, 1, "bar", "kg")
+	assert.False(t, ok)
+}
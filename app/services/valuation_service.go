@@ -0,0 +1,187 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+// ValuationLine is the stock quantity and value of one grouping key
+type ValuationLine struct {
+	ID       *uint   `json:"id"`
+	Label    string  `json:"label"`
+	Quantity float64 `json:"quantity"`
+	Value    float64 `json:"value"`
+}
+
+// ValuationReport is the stock value at a point in time
+type ValuationReport struct {
+	Method     string          `json:"method"`
+	At         time.Time       `json:"at"`
+	TotalValue float64         `json:"total_value"`
+	ByLocation []ValuationLine `json:"by_location"`
+	ByCategory []ValuationLine `json:"by_category"`
+	ByVariant  []ValuationLine `json:"by_variant"`
+}
+
+// valuationRow is a movement aggregate per location, product and variant
+type valuationRow struct {
+	LocationID uint
+	ProductID  uint
+	VariantID  *uint
+	Quantity   float64
+	Value      float64
+}
+
+type ValuationService struct {
+	stockService *StockService
+}
+
+func NewValuationService() *ValuationService {
+	return &ValuationService{
+		stockService: NewStockService(),
+	}
+}
+
+// Report values the stock at the given time by replaying the value changes recorded on the
+// movement ledger, so past dates are reported with the costs used at that time
+func (s *ValuationService) Report(at time.Time) (*ValuationReport, error) {
+	var rows []valuationRow
+	if err := facades.Orm().Query().Model(&models.StockMovement{}).
+		Select("location_id, product_id, variant_id, "+
+			"SUM(CASE WHEN movement_type = 'out' THEN -quantity ELSE quantity END) AS quantity, "+
+			"SUM(total_cost) AS value").
+		Where("created_at <= ?", at).
+		GroupBy("location_id", "product_id", "variant_id").
+		Scan(&rows); err != nil {
+		return nil, err
+	}
+
+	var locationIDs, productIDs, variantIDs []uint
+	for _, row := range rows {
+		locationIDs = append(locationIDs, row.LocationID)
+		productIDs = append(productIDs, row.ProductID)
+		if row.VariantID != nil {
+			variantIDs = append(variantIDs, *row.VariantID)
+		}
+	}
+
+	var locations []models.StorageLocation
+	var products []models.Product
+	var variants []models.ProductVariant
+	if len(rows) > 0 {
+		if err := facades.Orm().Query().Where("id IN ?", locationIDs).Find(&locations); err != nil {
+			return nil, err
+		}
+		if err := facades.Orm().Query().With("Category").Where("id IN ?", productIDs).Find(&products); err != nil {
+			return nil, err
+		}
+		if len(variantIDs) > 0 {
+			if err := facades.Orm().Query().Where("id IN ?", variantIDs).Find(&variants); err != nil {
+				return nil, err
+			}
+		}
+	}
+	locationNames := map[uint]string{}
+	for _, location := range locations {
+		locationNames[location.ID] = location.Name
+	}
+	productsByID := map[uint]models.Product{}
+	for _, product := range products {
+		productsByID[product.ID] = product
+	}
+	variantsByID := map[uint]models.ProductVariant{}
+	for _, variant := range variants {
+		variantsByID[variant.ID] = variant
+	}
+
+	byLocation := newValuationGroup()
+	byCategory := newValuationGroup()
+	byVariant := newValuationGroup()
+	report := &ValuationReport{Method: s.stockService.ValuationMethod(), At: at}
+	for _, row := range rows {
+		if roundQuantity(row.Quantity) == 0 && roundCost(row.Value) == 0 {
+			continue
+		}
+		report.TotalValue += row.Value
+
+		locationID := row.LocationID
+		byLocation.add("location", &locationID, locationNames[locationID], row)
+
+		product := productsByID[row.ProductID]
+		if product.Category != nil {
+			byCategory.add("category", product.CategoryID, product.Category.Title, row)
+		} else {
+			byCategory.add("category", nil, "Uncategorized", row)
+		}
+
+		// Products without variants are reported under their own title
+		if row.VariantID != nil {
+			variant := variantsByID[*row.VariantID]
+			byVariant.add("variant", row.VariantID, product.Title+" - "+variant.Title+" ("+variant.SKU+")", row)
+		} else {
+			byVariant.add("product", &product.ID, product.Title, row)
+		}
+	}
+	report.TotalValue = roundCost(report.TotalValue)
+	report.ByLocation = byLocation.lines()
+	report.ByCategory = byCategory.lines()
+	report.ByVariant = byVariant.lines()
+
+	return report, nil
+}
+
+// valuationKey identifies a valuation line, a nil ID being kept as zero
+type valuationKey struct {
+	kind string
+	id   uint
+}
+
+// valuationGroup accumulates valuation lines per key
+type valuationGroup map[valuationKey]*ValuationLine
+
+func newValuationGroup() valuationGroup {
+	return valuationGroup{}
+}
+
+// add accumulates a movement aggregate on the line of the given key
+func (g valuationGroup) add(kind string, id *uint, label string, row valuationRow) {
+	key := valuationKey{kind: kind}
+	var lineID *uint
+	if id != nil {
+		key.id = *id
+		value := *id
+		lineID = &value
+	}
+	line, ok := g[key]
+	if !ok {
+		line = &ValuationLine{ID: lineID, Label: label}
+		g[key] = line
+	}
+	line.Quantity += row.Quantity
+	line.Value += row.Value
+}
+
+// lines returns the rounded lines, highest value first
+func (g valuationGroup) lines() []ValuationLine {
+	lines := make([]ValuationLine, 0, len(g))
+	for _, line := range g {
+		lines = append(lines, ValuationLine{
+			ID:       line.ID,
+			Label:    line.Label,
+			Quantity: roundQuantity(line.Quantity),
+			Value:    roundCost(line.Value),
+		})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Value != lines[j].Value {
+			return lines[i].Value > lines[j].Value
+		}
+		return lines[i].Label < lines[j].Label
+	})
+
+	return lines
+}
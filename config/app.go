@@ -87,6 +87,46 @@ func init() {
 		// will not be safe. Please do this before deploying an application!
 		"key": config.Env("APP_KEY", ""),
 
+		// Document Number Sequences
+		//
+		// Patterns used to number documents, keyed by document type. Supported
+		// tokens are {YYYY}, {YY}, {MM}, {DD}, {seq} and {seq:N} where N is the
+		// zero-padded width of the counter. Counters restart every year.
+		"sequences": map[string]any{
+			"order_fabrication":        config.Env("SEQUENCE_ORDER_FABRICATION", "OF-{YYYY}-{seq:5}"),
+			"stock_request":            config.Env("SEQUENCE_STOCK_REQUEST", "DA-{YYYY}{MM}-{seq}"),
+			"stock_transfer":           config.Env("SEQUENCE_STOCK_TRANSFER", "TR-{YYYY}-{seq:5}"),
+			"inventory_count":          config.Env("SEQUENCE_INVENTORY_COUNT", "INV-{YYYY}-{seq:4}"),
+			"stock_lot":                config.Env("SEQUENCE_STOCK_LOT", "LOT-{YYYY}-{seq:5}"),
+			"serial_number":            config.Env("SEQUENCE_SERIAL_NUMBER", "SN-{YYYY}-{seq:6}"),
+			"delivery":                 config.Env("SEQUENCE_DELIVERY", "BL-{YYYY}-{seq:5}"),
+			"engineering_change_order": config.Env("SEQUENCE_ENGINEERING_CHANGE_ORDER", "ECO-{YYYY}-{seq:4}"),
+		},
+
+		// Stock Management
+		//
+		// Time of day (HH:MM) at which reorder points are checked and the achat
+		// role is mailed the low-stock report, and the method used to cost stock
+		// issues: "wac" (weighted average cost) or "fifo" (first in, first out).
+		// Completed manufacturing orders receive their goods at the product's
+		// location, or at the finished goods location ID when it has none.
+		// Stock is only received on leaf locations of the listed types.
+		"stock": map[string]any{
+			"reorder_check_at":        config.Env("STOCK_REORDER_CHECK_AT", "06:00"),
+			"valuation_method":        config.Env("STOCK_VALUATION_METHOD", "wac"),
+			"finished_goods_location": config.Env("STOCK_FINISHED_GOODS_LOCATION", 0),
+			"location_types":          config.Env("STOCK_LOCATION_TYPES", "bin"),
+		},
+
+		// Costing
+		//
+		// Source of the material costs rolled up into standard costs: "purchase"
+		// (the PrixAchat of the material) or "valuation" (the average cost of the
+		// stock on hand, PrixAchat for materials out of stock).
+		"costing": map[string]any{
+			"material_cost_source": config.Env("COSTING_MATERIAL_COST_SOURCE", "purchase"),
+		},
+
 		// Autoload service providers
 		//
 		// The service providers listed here will be automatically loaded on the
@@ -44,6 +44,82 @@ func (kernel Kernel) Migrations() []schema.Migration {
 		&migrations.M20240101000023CreateStockRequestsTable{},                  // depends on order_fabrications, product_variants, users
 		&migrations.M20240101000024CreateTechnicalDocumentsTable{},             // depends on products, users
 		&migrations.M20240101000025CreateFicheConceptionsTable{},               // depends on products, users
+
+		// Table updates
+		&migrations.M20240101000026UpdateOrderFabricationsTable{},
+		&migrations.M20240101000027UpdateProductionOfHistoryTable{},
+
+		// Document numbering
+		&migrations.M20240101000028CreateDocumentSequencesTable{},
+
+		// Stock requests
+		&migrations.M20240101000029UpdateStockRequestsTable{},
+		&migrations.M20240101000030UpdateStockLevelsTable{},
+		&migrations.M20240101000031AddRejectionReasonToStockRequestsTable{},
+
+		// Stock transfers
+		&migrations.M20240101000032CreateStockTransfersTable{},     // depends on storage_locations, users
+		&migrations.M20240101000033CreateStockTransferItemsTable{}, // depends on stock_transfers, products, product_variants
+
+		// Inventory counts
+		&migrations.M20240101000034CreateInventoryCountsTable{},     // depends on storage_locations, users
+		&migrations.M20240101000035CreateInventoryCountLinesTable{}, // depends on inventory_counts, products, product_variants
+
+		// Stock reservations
+		&migrations.M20240101000036CreateStockReservationsTable{}, // depends on order_fabrications, production_material_requirements, products, product_variants, storage_locations, users
+		&migrations.M20240101000037AddConsumedQuantityToProductionMaterialRequirementsTable{},
+
+		// Reorder points
+		&migrations.M20240101000038CreateReorderRulesTable{},             // depends on product_variants, storage_locations
+		&migrations.M20240101000039CreateReplenishmentSuggestionsTable{}, // depends on reorder_rules, products, product_variants, storage_locations, users
+
+		// Inventory valuation
+		&migrations.M20240101000040AddCostsToStockTables{},
+		&migrations.M20240101000041CreateStockCostLayersTable{}, // depends on stock_levels, stock_movements
+
+		// Lot traceability
+		&migrations.M20240101000042CreateStockLotsTable{},         // depends on products, product_variants, users
+		&migrations.M20240101000043CreateStockLotBalancesTable{},  // depends on stock_lots, storage_locations
+		&migrations.M20240101000044CreateStockMovementLotsTable{}, // depends on stock_movements, stock_lots
+
+		// Serial numbers and deliveries
+		&migrations.M20240101000045AddSerialNumberingToProductsTable{},
+		&migrations.M20240101000046CreateDeliveriesTable{},    // depends on clients, client_sites, users
+		&migrations.M20240101000047CreateSerialNumbersTable{}, // depends on order_fabrications, stock_movements, deliveries
+
+		// Location hierarchy
+		&migrations.M20240101000048AddHierarchyToStorageLocationsTable{},
+
+		// Units of measure
+		&migrations.M20240101000049CreateUnitsTable{},
+		&migrations.M20240101000050CreateProductUnitConversionsTable{}, // depends on products, product_variants
+		&migrations.M20240101000051AddUnitsToRecipeAndMovementTables{},
+
+		// Recipe versions and engineering change orders
+		&migrations.M20240101000052AddVersioningToRecipeVariantsTable{},
+		&migrations.M20240101000053CreateEngineeringChangeOrdersTable{}, // depends on product_variants, recipe_variants, users
+		&migrations.M20240101000054AddRecipeVariantIdToOrderFabricationsTable{},
+
+		// Standard costs
+		&migrations.M20240101000055CreateStandardCostsTable{}, // depends on products, product_variants, recipe_variants
+
+		// Scrap, yield and by-products
+		&migrations.M20240101000056AddScrapAndYieldToRecipeTables{},
+		&migrations.M20240101000057CreateRecipeVariantByProductsTable{}, // depends on recipe_variants, product_variants
+
+		// Recipe generation from product-level templates
+		&migrations.M20240101000058AddGenerationRulesToRecipeProductsTable{},
+
+		// Routings
+		&migrations.M20240101000059AddHourlyRateToOperationsTable{},
+		&migrations.M20240101000060CreateRoutingStepsTable{},               // depends on products, product_variants, operations
+		&migrations.M20240101000061CreateOrderFabricationOperationsTable{}, // depends on order_fabrications, operations
+
+		// Stock transfer lines keep their dispatch movement
+		&migrations.M20240101000062AddOutMovementIdToStockTransferItemsTable{}, // depends on stock_transfer_items, stock_movements
+
+		// Three-decimal quantities
+		&migrations.M20240101000063WidenQuantityColumns{},
 	}
 }
 
@@ -51,6 +127,7 @@ func (kernel Kernel) Seeders() []seeder.Seeder {
 	return []seeder.Seeder{
 		&seeders.RoleSeeder{},
 		&seeders.UserSeeder{},
+		&seeders.UnitSeeder{},
 		&seeders.DatabaseSeeder{},
 	}
 }
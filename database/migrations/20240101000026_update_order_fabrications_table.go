@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000026UpdateOrderFabricationsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000026UpdateOrderFabricationsTable) Signature() string {
+	return "20240101000026_update_order_fabrications_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000026UpdateOrderFabricationsTable) Up() error {
+	// Align status and priority with models.OrderFabrication
+	return facades.Schema().Table("order_fabrications", func(table schema.Blueprint) {
+		table.String("status", 50).Default("pending").Change()
+		table.String("priority", 20).Default("normal").Change()
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000026UpdateOrderFabricationsTable) Down() error {
+	return facades.Schema().Table("order_fabrications", func(table schema.Blueprint) {
+		table.Enum("status", []any{
+			"pending_validation", "validated", "rejected", "material_requested",
+			"ready_to_produce", "cutting_started", "cutting_paused", "cutting_completed",
+			"folding_started", "folding_completed", "assembly_started", "assembly_completed",
+			"finishing_started", "finishing_completed", "ready_for_delivery", "delivered", "cancelled",
+		}).Default("pending_validation").Change()
+		table.Integer("priority").Default(0).Change()
+	})
+}
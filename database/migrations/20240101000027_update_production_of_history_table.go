@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000027UpdateProductionOfHistoryTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000027UpdateProductionOfHistoryTable) Signature() string {
+	return "20240101000027_update_production_of_history_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000027UpdateProductionOfHistoryTable) Up() error {
+	// Status transitions are not always tied to an operation
+	return facades.Schema().Table("production_of_history", func(table schema.Blueprint) {
+		table.UnsignedBigInteger("operation_id").Nullable().Change()
+		table.String("previous_status", 50).Nullable()
+		table.TimestampsTz()
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000027UpdateProductionOfHistoryTable) Down() error {
+	return facades.Schema().Table("production_of_history", func(table schema.Blueprint) {
+		table.DropTimestampsTz()
+		table.DropColumn("previous_status")
+		table.UnsignedBigInteger("operation_id").Change()
+	})
+}
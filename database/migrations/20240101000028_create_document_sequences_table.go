@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000028CreateDocumentSequencesTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000028CreateDocumentSequencesTable) Signature() string {
+	return "20240101000028_create_document_sequences_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000028CreateDocumentSequencesTable) Up() error {
+	return facades.Schema().Create("document_sequences", func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("document_type", 50)
+		table.String("period", 10)
+		table.UnsignedBigInteger("last_value").Default(0)
+		table.TimestampsTz()
+
+		table.Unique("document_type", "period")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000028CreateDocumentSequencesTable) Down() error {
+	return facades.Schema().DropIfExists("document_sequences")
+}
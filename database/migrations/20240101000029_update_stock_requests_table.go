@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000029UpdateStockRequestsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000029UpdateStockRequestsTable) Signature() string {
+	return "20240101000029_update_stock_requests_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000029UpdateStockRequestsTable) Up() error {
+	// Align the table with models.StockRequest and link requests to material requirements
+	if err := facades.Schema().Table("stock_requests", func(table schema.Blueprint) {
+		table.RenameColumn("material_variant_id", "variant_id")
+		table.RenameColumn("requested_quantity", "quantity")
+	}); err != nil {
+		return err
+	}
+
+	return facades.Schema().Table("stock_requests", func(table schema.Blueprint) {
+		table.String("request_number", 100).Nullable()
+		table.UnsignedBigInteger("order_fabrication_id").Nullable().Change()
+		table.UnsignedBigInteger("variant_id").Nullable().Change()
+		table.UnsignedBigInteger("requirement_id").Nullable()
+		table.UnsignedBigInteger("product_id").Nullable()
+		table.UnsignedBigInteger("location_id").Nullable()
+		table.String("priority", 20).Default("medium")
+		table.UnsignedBigInteger("approved_by").Nullable()
+		table.Timestamp("approved_at").Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("requirement_id").References("id").On("production_material_requirements")
+		table.Foreign("product_id").References("id").On("products")
+		table.Foreign("location_id").References("id").On("storage_locations")
+		table.Foreign("approved_by").References("id").On("users")
+		table.Unique("request_number")
+		table.Index("requirement_id")
+		table.Index("product_id")
+		table.Index("location_id")
+		table.Index("priority")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000029UpdateStockRequestsTable) Down() error {
+	if err := facades.Schema().Table("stock_requests", func(table schema.Blueprint) {
+		table.DropForeign("requirement_id")
+		table.DropForeign("product_id")
+		table.DropForeign("location_id")
+		table.DropForeign("approved_by")
+		table.DropUnique("request_number")
+		table.DropIndex("requirement_id")
+		table.DropIndex("product_id")
+		table.DropIndex("location_id")
+		table.DropIndex("priority")
+		table.DropTimestampsTz()
+		table.DropColumn("request_number", "requirement_id", "product_id", "location_id", "priority", "approved_by", "approved_at")
+	}); err != nil {
+		return err
+	}
+
+	return facades.Schema().Table("stock_requests", func(table schema.Blueprint) {
+		table.RenameColumn("variant_id", "material_variant_id")
+		table.RenameColumn("quantity", "requested_quantity")
+	})
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000030UpdateStockLevelsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000030UpdateStockLevelsTable) Signature() string {
+	return "20240101000030_update_stock_levels_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000030UpdateStockLevelsTable) Up() error {
+	// models.StockLevel carries the standard timestamps
+	return facades.Schema().Table("stock_levels", func(table schema.Blueprint) {
+		table.TimestampsTz()
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000030UpdateStockLevelsTable) Down() error {
+	return facades.Schema().Table("stock_levels", func(table schema.Blueprint) {
+		table.DropTimestampsTz()
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000031AddRejectionReasonToStockRequestsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000031AddRejectionReasonToStockRequestsTable) Signature() string {
+	return "20240101000031_add_rejection_reason_to_stock_requests_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000031AddRejectionReasonToStockRequestsTable) Up() error {
+	return facades.Schema().Table("stock_requests", func(table schema.Blueprint) {
+		table.String("rejection_reason", 20).Nullable()
+		table.Text("rejection_notes").Nullable()
+
+		table.Index("rejection_reason")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000031AddRejectionReasonToStockRequestsTable) Down() error {
+	return facades.Schema().Table("stock_requests", func(table schema.Blueprint) {
+		table.DropIndex("rejection_reason")
+		table.DropColumn("rejection_reason", "rejection_notes")
+	})
+}
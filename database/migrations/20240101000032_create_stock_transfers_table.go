@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000032CreateStockTransfersTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000032CreateStockTransfersTable) Signature() string {
+	return "20240101000032_create_stock_transfers_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000032CreateStockTransfersTable) Up() error {
+	return facades.Schema().Create("stock_transfers", func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("transfer_number", 100)
+		table.UnsignedBigInteger("from_location_id")
+		table.UnsignedBigInteger("to_location_id")
+		table.String("status", 20).Default("draft")
+		table.Text("notes").Nullable()
+		table.UnsignedBigInteger("created_by")
+		table.UnsignedBigInteger("dispatched_by").Nullable()
+		table.Timestamp("dispatched_at").Nullable()
+		table.UnsignedBigInteger("received_by").Nullable()
+		table.Timestamp("received_at").Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("from_location_id").References("id").On("storage_locations")
+		table.Foreign("to_location_id").References("id").On("storage_locations")
+		table.Foreign("created_by").References("id").On("users")
+		table.Foreign("dispatched_by").References("id").On("users")
+		table.Foreign("received_by").References("id").On("users")
+
+		table.Unique("transfer_number")
+		table.Index("from_location_id")
+		table.Index("to_location_id")
+		table.Index("status")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000032CreateStockTransfersTable) Down() error {
+	return facades.Schema().DropIfExists("stock_transfers")
+}
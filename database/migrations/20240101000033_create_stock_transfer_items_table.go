@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000033CreateStockTransferItemsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000033CreateStockTransferItemsTable) Signature() string {
+	return "20240101000033_create_stock_transfer_items_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000033CreateStockTransferItemsTable) Up() error {
+	return facades.Schema().Create("stock_transfer_items", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("stock_transfer_id")
+		table.UnsignedBigInteger("product_id")
+		table.UnsignedBigInteger("variant_id").Nullable()
+		table.Decimal("quantity").Total(10).Places(3)
+		table.String("unit", 50).Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("stock_transfer_id").References("id").On("stock_transfers")
+		table.Foreign("product_id").References("id").On("products")
+		table.Foreign("variant_id").References("id").On("product_variants")
+
+		table.Index("stock_transfer_id")
+		table.Index("product_id")
+		table.Index("variant_id")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000033CreateStockTransferItemsTable) Down() error {
+	return facades.Schema().DropIfExists("stock_transfer_items")
+}
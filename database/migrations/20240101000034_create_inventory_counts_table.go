@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000034CreateInventoryCountsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000034CreateInventoryCountsTable) Signature() string {
+	return "20240101000034_create_inventory_counts_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000034CreateInventoryCountsTable) Up() error {
+	return facades.Schema().Create("inventory_counts", func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("count_number", 100)
+		table.UnsignedBigInteger("location_id")
+		table.Json("category_ids").Nullable()
+		table.String("status", 20).Default("open")
+		table.Boolean("block_movements").Default(true)
+		table.Text("notes").Nullable()
+		table.UnsignedBigInteger("opened_by")
+		table.Timestamp("opened_at").Nullable()
+		table.UnsignedBigInteger("validated_by").Nullable()
+		table.Timestamp("validated_at").Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("location_id").References("id").On("storage_locations")
+		table.Foreign("opened_by").References("id").On("users")
+		table.Foreign("validated_by").References("id").On("users")
+
+		table.Unique("count_number")
+		table.Index("location_id")
+		table.Index("status")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000034CreateInventoryCountsTable) Down() error {
+	return facades.Schema().DropIfExists("inventory_counts")
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000035CreateInventoryCountLinesTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000035CreateInventoryCountLinesTable) Signature() string {
+	return "20240101000035_create_inventory_count_lines_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000035CreateInventoryCountLinesTable) Up() error {
+	return facades.Schema().Create("inventory_count_lines", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("inventory_count_id")
+		table.UnsignedBigInteger("product_id")
+		table.UnsignedBigInteger("variant_id").Nullable()
+		table.Decimal("expected_quantity").Total(10).Places(3).Default(0)
+		table.Decimal("counted_quantity").Total(10).Places(3).Nullable()
+		table.String("unit", 50).Nullable()
+		table.UnsignedBigInteger("counted_by").Nullable()
+		table.Timestamp("counted_at").Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("inventory_count_id").References("id").On("inventory_counts")
+		table.Foreign("product_id").References("id").On("products")
+		table.Foreign("variant_id").References("id").On("product_variants")
+		table.Foreign("counted_by").References("id").On("users")
+
+		table.Index("inventory_count_id")
+		table.Index("product_id")
+		table.Index("variant_id")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000035CreateInventoryCountLinesTable) Down() error {
+	return facades.Schema().DropIfExists("inventory_count_lines")
+}
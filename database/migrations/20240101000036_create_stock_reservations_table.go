@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000036CreateStockReservationsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000036CreateStockReservationsTable) Signature() string {
+	return "20240101000036_create_stock_reservations_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000036CreateStockReservationsTable) Up() error {
+	return facades.Schema().Create("stock_reservations", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("order_fabrication_id")
+		table.UnsignedBigInteger("requirement_id")
+		table.UnsignedBigInteger("product_id")
+		table.UnsignedBigInteger("variant_id").Nullable()
+		table.UnsignedBigInteger("location_id")
+		table.Decimal("quantity").Total(10).Places(3)
+		table.Decimal("consumed_quantity").Total(10).Places(3).Default(0)
+		table.String("unit", 50).Nullable()
+		table.String("status", 20).Default("active")
+		table.UnsignedBigInteger("created_by")
+		table.Timestamp("released_at").Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("order_fabrication_id").References("id").On("order_fabrications")
+		table.Foreign("requirement_id").References("id").On("production_material_requirements")
+		table.Foreign("product_id").References("id").On("products")
+		table.Foreign("variant_id").References("id").On("product_variants")
+		table.Foreign("location_id").References("id").On("storage_locations")
+		table.Foreign("created_by").References("id").On("users")
+
+		table.Index("order_fabrication_id")
+		table.Index("requirement_id")
+		table.Index("variant_id", "location_id")
+		table.Index("status")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000036CreateStockReservationsTable) Down() error {
+	return facades.Schema().DropIfExists("stock_reservations")
+}
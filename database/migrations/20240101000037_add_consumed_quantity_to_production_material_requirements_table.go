@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000037AddConsumedQuantityToProductionMaterialRequirementsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000037AddConsumedQuantityToProductionMaterialRequirementsTable) Signature() string {
+	return "20240101000037_add_consumed_quantity_to_production_material_requirements_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000037AddConsumedQuantityToProductionMaterialRequirementsTable) Up() error {
+	return facades.Schema().Table("production_material_requirements", func(table schema.Blueprint) {
+		table.Decimal("consumed_quantity").Total(10).Places(3).Default(0)
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000037AddConsumedQuantityToProductionMaterialRequirementsTable) Down() error {
+	return facades.Schema().Table("production_material_requirements", func(table schema.Blueprint) {
+		table.DropColumn("consumed_quantity")
+	})
+}
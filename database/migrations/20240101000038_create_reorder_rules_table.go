@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000038CreateReorderRulesTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000038CreateReorderRulesTable) Signature() string {
+	return "20240101000038_create_reorder_rules_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000038CreateReorderRulesTable) Up() error {
+	return facades.Schema().Create("reorder_rules", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("variant_id")
+		table.UnsignedBigInteger("location_id").Nullable()
+		table.Decimal("min_quantity").Total(10).Places(3).Default(0)
+		table.Decimal("max_quantity").Total(10).Places(3).Default(0)
+		table.Decimal("reorder_point").Total(10).Places(3).Default(0)
+		table.Boolean("is_active").Default(true)
+		table.TimestampsTz()
+
+		table.Foreign("variant_id").References("id").On("product_variants")
+		table.Foreign("location_id").References("id").On("storage_locations")
+
+		table.Index("variant_id")
+		table.Index("location_id")
+		table.Index("is_active")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000038CreateReorderRulesTable) Down() error {
+	return facades.Schema().DropIfExists("reorder_rules")
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000039CreateReplenishmentSuggestionsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000039CreateReplenishmentSuggestionsTable) Signature() string {
+	return "20240101000039_create_replenishment_suggestions_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000039CreateReplenishmentSuggestionsTable) Up() error {
+	return facades.Schema().Create("replenishment_suggestions", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("reorder_rule_id")
+		table.UnsignedBigInteger("product_id")
+		table.UnsignedBigInteger("variant_id")
+		table.UnsignedBigInteger("location_id").Nullable()
+		table.Decimal("on_hand").Total(10).Places(3).Default(0)
+		table.Decimal("reserved").Total(10).Places(3).Default(0)
+		table.Decimal("available").Total(10).Places(3).Default(0)
+		table.Decimal("suggested_quantity").Total(10).Places(3).Default(0)
+		table.String("unit", 50).Nullable()
+		table.String("status", 20).Default("draft")
+		table.Boolean("is_critical").Default(false)
+		table.Timestamp("notified_at").Nullable()
+		table.UnsignedBigInteger("handled_by").Nullable()
+		table.Timestamp("handled_at").Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("reorder_rule_id").References("id").On("reorder_rules")
+		table.Foreign("product_id").References("id").On("products")
+		table.Foreign("variant_id").References("id").On("product_variants")
+		table.Foreign("location_id").References("id").On("storage_locations")
+		table.Foreign("handled_by").References("id").On("users")
+
+		table.Index("reorder_rule_id")
+		table.Index("variant_id")
+		table.Index("status")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000039CreateReplenishmentSuggestionsTable) Down() error {
+	return facades.Schema().DropIfExists("replenishment_suggestions")
+}
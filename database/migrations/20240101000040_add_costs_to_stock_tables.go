@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000040AddCostsToStockTables struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000040AddCostsToStockTables) Signature() string {
+	return "20240101000040_add_costs_to_stock_tables"
+}
+
+// Up Run the migrations.
+func (r *M20240101000040AddCostsToStockTables) Up() error {
+	if err := facades.Schema().Table("stock_movements", func(table schema.Blueprint) {
+		table.Decimal("unit_cost").Total(14).Places(4).Nullable()
+		table.Decimal("total_cost").Total(16).Places(4).Default(0)
+	}); err != nil {
+		return err
+	}
+
+	return facades.Schema().Table("stock_levels", func(table schema.Blueprint) {
+		table.Decimal("average_cost").Total(14).Places(4).Default(0)
+		table.Decimal("total_value").Total(16).Places(4).Default(0)
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000040AddCostsToStockTables) Down() error {
+	if err := facades.Schema().Table("stock_levels", func(table schema.Blueprint) {
+		table.DropColumn("average_cost", "total_value")
+	}); err != nil {
+		return err
+	}
+
+	return facades.Schema().Table("stock_movements", func(table schema.Blueprint) {
+		table.DropColumn("unit_cost", "total_cost")
+	})
+}
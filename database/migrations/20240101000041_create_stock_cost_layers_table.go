@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000041CreateStockCostLayersTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000041CreateStockCostLayersTable) Signature() string {
+	return "20240101000041_create_stock_cost_layers_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000041CreateStockCostLayersTable) Up() error {
+	return facades.Schema().Create("stock_cost_layers", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("stock_level_id")
+		table.UnsignedBigInteger("movement_id")
+		table.Decimal("quantity").Total(10).Places(3)
+		table.Decimal("remaining_quantity").Total(10).Places(3)
+		table.Decimal("unit_cost").Total(14).Places(4)
+		table.TimestampsTz()
+
+		table.Foreign("stock_level_id").References("id").On("stock_levels")
+		table.Foreign("movement_id").References("id").On("stock_movements")
+
+		table.Index("stock_level_id", "remaining_quantity")
+		table.Index("movement_id")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000041CreateStockCostLayersTable) Down() error {
+	return facades.Schema().DropIfExists("stock_cost_layers")
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000042CreateStockLotsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000042CreateStockLotsTable) Signature() string {
+	return "20240101000042_create_stock_lots_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000042CreateStockLotsTable) Up() error {
+	return facades.Schema().Create("stock_lots", func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("lot_number", 100)
+		table.UnsignedBigInteger("product_id")
+		table.UnsignedBigInteger("variant_id")
+		table.String("heat_number", 100).Nullable()
+		table.String("supplier", 255).Nullable()
+		table.String("certificate_url", 500).Nullable()
+		table.Timestamp("received_at").Nullable()
+		table.Text("notes").Nullable()
+		table.UnsignedBigInteger("created_by")
+		table.TimestampsTz()
+
+		table.Foreign("product_id").References("id").On("products")
+		table.Foreign("variant_id").References("id").On("product_variants")
+		table.Foreign("created_by").References("id").On("users")
+
+		table.Unique("lot_number")
+		table.Index("variant_id")
+		table.Index("heat_number")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000042CreateStockLotsTable) Down() error {
+	return facades.Schema().DropIfExists("stock_lots")
+}
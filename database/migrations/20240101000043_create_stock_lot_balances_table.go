@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000043CreateStockLotBalancesTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000043CreateStockLotBalancesTable) Signature() string {
+	return "20240101000043_create_stock_lot_balances_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000043CreateStockLotBalancesTable) Up() error {
+	return facades.Schema().Create("stock_lot_balances", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("lot_id")
+		table.UnsignedBigInteger("location_id")
+		table.Decimal("quantity").Total(10).Places(3).Default(0)
+		table.TimestampsTz()
+
+		table.Foreign("lot_id").References("id").On("stock_lots")
+		table.Foreign("location_id").References("id").On("storage_locations")
+
+		table.Unique("lot_id", "location_id")
+		table.Index("location_id")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000043CreateStockLotBalancesTable) Down() error {
+	return facades.Schema().DropIfExists("stock_lot_balances")
+}
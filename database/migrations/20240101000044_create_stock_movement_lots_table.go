@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000044CreateStockMovementLotsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000044CreateStockMovementLotsTable) Signature() string {
+	return "20240101000044_create_stock_movement_lots_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000044CreateStockMovementLotsTable) Up() error {
+	return facades.Schema().Create("stock_movement_lots", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("movement_id")
+		table.UnsignedBigInteger("lot_id")
+		table.Decimal("quantity").Total(10).Places(3)
+		table.TimestampsTz()
+
+		table.Foreign("movement_id").References("id").On("stock_movements")
+		table.Foreign("lot_id").References("id").On("stock_lots")
+
+		table.Index("movement_id")
+		table.Index("lot_id")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000044CreateStockMovementLotsTable) Down() error {
+	return facades.Schema().DropIfExists("stock_movement_lots")
+}
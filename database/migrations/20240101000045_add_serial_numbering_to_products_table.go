@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000045AddSerialNumberingToProductsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000045AddSerialNumberingToProductsTable) Signature() string {
+	return "20240101000045_add_serial_numbering_to_products_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000045AddSerialNumberingToProductsTable) Up() error {
+	return facades.Schema().Table("products", func(table schema.Blueprint) {
+		table.Boolean("is_serialized").Default(false)
+		table.String("serial_pattern", 100).Nullable()
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000045AddSerialNumberingToProductsTable) Down() error {
+	return facades.Schema().Table("products", func(table schema.Blueprint) {
+		table.DropColumn("is_serialized", "serial_pattern")
+	})
+}
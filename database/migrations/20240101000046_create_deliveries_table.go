@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000046CreateDeliveriesTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000046CreateDeliveriesTable) Signature() string {
+	return "20240101000046_create_deliveries_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000046CreateDeliveriesTable) Up() error {
+	return facades.Schema().Create("deliveries", func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("delivery_number", 100)
+		table.UnsignedBigInteger("client_id")
+		table.UnsignedBigInteger("client_site_id")
+		table.Timestamp("delivered_at")
+		table.Text("notes").Nullable()
+		table.UnsignedBigInteger("created_by")
+		table.TimestampsTz()
+
+		table.Foreign("client_id").References("id").On("clients")
+		table.Foreign("client_site_id").References("id").On("client_sites")
+		table.Foreign("created_by").References("id").On("users")
+
+		table.Unique("delivery_number")
+		table.Index("client_id")
+		table.Index("client_site_id")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000046CreateDeliveriesTable) Down() error {
+	return facades.Schema().DropIfExists("deliveries")
+}
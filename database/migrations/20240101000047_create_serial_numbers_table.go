@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000047CreateSerialNumbersTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000047CreateSerialNumbersTable) Signature() string {
+	return "20240101000047_create_serial_numbers_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000047CreateSerialNumbersTable) Up() error {
+	return facades.Schema().Create("serial_numbers", func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("serial_number", 100)
+		table.UnsignedBigInteger("product_id")
+		table.UnsignedBigInteger("variant_id").Nullable()
+		table.UnsignedBigInteger("order_fabrication_id")
+		table.UnsignedBigInteger("movement_id")
+		table.UnsignedBigInteger("location_id").Nullable()
+		table.String("status", 20).Default("in_stock")
+		table.UnsignedBigInteger("delivery_id").Nullable()
+		table.UnsignedBigInteger("delivery_movement_id").Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("product_id").References("id").On("products")
+		table.Foreign("variant_id").References("id").On("product_variants")
+		table.Foreign("order_fabrication_id").References("id").On("order_fabrications")
+		table.Foreign("movement_id").References("id").On("stock_movements")
+		table.Foreign("location_id").References("id").On("storage_locations")
+		table.Foreign("delivery_id").References("id").On("deliveries")
+		table.Foreign("delivery_movement_id").References("id").On("stock_movements")
+
+		table.Unique("serial_number")
+		table.Index("order_fabrication_id")
+		table.Index("delivery_id")
+		table.Index("product_id", "status")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000047CreateSerialNumbersTable) Down() error {
+	return facades.Schema().DropIfExists("serial_numbers")
+}
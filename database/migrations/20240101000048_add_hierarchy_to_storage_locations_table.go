@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000048AddHierarchyToStorageLocationsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000048AddHierarchyToStorageLocationsTable) Signature() string {
+	return "20240101000048_add_hierarchy_to_storage_locations_table"
+}
+
+// Up Run the migrations. Existing flat locations become bins so they keep holding stock.
+func (r *M20240101000048AddHierarchyToStorageLocationsTable) Up() error {
+	return facades.Schema().Table("storage_locations", func(table schema.Blueprint) {
+		table.UnsignedBigInteger("parent_id").Nullable()
+		table.String("type", 20).Default("bin")
+
+		table.Foreign("parent_id").References("id").On("storage_locations")
+		table.Index("parent_id")
+		table.Index("type")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000048AddHierarchyToStorageLocationsTable) Down() error {
+	return facades.Schema().Table("storage_locations", func(table schema.Blueprint) {
+		table.DropForeign("parent_id")
+		table.DropColumn("parent_id", "type")
+	})
+}
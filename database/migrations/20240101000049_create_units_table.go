@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000049CreateUnitsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000049CreateUnitsTable) Signature() string {
+	return "20240101000049_create_units_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000049CreateUnitsTable) Up() error {
+	return facades.Schema().Create("units", func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("code", 50)
+		table.String("name", 100)
+		table.String("category", 20)
+		table.Decimal("factor").Total(18).Places(6).Default(1)
+		table.TimestampsTz()
+
+		table.Unique("code")
+		table.Index("category")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000049CreateUnitsTable) Down() error {
+	return facades.Schema().DropIfExists("units")
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000050CreateProductUnitConversionsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000050CreateProductUnitConversionsTable) Signature() string {
+	return "20240101000050_create_product_unit_conversions_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000050CreateProductUnitConversionsTable) Up() error {
+	return facades.Schema().Create("product_unit_conversions", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("product_id")
+		table.UnsignedBigInteger("variant_id").Nullable()
+		table.String("from_unit", 50)
+		table.String("to_unit", 50)
+		table.Decimal("factor").Total(18).Places(6)
+		table.Text("notes").Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("product_id").References("id").On("products")
+		table.Foreign("variant_id").References("id").On("product_variants")
+
+		table.Index("product_id", "variant_id")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000050CreateProductUnitConversionsTable) Down() error {
+	return facades.Schema().DropIfExists("product_unit_conversions")
+}
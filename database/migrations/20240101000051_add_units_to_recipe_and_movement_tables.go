@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000051AddUnitsToRecipeAndMovementTables struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000051AddUnitsToRecipeAndMovementTables) Signature() string {
+	return "20240101000051_add_units_to_recipe_and_movement_tables"
+}
+
+// Up Run the migrations. Recipe items without a unit use the unit of their material, and
+// movements keep the quantity as entered when it was converted to the stock unit.
+func (r *M20240101000051AddUnitsToRecipeAndMovementTables) Up() error {
+	if err := facades.Schema().Table("recipe_variant_items", func(table schema.Blueprint) {
+		table.String("unit", 50).Nullable()
+	}); err != nil {
+		return err
+	}
+
+	return facades.Schema().Table("stock_movements", func(table schema.Blueprint) {
+		table.Decimal("entered_quantity").Total(12).Places(3).Nullable()
+		table.String("entered_unit", 50).Nullable()
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000051AddUnitsToRecipeAndMovementTables) Down() error {
+	if err := facades.Schema().Table("stock_movements", func(table schema.Blueprint) {
+		table.DropColumn("entered_quantity", "entered_unit")
+	}); err != nil {
+		return err
+	}
+
+	return facades.Schema().Table("recipe_variant_items", func(table schema.Blueprint) {
+		table.DropColumn("unit")
+	})
+}
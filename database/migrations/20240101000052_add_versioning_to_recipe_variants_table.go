@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000052AddVersioningToRecipeVariantsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000052AddVersioningToRecipeVariantsTable) Signature() string {
+	return "20240101000052_add_versioning_to_recipe_variants_table"
+}
+
+// Up Run the migrations. Existing recipes become version 1, active since always.
+func (r *M20240101000052AddVersioningToRecipeVariantsTable) Up() error {
+	return facades.Schema().Table("recipe_variants", func(table schema.Blueprint) {
+		table.DropUnique("product_id", "variant_id")
+		table.Integer("version").Default(1)
+		table.String("status", 20).Default("active")
+		table.Timestamp("effective_from").Nullable()
+
+		table.Unique("variant_id", "version")
+		table.Index("status")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000052AddVersioningToRecipeVariantsTable) Down() error {
+	return facades.Schema().Table("recipe_variants", func(table schema.Blueprint) {
+		table.DropUnique("variant_id", "version")
+		table.DropIndex("status")
+		table.DropColumn("version", "status", "effective_from")
+		table.Unique("product_id", "variant_id")
+	})
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000053CreateEngineeringChangeOrdersTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000053CreateEngineeringChangeOrdersTable) Signature() string {
+	return "20240101000053_create_engineering_change_orders_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000053CreateEngineeringChangeOrdersTable) Up() error {
+	return facades.Schema().Create("engineering_change_orders", func(table schema.Blueprint) {
+		table.ID("id")
+		table.String("eco_number", 100)
+		table.UnsignedBigInteger("variant_id")
+		table.UnsignedBigInteger("recipe_variant_id")
+		table.UnsignedBigInteger("previous_recipe_id").Nullable()
+		table.Text("reason")
+		table.String("status", 20).Default("pending")
+		table.Timestamp("effective_from").Nullable()
+		table.UnsignedBigInteger("requested_by")
+		table.UnsignedBigInteger("approved_by").Nullable()
+		table.Timestamp("approved_at").Nullable()
+		table.Text("rejection_reason").Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("variant_id").References("id").On("product_variants")
+		table.Foreign("recipe_variant_id").References("id").On("recipe_variants")
+		table.Foreign("previous_recipe_id").References("id").On("recipe_variants")
+		table.Foreign("requested_by").References("id").On("users")
+		table.Foreign("approved_by").References("id").On("users")
+
+		table.Unique("eco_number")
+		table.Index("variant_id")
+		table.Index("recipe_variant_id")
+		table.Index("status")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000053CreateEngineeringChangeOrdersTable) Down() error {
+	return facades.Schema().DropIfExists("engineering_change_orders")
+}
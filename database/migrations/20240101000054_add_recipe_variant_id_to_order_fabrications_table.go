@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000054AddRecipeVariantIdToOrderFabricationsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000054AddRecipeVariantIdToOrderFabricationsTable) Signature() string {
+	return "20240101000054_add_recipe_variant_id_to_order_fabrications_table"
+}
+
+// Up Run the migrations. Orders are pinned to the recipe version exploded at release.
+func (r *M20240101000054AddRecipeVariantIdToOrderFabricationsTable) Up() error {
+	return facades.Schema().Table("order_fabrications", func(table schema.Blueprint) {
+		table.UnsignedBigInteger("recipe_variant_id").Nullable()
+
+		table.Foreign("recipe_variant_id").References("id").On("recipe_variants")
+		table.Index("recipe_variant_id")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000054AddRecipeVariantIdToOrderFabricationsTable) Down() error {
+	return facades.Schema().Table("order_fabrications", func(table schema.Blueprint) {
+		table.DropForeign("recipe_variant_id")
+		table.DropColumn("recipe_variant_id")
+	})
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000055CreateStandardCostsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000055CreateStandardCostsTable) Signature() string {
+	return "20240101000055_create_standard_costs_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000055CreateStandardCostsTable) Up() error {
+	return facades.Schema().Create("standard_costs", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("product_id")
+		table.UnsignedBigInteger("variant_id")
+		table.UnsignedBigInteger("recipe_variant_id").Nullable()
+		table.String("cost_source", 20)
+		table.Decimal("material_cost").Total(14).Places(4).Default(0)
+		table.Decimal("labour_cost").Total(14).Places(4).Default(0)
+		table.Decimal("total_cost").Total(14).Places(4).Default(0)
+		table.Timestamp("computed_at")
+		table.TimestampsTz()
+
+		table.Foreign("product_id").References("id").On("products")
+		table.Foreign("variant_id").References("id").On("product_variants")
+		table.Foreign("recipe_variant_id").References("id").On("recipe_variants")
+
+		table.Unique("variant_id")
+		table.Index("product_id")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000055CreateStandardCostsTable) Down() error {
+	return facades.Schema().DropIfExists("standard_costs")
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000056AddScrapAndYieldToRecipeTables struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000056AddScrapAndYieldToRecipeTables) Signature() string {
+	return "20240101000056_add_scrap_and_yield_to_recipe_tables"
+}
+
+// Up Run the migrations. Items lose a percentage of their material as scrap and recipes
+// deliver a percentage of their output as good parts.
+func (r *M20240101000056AddScrapAndYieldToRecipeTables) Up() error {
+	if err := facades.Schema().Table("recipe_variants", func(table schema.Blueprint) {
+		table.Decimal("yield_percent").Total(5).Places(2).Default(100)
+	}); err != nil {
+		return err
+	}
+
+	return facades.Schema().Table("recipe_variant_items", func(table schema.Blueprint) {
+		table.Decimal("scrap_percent").Total(5).Places(2).Default(0)
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000056AddScrapAndYieldToRecipeTables) Down() error {
+	if err := facades.Schema().Table("recipe_variant_items", func(table schema.Blueprint) {
+		table.DropColumn("scrap_percent")
+	}); err != nil {
+		return err
+	}
+
+	return facades.Schema().Table("recipe_variants", func(table schema.Blueprint) {
+		table.DropColumn("yield_percent")
+	})
+}
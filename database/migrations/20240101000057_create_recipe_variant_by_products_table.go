@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000057CreateRecipeVariantByProductsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000057CreateRecipeVariantByProductsTable) Signature() string {
+	return "20240101000057_create_recipe_variant_by_products_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000057CreateRecipeVariantByProductsTable) Up() error {
+	return facades.Schema().Create("recipe_variant_by_products", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("recipe_variant_id")
+		table.UnsignedBigInteger("variant_id")
+		table.Decimal("quantity").Total(10).Places(3)
+		table.String("unit", 50).Nullable()
+		table.Text("notes").Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("recipe_variant_id").References("id").On("recipe_variants")
+		table.Foreign("variant_id").References("id").On("product_variants")
+
+		table.Index("recipe_variant_id")
+		table.Index("variant_id")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000057CreateRecipeVariantByProductsTable) Down() error {
+	return facades.Schema().DropIfExists("recipe_variant_by_products")
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000058AddGenerationRulesToRecipeProductsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000058AddGenerationRulesToRecipeProductsTable) Signature() string {
+	return "20240101000058_add_generation_rules_to_recipe_products_table"
+}
+
+// Up Run the migrations. Product-level recipe lines gain the rules generating the variant
+// recipes: the material variant is fixed or matched on an attribute, and the quantity may be
+// scaled by a numeric attribute of the variant.
+func (r *M20240101000058AddGenerationRulesToRecipeProductsTable) Up() error {
+	return facades.Schema().Table("recipe_products", func(table schema.Blueprint) {
+		table.UnsignedBigInteger("material_variant_id").Nullable()
+		table.Decimal("quantity").Total(10).Places(3).Default(0)
+		table.String("unit", 50).Nullable()
+		table.Decimal("scrap_percent").Total(5).Places(2).Default(0)
+		table.String("match_attribute", 100).Nullable()
+		table.String("material_attribute", 100).Nullable()
+		table.String("scale_attribute", 100).Nullable()
+		table.Decimal("scale_factor").Total(18).Places(6).Default(1)
+
+		table.Foreign("material_variant_id").References("id").On("product_variants")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000058AddGenerationRulesToRecipeProductsTable) Down() error {
+	return facades.Schema().Table("recipe_products", func(table schema.Blueprint) {
+		table.DropForeign("material_variant_id")
+		table.DropColumn("material_variant_id", "quantity", "unit", "scrap_percent", "match_attribute", "material_attribute", "scale_attribute", "scale_factor")
+	})
+}
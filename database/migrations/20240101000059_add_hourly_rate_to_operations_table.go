@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000059AddHourlyRateToOperationsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000059AddHourlyRateToOperationsTable) Signature() string {
+	return "20240101000059_add_hourly_rate_to_operations_table"
+}
+
+// Up Run the migrations. The rate costs the time routings spend on an operation.
+func (r *M20240101000059AddHourlyRateToOperationsTable) Up() error {
+	return facades.Schema().Table("operations", func(table schema.Blueprint) {
+		table.Decimal("hourly_rate").Total(12).Places(4).Default(0)
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000059AddHourlyRateToOperationsTable) Down() error {
+	return facades.Schema().Table("operations", func(table schema.Blueprint) {
+		table.DropColumn("hourly_rate")
+	})
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000060CreateRoutingStepsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000060CreateRoutingStepsTable) Signature() string {
+	return "20240101000060_create_routing_steps_table"
+}
+
+// Up Run the migrations. Steps without variant form the routing of the product; a variant
+// with steps of its own follows those instead.
+func (r *M20240101000060CreateRoutingStepsTable) Up() error {
+	return facades.Schema().Create("routing_steps", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("product_id")
+		table.UnsignedBigInteger("variant_id").Nullable()
+		table.UnsignedBigInteger("operation_id")
+		table.Integer("sequence")
+		table.Decimal("setup_minutes").Total(10).Places(2).Default(0)
+		table.Decimal("run_minutes").Total(10).Places(3).Default(0)
+		table.String("workstation", 100).Nullable()
+		table.String("required_role", 50).Nullable()
+		table.Text("notes").Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("product_id").References("id").On("products")
+		table.Foreign("variant_id").References("id").On("product_variants")
+		table.Foreign("operation_id").References("id").On("operations")
+
+		table.Index("product_id", "variant_id")
+		table.Index("operation_id")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000060CreateRoutingStepsTable) Down() error {
+	return facades.Schema().DropIfExists("routing_steps")
+}
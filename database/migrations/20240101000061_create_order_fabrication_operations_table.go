@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000061CreateOrderFabricationOperationsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000061CreateOrderFabricationOperationsTable) Signature() string {
+	return "20240101000061_create_order_fabrication_operations_table"
+}
+
+// Up Run the migrations.
+func (r *M20240101000061CreateOrderFabricationOperationsTable) Up() error {
+	return facades.Schema().Create("order_fabrication_operations", func(table schema.Blueprint) {
+		table.ID("id")
+		table.UnsignedBigInteger("order_fabrication_id")
+		table.UnsignedBigInteger("operation_id")
+		table.UnsignedBigInteger("routing_step_id").Nullable()
+		table.Integer("sequence")
+		table.Decimal("setup_minutes").Total(10).Places(2).Default(0)
+		table.Decimal("run_minutes").Total(10).Places(3).Default(0)
+		table.Decimal("planned_minutes").Total(12).Places(2).Default(0)
+		table.String("workstation", 100).Nullable()
+		table.String("required_role", 50).Nullable()
+		table.Text("notes").Nullable()
+		table.TimestampsTz()
+
+		table.Foreign("order_fabrication_id").References("id").On("order_fabrications")
+		table.Foreign("operation_id").References("id").On("operations")
+
+		table.Index("order_fabrication_id")
+		table.Index("operation_id")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000061CreateOrderFabricationOperationsTable) Down() error {
+	return facades.Schema().DropIfExists("order_fabrication_operations")
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000062AddOutMovementIdToStockTransferItemsTable struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000062AddOutMovementIdToStockTransferItemsTable) Signature() string {
+	return "20240101000062_add_out_movement_id_to_stock_transfer_items_table"
+}
+
+// Up Run the migrations. Each line keeps the movement that dispatched it, which the receipt
+// or the return of the line copies.
+func (r *M20240101000062AddOutMovementIdToStockTransferItemsTable) Up() error {
+	return facades.Schema().Table("stock_transfer_items", func(table schema.Blueprint) {
+		table.UnsignedBigInteger("out_movement_id").Nullable()
+
+		table.Foreign("out_movement_id").References("id").On("stock_movements")
+	})
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000062AddOutMovementIdToStockTransferItemsTable) Down() error {
+	return facades.Schema().Table("stock_transfer_items", func(table schema.Blueprint) {
+		table.DropForeign("out_movement_id")
+		table.DropColumn("out_movement_id")
+	})
+}
@@ -0,0 +1,112 @@
+package migrations
+
+import (
+	"github.com/goravel/framework/contracts/database/schema"
+	"github.com/goravel/framework/facades"
+)
+
+type M20240101000063WidenQuantityColumns struct{}
+
+// Signature The unique signature for the migration.
+func (r *M20240101000063WidenQuantityColumns) Signature() string {
+	return "20240101000063_widen_quantity_columns"
+}
+
+// Up Run the migrations. The quantity columns created with the default precision kept two
+// decimals only; quantities in kg or m keep the three decimals the services round to.
+func (r *M20240101000063WidenQuantityColumns) Up() error {
+	if err := facades.Schema().Table("stock_movements", func(table schema.Blueprint) {
+		table.Decimal("quantity").Total(10).Places(3).Change()
+	}); err != nil {
+		return err
+	}
+
+	if err := facades.Schema().Table("stock_levels", func(table schema.Blueprint) {
+		table.Decimal("quantity").Total(10).Places(3).Default(0).Change()
+	}); err != nil {
+		return err
+	}
+
+	if err := facades.Schema().Table("order_fabrications", func(table schema.Blueprint) {
+		table.Decimal("quantity").Total(10).Places(3).Change()
+	}); err != nil {
+		return err
+	}
+
+	if err := facades.Schema().Table("recipe_variants", func(table schema.Blueprint) {
+		table.Decimal("output_quantity").Total(10).Places(3).Change()
+	}); err != nil {
+		return err
+	}
+
+	if err := facades.Schema().Table("recipe_variant_items", func(table schema.Blueprint) {
+		table.Decimal("quantity").Total(10).Places(3).Change()
+	}); err != nil {
+		return err
+	}
+
+	if err := facades.Schema().Table("production_material_requirements", func(table schema.Blueprint) {
+		table.Decimal("required_quantity").Total(10).Places(3).Change()
+		table.Decimal("stock_quantity").Total(10).Places(3).Default(0).Change()
+		table.Decimal("request_quantity").Total(10).Places(3).Default(0).Change()
+	}); err != nil {
+		return err
+	}
+
+	if err := facades.Schema().Table("stock_requests", func(table schema.Blueprint) {
+		table.Decimal("quantity").Total(10).Places(3).Change()
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Down Reverse the migrations.
+func (r *M20240101000063WidenQuantityColumns) Down() error {
+	if err := facades.Schema().Table("stock_movements", func(table schema.Blueprint) {
+		table.Decimal("quantity").Change()
+	}); err != nil {
+		return err
+	}
+
+	if err := facades.Schema().Table("stock_levels", func(table schema.Blueprint) {
+		table.Decimal("quantity").Default(0).Change()
+	}); err != nil {
+		return err
+	}
+
+	if err := facades.Schema().Table("order_fabrications", func(table schema.Blueprint) {
+		table.Decimal("quantity").Change()
+	}); err != nil {
+		return err
+	}
+
+	if err := facades.Schema().Table("recipe_variants", func(table schema.Blueprint) {
+		table.Decimal("output_quantity").Change()
+	}); err != nil {
+		return err
+	}
+
+	if err := facades.Schema().Table("recipe_variant_items", func(table schema.Blueprint) {
+		table.Decimal("quantity").Change()
+	}); err != nil {
+		return err
+	}
+
+	if err := facades.Schema().Table("production_material_requirements", func(table schema.Blueprint) {
+		table.Decimal("required_quantity").Change()
+		table.Decimal("stock_quantity").Default(0).Change()
+		table.Decimal("request_quantity").Default(0).Change()
+	}); err != nil {
+		return err
+	}
+
+	if err := facades.Schema().Table("stock_requests", func(table schema.Blueprint) {
+		table.Decimal("quantity").Change()
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
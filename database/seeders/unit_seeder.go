@@ -0,0 +1,57 @@
+package seeders
+
+import (
+	"github.com/goravel/framework/facades"
+
+	"pms/app/models"
+)
+
+type UnitSeeder struct {
+}
+
+// Signature The unique signature for the seeder.
+func (s *UnitSeeder) Signature() string {
+	return "UnitSeeder"
+}
+
+// Run executes the seeder.
+func (s *UnitSeeder) Run() error {
+	// Check if units already exist
+	count, err := facades.Orm().Query().Model(&models.Unit{}).Count()
+	if err != nil {
+		facades.Log().Error("Failed to count units")
+		return err
+	}
+
+	if count > 0 {
+		facades.Log().Info("Units already exist, skipping seeder")
+		return nil
+	}
+
+	// Factors are expressed in the base unit of each category: kg, m, m2, l
+	units := []models.Unit{
+		{Code: "kg", Name: "Kilogramme", Category: "mass", Factor: 1},
+		{Code: "g", Name: "Gramme", Category: "mass", Factor: 0.001},
+		{Code: "t", Name: "Tonne", Category: "mass", Factor: 1000},
+		{Code: "m", Name: "Mètre", Category: "length", Factor: 1},
+		{Code: "cm", Name: "Centimètre", Category: "length", Factor: 0.01},
+		{Code: "mm", Name: "Millimètre", Category: "length", Factor: 0.001},
+		{Code: "m2", Name: "Mètre carré", Category: "area", Factor: 1},
+		{Code: "mm2", Name: "Millimètre carré", Category: "area", Factor: 0.000001},
+		{Code: "l", Name: "Litre", Category: "volume", Factor: 1},
+		{Code: "m3", Name: "Mètre cube", Category: "volume", Factor: 1000},
+		{Code: "pcs", Name: "Pièce", Category: "count", Factor: 1},
+		{Code: "sheet", Name: "Tôle", Category: "count", Factor: 1},
+		{Code: "bar", Name: "Barre", Category: "count", Factor: 1},
+	}
+
+	for _, unit := range units {
+		if err := facades.Orm().Query().Create(&unit); err != nil {
+			facades.Log().Error("Failed to create unit: " + unit.Code)
+			return err
+		}
+	}
+
+	facades.Log().Info("Units seeded successfully")
+	return nil
+}
@@ -53,9 +53,15 @@ func Api() {
 		// List storage locations with pagination, search and filtering
 		router.Get("/storage-locations", storageLocationController.Index)
 
+		// Get storage locations tree with subtree stock
+		router.Get("/storage-locations/tree", storageLocationController.Tree)
+
 		// Get specific storage location
 		router.Get("/storage-locations/{id}", storageLocationController.Show)
 
+		// Get stock held by a storage location and its children
+		router.Get("/storage-locations/{id}/stock", storageLocationController.Stock)
+
 		// Create new storage location
 		router.Post("/storage-locations", storageLocationController.Store)
 
@@ -128,7 +134,79 @@ func Api() {
 		router.Get("/products/bulk/search", productController.ListAllVariantsForBulkEdit)
 		router.Post("/products/bulk/update", productController.BulkUpdateVariants)
 		// Note: Step 5 (Define storage location) is handled in the main product creation/update
-		// Note: Step 6 (Define recipe) will be implemented separately as recipe management
+		// Note: Step 6 (Define recipe) is handled by the recipe routes below
+	})
+
+	// Recipe (BOM) management routes (methodes/admin write)
+	recipeController := controllers.NewRecipeController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		router.Get("/recipes", recipeController.Index)
+		router.Get("/recipes/{id}", recipeController.Show)
+		router.Post("/recipes", recipeController.Store)
+		router.Put("/recipes/{id}", recipeController.Update)
+		router.Delete("/recipes/{id}", recipeController.Destroy)
+
+		// Copy a recipe onto another variant
+		router.Post("/recipes/{id}/copy", recipeController.Copy)
+
+		// Start a draft version from a recipe version
+		router.Post("/recipes/{id}/versions", recipeController.NewVersion)
+
+		// Recipe of a variant
+		router.Get("/product-variants/{id}/recipe", recipeController.ShowByVariant)
+
+		// Indented multi-level explosion (?levels=all|n&qty=)
+		router.Get("/variants/{id}/bom", recipeController.Bom)
+
+		// Recipes, sub-assemblies and open orders depending on a material
+		router.Get("/variants/{id}/where-used", recipeController.WhereUsed)
+	})
+
+	// Product-level recipe templates and the generation of the variant recipes (methodes/admin write)
+	recipeTemplateController := controllers.NewRecipeTemplateController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		router.Get("/products/{id}/recipe-template", recipeTemplateController.Show)
+		router.Put("/products/{id}/recipe-template", recipeTemplateController.Update)
+
+		// Generate the variant recipes ({"dry_run": true} only reports the changes)
+		router.Post("/products/{id}/recipe-template/generate", recipeTemplateController.Generate)
+	})
+
+	// Shop floor operations and product routings (methodes/admin write)
+	operationController := controllers.NewOperationController()
+	routingController := controllers.NewRoutingController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		router.Get("/operations", operationController.Index)
+		router.Post("/operations", operationController.Store)
+		router.Put("/operations/{id}", operationController.Update)
+
+		// Routing of a product, or of a variant (?variant_id= / "variant_id" in the payload)
+		router.Get("/products/{id}/routing", routingController.Show)
+		router.Put("/products/{id}/routing", routingController.Update)
+	})
+
+	// Standard cost routes (methodes/admin write)
+	standardCostController := controllers.NewStandardCostController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		// Margin report of the stored standard costs
+		router.Get("/standard-costs", standardCostController.Index)
+		router.Post("/standard-costs/roll-up", standardCostController.RollUpAll)
+
+		// Cost breakdown of a variant (?source=purchase|valuation) and its storage
+		router.Get("/variants/{id}/cost-roll-up", standardCostController.RollUp)
+		router.Post("/variants/{id}/standard-cost", standardCostController.Store)
+	})
+
+	// Engineering change order routes (methodes/admin write)
+	engineeringChangeOrderController := controllers.NewEngineeringChangeOrderController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		router.Get("/engineering-change-orders", engineeringChangeOrderController.Index)
+		router.Get("/engineering-change-orders/{id}", engineeringChangeOrderController.Show)
+		router.Post("/engineering-change-orders", engineeringChangeOrderController.Store)
+
+		// Approval workflow
+		router.Post("/engineering-change-orders/{id}/approve", engineeringChangeOrderController.Approve)
+		router.Post("/engineering-change-orders/{id}/reject", engineeringChangeOrderController.Reject)
 	})
 
 	// Client management routes (commercial/admin only)
@@ -172,6 +250,185 @@ func Api() {
 		router.Delete("/clients/{clientId}/sites/{siteId}", clientSiteController.Destroy)
 	})
 
+	// Manufacturing order routes (commercial/admin manage, all roles can view)
+	orderFabricationController := controllers.NewOrderFabricationController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		// List manufacturing orders with pagination, search and filtering
+		router.Get("/order-fabrications", orderFabricationController.Index)
+
+		// Get specific manufacturing order with relationships
+		router.Get("/order-fabrications/{id}", orderFabricationController.Show)
+
+		// Create new manufacturing order
+		router.Post("/order-fabrications", orderFabricationController.Store)
+
+		// Update existing manufacturing order
+		router.Put("/order-fabrications/{id}", orderFabricationController.Update)
+
+		// Cancel manufacturing order
+		router.Post("/order-fabrications/{id}/cancel", orderFabricationController.Cancel)
+
+		// Status workflow: transition and history with allowed next statuses
+		router.Post("/order-fabrications/{id}/transition", orderFabricationController.Transition)
+		router.Get("/order-fabrications/{id}/history", orderFabricationController.History)
+
+		// Routing steps copied onto the order at release
+		router.Get("/order-fabrications/{id}/operations", orderFabricationController.Operations)
+
+		// Material requirements exploded from the variant recipe on release
+		router.Get("/order-fabrications/{id}/materials", orderFabricationController.Materials)
+
+		// Recompute material requirements and stock requests for shortages
+		router.Post("/order-fabrications/{id}/materials/refresh", orderFabricationController.RefreshMaterials)
+
+		// Consume reserved material into out movements
+		router.Post("/order-fabrications/{id}/materials/{requirementId}/consume", orderFabricationController.ConsumeMaterial)
+	})
+
+	// Stock request routes (magasinier approves and fulfils, achat sees shortages)
+	stockRequestController := controllers.NewStockRequestController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		// List stock requests with pagination, search and filtering
+		router.Get("/stock-requests", stockRequestController.Index)
+
+		// Get specific stock request
+		router.Get("/stock-requests/{id}", stockRequestController.Show)
+
+		// Create new stock request
+		router.Post("/stock-requests", stockRequestController.Store)
+
+		// Approval workflow
+		router.Post("/stock-requests/{id}/approve", stockRequestController.Approve)
+		router.Post("/stock-requests/{id}/reject", stockRequestController.Reject)
+		router.Post("/stock-requests/{id}/fulfil", stockRequestController.Fulfil)
+	})
+
+	// Stock level routes with reserved and available quantities
+	stockLevelController := controllers.NewStockLevelController()
+	facades.Route().Middleware(middleware.Auth()).Get("/stock-levels", stockLevelController.Index)
+
+	// Stock movement ledger routes (magasinier/admin post movements)
+	stockMovementController := controllers.NewStockMovementController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		// Ledger with pagination and filtering
+		router.Get("/stock-movements", stockMovementController.Index)
+
+		// Get specific stock movement
+		router.Get("/stock-movements/{id}", stockMovementController.Show)
+
+		// Post movement and update the stock level
+		router.Post("/stock-movements", stockMovementController.Store)
+	})
+
+	// Stock transfer routes between storage locations (magasinier/admin manage)
+	stockTransferController := controllers.NewStockTransferController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		// List stock transfers with pagination, search and filtering
+		router.Get("/stock-transfers", stockTransferController.Index)
+
+		// Get specific stock transfer with its lines
+		router.Get("/stock-transfers/{id}", stockTransferController.Show)
+
+		// Create new draft stock transfer
+		router.Post("/stock-transfers", stockTransferController.Store)
+
+		// Transfer workflow: dispatch puts goods in transit, receive books them at destination
+		router.Post("/stock-transfers/{id}/dispatch", stockTransferController.Dispatch)
+		router.Post("/stock-transfers/{id}/receive", stockTransferController.Receive)
+		router.Post("/stock-transfers/{id}/cancel", stockTransferController.Cancel)
+	})
+
+	// Inventory count routes (magasinier/admin only)
+	inventoryCountController := controllers.NewInventoryCountController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		// List inventory count sessions
+		router.Get("/inventory-counts", inventoryCountController.Index)
+
+		// Get count session with lines, variances and concurrent movements
+		router.Get("/inventory-counts/{id}", inventoryCountController.Show)
+
+		// Open count session and snapshot expected quantities
+		router.Post("/inventory-counts", inventoryCountController.Store)
+
+		// Submit counted quantities, validate with adjustments or cancel
+		router.Post("/inventory-counts/{id}/counts", inventoryCountController.SubmitCounts)
+		router.Post("/inventory-counts/{id}/validate", inventoryCountController.Validate)
+		router.Post("/inventory-counts/{id}/cancel", inventoryCountController.Cancel)
+	})
+
+	// Reorder point routes (stock roles manage rules, achat handles suggestions)
+	reorderRuleController := controllers.NewReorderRuleController()
+	replenishmentSuggestionController := controllers.NewReplenishmentSuggestionController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		// Min/max/reorder point settings per variant and location
+		router.Get("/reorder-rules", reorderRuleController.Index)
+		router.Post("/reorder-rules", reorderRuleController.Store)
+		router.Put("/reorder-rules/{id}", reorderRuleController.Update)
+		router.Delete("/reorder-rules/{id}", reorderRuleController.Destroy)
+
+		// Live low-stock report
+		router.Get("/stock/low-stock", reorderRuleController.LowStock)
+
+		// Draft replenishment suggestions produced by the scheduled check
+		router.Get("/replenishment-suggestions", replenishmentSuggestionController.Index)
+		router.Patch("/replenishment-suggestions/{id}", replenishmentSuggestionController.Update)
+	})
+
+	// Inventory valuation route (weighted average or FIFO, see app.stock.valuation_method)
+	stockValuationController := controllers.NewStockValuationController()
+	facades.Route().Middleware(middleware.Auth()).Get("/stock/valuation", stockValuationController.Index)
+
+	// Point-in-time stock replayed from the movement ledger and consistency checks
+	stockHistoryController := controllers.NewStockHistoryController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		router.Get("/stock/history", stockHistoryController.Snapshot)
+		router.Get("/stock/consistency", stockHistoryController.Consistency)
+
+		// Realign stock levels on the ledger (admin only)
+		router.Post("/stock/consistency/repair", stockHistoryController.Repair)
+	})
+
+	// Lot and heat number traceability of raw materials
+	stockLotController := controllers.NewStockLotController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		router.Get("/stock-lots", stockLotController.Index)
+		router.Get("/stock-lots/{id}", stockLotController.Show)
+		router.Put("/stock-lots/{id}", stockLotController.Update)
+
+		// Lot to orders and clients, and order to lots
+		router.Get("/stock-lots/{id}/genealogy", stockLotController.Genealogy)
+		router.Get("/order-fabrications/{id}/lots", stockLotController.OrderLots)
+	})
+
+	// Serial numbers of finished goods and deliveries to client sites
+	serialNumberController := controllers.NewSerialNumberController()
+	deliveryController := controllers.NewDeliveryController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		router.Get("/serial-numbers", serialNumberController.Index)
+
+		// Trace a unit back to its order, operators, material lots and delivery
+		router.Get("/serial-numbers/{serial}/trace", serialNumberController.Trace)
+
+		// Deliveries ship serial numbers in stock (magasinier/admin create)
+		router.Get("/deliveries", deliveryController.Index)
+		router.Get("/deliveries/{id}", deliveryController.Show)
+		router.Post("/deliveries", deliveryController.Store)
+	})
+
+	// Units of measure and per-product conversions
+	unitController := controllers.NewUnitController()
+	facades.Route().Middleware(middleware.Auth()).Group(func(router route.Router) {
+		router.Get("/units", unitController.Index)
+		router.Post("/units", unitController.Store)
+		router.Put("/units/{id}", unitController.Update)
+		router.Delete("/units/{id}", unitController.Destroy)
+
+		// Conversions such as 1 sheet = 31.4 kg, for a product or one of its variants
+		router.Get("/products/{id}/unit-conversions", unitController.Conversions)
+		router.Post("/products/{id}/unit-conversions", unitController.StoreConversion)
+		router.Delete("/products/{id}/unit-conversions/{conversion}", unitController.DestroyConversion)
+	})
+
 	// Add this to the Api() function
 	// File Upload routes
 	fileUploadController := controllers.NewFileUploadController()